@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/wb-go/wbf/dbpg"
 	"github.com/wb-go/wbf/ginext"
@@ -11,11 +18,18 @@ import (
 	"github.com/yokitheyo/WarehouseControl/internal/config"
 	httpDelivery "github.com/yokitheyo/WarehouseControl/internal/delivery/http"
 	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/handler"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/repository"
 	"github.com/yokitheyo/WarehouseControl/internal/pkg/jwt"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/notifier"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/oidc"
+	"github.com/yokitheyo/WarehouseControl/internal/repository/memory"
 	"github.com/yokitheyo/WarehouseControl/internal/repository/postgres"
 	"github.com/yokitheyo/WarehouseControl/internal/usecase"
 )
 
+// shutdownTimeout is how long requests in flight get to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// Initialize logger
 	zlog.InitConsole()
@@ -47,20 +61,99 @@ func main() {
 
 	// Initialize repositories
 	userRepo := postgres.NewUserRepository(db)
+	userHistoryRepo := postgres.NewUserHistoryRepository(db)
 	itemRepo := postgres.NewItemRepository(db)
-	historyRepo := postgres.NewHistoryRepository(db)
+	historyRepo := postgres.NewHistoryRepository(db, cfg.History.HashKey)
+	warehouseRepo := postgres.NewWarehouseRepository(db)
+	locationRepo := postgres.NewLocationRepository(db)
+	stockRepo := postgres.NewStockRepository(db)
+	movementRepo := postgres.NewStockMovementRepository(db)
+	transferRepo := postgres.NewTransferRepository(db)
+	transferHistoryRepo := postgres.NewTransferHistoryRepository(db)
+	reservationRepo := postgres.NewReservationRepository(db)
+	tokenRepo := postgres.NewTokenRepository(db)
+	invitationRepo := postgres.NewInvitationRepository(db)
+	apiKeyRepo := postgres.NewAPIKeyRepository(db)
+	mfaRepo := postgres.NewMFARepository(db, cfg.Auth.MFA.Secrets)
+	resetRepo := postgres.NewPasswordResetRepository(db)
+	transactor := postgres.NewTransactor(db)
+
+	var attemptRepo repository.LoginAttemptRepository = memory.NewLoginAttemptRepository()
+	if cfg.Auth.LoginThrottle.Store == config.ThrottleStorePostgres {
+		attemptRepo = postgres.NewLoginAttemptRepository(db)
+	}
+
+	sealed, err := mfaRepo.SealPlaintextSecrets(context.Background())
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("Failed to encrypt MFA secrets")
+	}
+	if sealed > 0 {
+		zlog.Logger.Info().Int("count", sealed).Msg("Encrypted stored MFA secrets")
+	}
+
+	if err := historyRepo.SignChainHead(context.Background()); err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("Failed to sign history chain head")
+	}
+
+	// Only the log notifier exists so far; config.Load rejects anything else.
+	resetNotifier := notifier.NewLogNotifier()
 
 	// Initialize JWT manager
-	jwtManager := jwt.NewManager(cfg.JWT.Secret, cfg.JWT.Expiration)
+	jwtManager := jwt.NewManager(cfg.JWT.Keys, cfg.JWT.Issuer, cfg.JWT.Audience, cfg.JWT.Expiration, tokenRepo)
 
 	// Initialize use cases
-	authUseCase := usecase.NewAuthUseCase(userRepo, jwtManager)
-	itemUseCase := usecase.NewItemUseCase(itemRepo)
-	historyUseCase := usecase.NewHistoryUseCase(historyRepo)
+	authUseCase := usecase.NewAuthUseCase(userRepo, userHistoryRepo, tokenRepo, invitationRepo, attemptRepo, mfaRepo, resetRepo, transactor, jwtManager, resetNotifier, usecase.AuthSettings{
+		RefreshTTL:         cfg.JWT.RefreshExpiration,
+		PublicRegistration: cfg.Auth.PublicRegistration,
+		InvitationTTL:      cfg.Auth.InvitationTTL,
+		Permissions:        cfg.Permissions,
+		UserThrottle:       cfg.Auth.LoginThrottle.UserPolicy(),
+		IPThrottle:         cfg.Auth.LoginThrottle.IPPolicy(),
+		MFARequiredRoles:   cfg.Auth.MFA.RequiredRoles,
+		MFAPendingTTL:      cfg.Auth.MFA.PendingTTL,
+		MFAIssuer:          cfg.Auth.MFA.Issuer,
+		PasswordPolicy:     cfg.Auth.PasswordPolicy,
+		PasswordResetTTL:   cfg.Auth.PasswordReset.TokenTTL,
+		PasswordResetURL:   cfg.Auth.PasswordReset.URL,
+	})
+	itemUseCase := usecase.NewItemUseCase(itemRepo, historyRepo, transactor)
+	historyUseCase := usecase.NewHistoryUseCase(historyRepo, itemUseCase)
+	stockUseCase := usecase.NewStockUseCase(itemRepo, locationRepo, stockRepo, movementRepo, historyRepo, transactor)
+	warehouseUseCase := usecase.NewWarehouseUseCase(warehouseRepo, locationRepo)
+	transferUseCase := usecase.NewTransferUseCase(transferRepo, transferHistoryRepo, itemRepo, warehouseRepo, locationRepo, stockUseCase, transactor)
+	reservationUseCase := usecase.NewReservationUseCase(reservationRepo, itemRepo, transactor, usecase.ReservationSettings{
+		DefaultTTL: cfg.Reservations.DefaultTTL,
+		MaxTTL:     cfg.Reservations.MaxTTL,
+	})
+	apiKeyUseCase := usecase.NewAPIKeyUseCase(apiKeyRepo, authUseCase)
+
+	var ssoHandler *handler.SSOHandler
+	if cfg.Auth.SSO.Enabled {
+		provider := oidc.NewProvider(oidc.Config{
+			IssuerURL:    cfg.Auth.SSO.IssuerURL,
+			ClientID:     cfg.Auth.SSO.ClientID,
+			ClientSecret: cfg.Auth.SSO.ClientSecret,
+			RedirectURL:  cfg.Auth.SSO.RedirectURL,
+			Scopes:       cfg.Auth.SSO.Scopes,
+		}, nil)
+		ssoUseCase := usecase.NewSSOUseCase(provider, userRepo, userHistoryRepo, transactor, authUseCase, usecase.SSOSettings{
+			UsernameClaim: cfg.Auth.SSO.UsernameClaim,
+			GroupsClaim:   cfg.Auth.SSO.GroupsClaim,
+			RoleMapping:   cfg.Auth.SSO.RoleMapping,
+		})
+		ssoHandler = handler.NewSSOHandler(ssoUseCase)
+		zlog.Logger.Info().Str("issuer", cfg.Auth.SSO.IssuerURL).Msg("SSO login enabled")
+	}
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authUseCase)
+	userHandler := handler.NewUserHandler(authUseCase)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyUseCase)
 	itemHandler := handler.NewItemHandler(itemUseCase)
+	stockHandler := handler.NewStockHandler(stockUseCase)
+	warehouseHandler := handler.NewWarehouseHandler(warehouseUseCase)
+	transferHandler := handler.NewTransferHandler(transferUseCase)
+	reservationHandler := handler.NewReservationHandler(reservationUseCase)
 	historyHandler := handler.NewHistoryHandler(historyUseCase)
 
 	// Create Gin Engine
@@ -71,13 +164,71 @@ func main() {
 	engine.Use(ginext.Recovery())
 
 	// Configure routes
-	httpDelivery.SetupRouter(engine, authHandler, itemHandler, historyHandler, jwtManager)
+	httpDelivery.SetupRouter(engine, authHandler, ssoHandler, userHandler, apiKeyHandler, itemHandler, stockHandler, warehouseHandler, transferHandler, reservationHandler, historyHandler, jwtManager, authUseCase, apiKeyUseCase)
+
+	// Background jobs and the server stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Release expired reservations in the background
+	jobDone := make(chan struct{})
+	go func() {
+		defer close(jobDone)
+		releaseExpiredReservations(ctx, reservationUseCase, cfg.Reservations.ExpiryInterval)
+	}()
 
 	// Start the server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 	zlog.Logger.Info().Str("address", addr).Msg("Starting HTTP server")
 
-	if err := engine.Run(addr); err != nil {
-		zlog.Logger.Fatal().Err(err).Msg("Failed to start server")
+	server := &http.Server{Addr: addr, Handler: engine}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			zlog.Logger.Fatal().Err(err).Msg("Failed to start server")
+		}
+	case <-ctx.Done():
+		zlog.Logger.Info().Msg("Shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		zlog.Logger.Error().Err(err).Msg("Failed to shut down server gracefully")
+	}
+	<-jobDone
+}
+
+// releaseExpiredReservations marks expired reservations every interval until
+// ctx is done. Running it on several instances is harmless.
+func releaseExpiredReservations(ctx context.Context, reservationUseCase *usecase.ReservationUseCase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// A run that takes longer than the interval is given up, so a stuck
+		// query cannot pile up behind the next ticks.
+		tickCtx, cancel := context.WithTimeout(ctx, interval)
+		released, err := reservationUseCase.ReleaseExpired(tickCtx)
+		cancel()
+		if err != nil {
+			zlog.Logger.Error().Err(err).Msg("Failed to release expired reservations")
+			continue
+		}
+		if released > 0 {
+			zlog.Logger.Info().Int("count", released).Msg("Released expired reservations")
+		}
 	}
 }
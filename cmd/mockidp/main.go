@@ -0,0 +1,294 @@
+// Command mockidp is a throwaway OpenID Connect provider for trying SSO
+// locally. It signs in whoever types a username, with the groups they type.
+// Never expose it outside a development machine.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/wb-go/wbf/zlog"
+)
+
+const (
+	keyID   = "mockidp"
+	codeTTL = time.Minute
+	idTTL   = 5 * time.Minute
+)
+
+type authCode struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	username      string
+	groups        []string
+	expiresAt     time.Time
+}
+
+type provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*authCode
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, as configured in auth.sso.issuer_url")
+	clientID := flag.String("client-id", "warehouse-control", "accepted client id")
+	clientSecret := flag.String("client-secret", "", "client secret; empty accepts a public client")
+	flag.Parse()
+
+	zlog.InitConsole()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("Failed to generate signing key")
+	}
+
+	p := &provider{
+		issuer:       strings.TrimSuffix(*issuer, "/"),
+		clientID:     *clientID,
+		clientSecret: *clientSecret,
+		key:          key,
+		codes:        make(map[string]*authCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	mux.HandleFunc("GET /authorize", p.authorizeForm)
+	mux.HandleFunc("POST /authorize", p.authorize)
+	mux.HandleFunc("POST /token", p.token)
+
+	zlog.Logger.Info().Str("address", *addr).Str("issuer", p.issuer).Msg("Mock identity provider started")
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		zlog.Logger.Fatal().Err(err).Msg("Mock identity provider stopped")
+	}
+}
+
+func (p *provider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, _ *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var authorizeTemplate = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head><meta charset="UTF-8"><title>Mock IdP</title></head>
+<body style="font-family: sans-serif; max-width: 420px; margin: 60px auto;">
+    <h2>Mock IdP</h2>
+    <p>Тестовый провайдер: войти можно под любым именем.</p>
+    <form method="post" action="/authorize">
+        {{range $name, $value := .}}<input type="hidden" name="{{$name}}" value="{{index $value 0}}">
+        {{end}}
+        <p><label>Имя пользователя<br><input name="username" required></label></p>
+        <p><label>Группы через запятую<br><input name="groups" value="warehouse-staff"></label></p>
+        <button type="submit">Войти</button>
+    </form>
+</body>
+</html>`))
+
+func (p *provider) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if msg := p.checkAuthorizeParams(query); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	params := url.Values{}
+	for _, name := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge"} {
+		params.Set(name, query.Get(name))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = authorizeTemplate.Execute(w, params)
+}
+
+func (p *provider) checkAuthorizeParams(params url.Values) string {
+	switch {
+	case params.Get("response_type") != "" && params.Get("response_type") != "code":
+		return "unsupported response_type"
+	case params.Get("client_id") != p.clientID:
+		return "unknown client_id"
+	case params.Get("redirect_uri") == "":
+		return "redirect_uri is required"
+	case params.Get("code_challenge") == "":
+		return "code_challenge is required"
+	case params.Get("code_challenge_method") != "" && params.Get("code_challenge_method") != "S256":
+		return "only S256 code_challenge_method is supported"
+	}
+	return ""
+}
+
+func (p *provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	if msg := p.checkAuthorizeParams(r.PostForm); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	username := strings.TrimSpace(r.PostForm.Get("username"))
+	if username == "" {
+		http.Error(w, "username is required", http.StatusBadRequest)
+		return
+	}
+
+	var groups []string
+	for _, g := range strings.Split(r.PostForm.Get("groups"), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = &authCode{
+		clientID:      r.PostForm.Get("client_id"),
+		redirectURI:   r.PostForm.Get("redirect_uri"),
+		codeChallenge: r.PostForm.Get("code_challenge"),
+		nonce:         r.PostForm.Get("nonce"),
+		username:      username,
+		groups:        groups,
+		expiresAt:     time.Now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+
+	redirect, err := url.Parse(r.PostForm.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	query := redirect.Query()
+	query.Set("code", code)
+	query.Set("state", r.PostForm.Get("state"))
+	redirect.RawQuery = query.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	if p.clientSecret != "" {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != p.clientID || subtle.ConstantTimeCompare([]byte(secret), []byte(p.clientSecret)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	// Codes are single use: they are removed whether the exchange succeeds
+	// or not.
+	p.mu.Lock()
+	code, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(code.expiresAt) ||
+		code.clientID != r.PostForm.Get("client_id") ||
+		code.redirectURI != r.PostForm.Get("redirect_uri") ||
+		pkceChallenge(r.PostForm.Get("code_verifier")) != code.codeChallenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                p.issuer,
+		"sub":                "mock|" + code.username,
+		"aud":                code.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(idTTL).Unix(),
+		"nonce":              code.nonce,
+		"preferred_username": code.username,
+		"groups":             code.groups,
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = keyID
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(idTTL.Seconds()),
+		"id_token":     signed,
+	})
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -1,17 +1,32 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/wb-go/wbf/config"
 	"github.com/wb-go/wbf/zlog"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/jwt"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/secretbox"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	JWT          JWTConfig
+	Auth         AuthConfig
+	Reservations ReservationsConfig
+	History      HistoryConfig
+
+	// Permissions maps role names to the permissions they grant. New roles
+	// are added here, without code changes.
+	Permissions entity.PermissionMatrix
 }
 
 type ServerConfig struct {
@@ -32,8 +47,145 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret     string
-	Expiration time.Duration
+	// Issuer and Audience are checked on every token, so tokens of other
+	// services sharing a key are not accepted.
+	Issuer            string
+	Audience          string
+	Expiration        time.Duration
+	RefreshExpiration time.Duration
+	// Keys is built from jwt.keys, or from jwt.secret alone when no key ring
+	// is configured.
+	Keys *jwt.KeyRing
+}
+
+// jwtKeyConfig is one entry of jwt.keys. HS256 keys take a secret; RS256 and
+// EdDSA keys a PEM file, where a public key alone makes a verify-only key.
+type jwtKeyConfig struct {
+	ID             string `mapstructure:"id"`
+	Algorithm      string `mapstructure:"algorithm"`
+	Secret         string `mapstructure:"secret"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+type ReservationsConfig struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+	// ExpiryInterval is how often expired reservations are marked. They stop
+	// counting against available stock at expiry regardless.
+	ExpiryInterval time.Duration
+}
+
+type HistoryConfig struct {
+	// HashKey keys the HMAC of the item history hash chain and of its head.
+	// It is built from history.hash_key.
+	HashKey []byte
+}
+
+type AuthConfig struct {
+	// PublicRegistration is "disabled" or "viewer". Elevated accounts are only
+	// created by an admin or through an invitation.
+	PublicRegistration entity.RegistrationMode
+	InvitationTTL      time.Duration
+	LoginThrottle      LoginThrottleConfig
+	MFA                MFAConfig
+	PasswordPolicy     entity.PasswordPolicy
+	PasswordReset      PasswordResetConfig
+	SSO                SSOConfig
+}
+
+// SSOConfig configures login through an OpenID Connect provider.
+type SSOConfig struct {
+	Enabled      bool
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// UsernameClaim names the local account on first login.
+	UsernameClaim string
+	// GroupsClaim holds the values RoleMapping matches against.
+	GroupsClaim string
+	RoleMapping entity.RoleMapping
+}
+
+type roleMappingRuleConfig struct {
+	Value string `mapstructure:"value"`
+	Role  string `mapstructure:"role"`
+}
+
+// Password reset notifiers.
+const (
+	NotifierLog = "log"
+)
+
+type PasswordResetConfig struct {
+	// Notifier delivers reset links. "log" writes them to the application
+	// log and is meant for development only.
+	Notifier string
+	TokenTTL time.Duration
+	// URL is the reset page; the token is appended as a query parameter.
+	URL string
+}
+
+// defaultPasswordDenyList is always rejected; auth.password_policy.deny_list_file
+// adds to it.
+var defaultPasswordDenyList = []string{
+	"password", "password1", "password123", "passw0rd", "p@ssw0rd", "qwerty", "qwerty123",
+	"qwertyuiop", "123456", "12345678", "123456789", "1234567890", "111111", "000000",
+	"abc123", "iloveyou", "letmein", "welcome", "welcome1", "admin", "admin123",
+	"administrator", "changeme", "secret", "monkey", "dragon", "football", "sunshine",
+	"trustno1", "warehouse", "warehouse1", "Qwerty123!", "Password1!", "Welcome123",
+}
+
+type MFAConfig struct {
+	// RequiredRoles must pass a TOTP check on every login. Users of other
+	// roles may still enable MFA themselves.
+	RequiredRoles []entity.Role
+	PendingTTL    time.Duration
+	Issuer        string
+	// Secrets encrypts TOTP secrets at rest. It is built from
+	// auth.mfa.encryption_key.
+	Secrets *secretbox.Box
+}
+
+// Login throttle stores.
+const (
+	ThrottleStoreMemory   = "memory"
+	ThrottleStorePostgres = "postgres"
+)
+
+type LoginThrottleConfig struct {
+	// Store is "memory" for a single instance or "postgres" when several
+	// instances must share the counters.
+	Store            string
+	Window           time.Duration
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutDuration  time.Duration
+	UserFreeAttempts int
+	UserLockoutAfter int
+	IPFreeAttempts   int
+	IPLockoutAfter   int
+}
+
+func (c *LoginThrottleConfig) UserPolicy() entity.LoginThrottlePolicy {
+	return c.policy(c.UserFreeAttempts, c.UserLockoutAfter)
+}
+
+func (c *LoginThrottleConfig) IPPolicy() entity.LoginThrottlePolicy {
+	return c.policy(c.IPFreeAttempts, c.IPLockoutAfter)
+}
+
+func (c *LoginThrottleConfig) policy(freeAttempts, lockoutAfter int) entity.LoginThrottlePolicy {
+	return entity.LoginThrottlePolicy{
+		FreeAttempts:    freeAttempts,
+		BaseDelay:       c.BaseDelay,
+		MaxDelay:        c.MaxDelay,
+		LockoutAfter:    lockoutAfter,
+		LockoutDuration: c.LockoutDuration,
+		Window:          c.Window,
+	}
 }
 
 func Load() (*Config, error) {
@@ -57,7 +209,41 @@ func Load() (*Config, error) {
 	cfg.SetDefault("database.max_open_conns", 25)
 	cfg.SetDefault("database.max_idle_conns", 5)
 	cfg.SetDefault("database.conn_max_lifetime", "5m")
-	cfg.SetDefault("jwt.expiration", "24h")
+	cfg.SetDefault("jwt.issuer", "warehouse-control")
+	cfg.SetDefault("jwt.audience", "warehouse-control-api")
+	cfg.SetDefault("jwt.expiration", "15m")
+	cfg.SetDefault("jwt.refresh_expiration", "720h")
+	cfg.SetDefault("auth.public_registration", string(entity.RegistrationDisabled))
+	cfg.SetDefault("auth.invitation_ttl", "72h")
+	cfg.SetDefault("auth.mfa.required_roles", []string{})
+	cfg.SetDefault("auth.mfa.pending_ttl", "5m")
+	cfg.SetDefault("auth.mfa.issuer", "Warehouse Control")
+	cfg.SetDefault("auth.mfa.encryption_key", "")
+	cfg.SetDefault("auth.password_policy.min_length", 10)
+	cfg.SetDefault("auth.password_policy.require_upper", true)
+	cfg.SetDefault("auth.password_policy.require_lower", true)
+	cfg.SetDefault("auth.password_policy.require_digit", true)
+	cfg.SetDefault("auth.password_policy.require_symbol", false)
+	cfg.SetDefault("auth.password_reset.notifier", NotifierLog)
+	cfg.SetDefault("auth.password_reset.token_ttl", "30m")
+	cfg.SetDefault("auth.password_reset.url", "http://localhost:8080/reset-password")
+	cfg.SetDefault("auth.sso.enabled", false)
+	cfg.SetDefault("auth.sso.scopes", []string{"openid", "profile"})
+	cfg.SetDefault("auth.sso.username_claim", "preferred_username")
+	cfg.SetDefault("auth.sso.groups_claim", "groups")
+	cfg.SetDefault("auth.login_throttle.store", ThrottleStoreMemory)
+	cfg.SetDefault("auth.login_throttle.window", "1h")
+	cfg.SetDefault("auth.login_throttle.base_delay", "1s")
+	cfg.SetDefault("auth.login_throttle.max_delay", "30s")
+	cfg.SetDefault("auth.login_throttle.lockout_duration", "15m")
+	cfg.SetDefault("auth.login_throttle.user_free_attempts", 3)
+	cfg.SetDefault("auth.login_throttle.user_lockout_after", 10)
+	cfg.SetDefault("auth.login_throttle.ip_free_attempts", 10)
+	cfg.SetDefault("auth.login_throttle.ip_lockout_after", 100)
+	cfg.SetDefault("reservations.default_ttl", "24h")
+	cfg.SetDefault("reservations.max_ttl", "720h")
+	cfg.SetDefault("reservations.expiry_interval", "1m")
+	cfg.SetDefault("history.hash_key", "")
 
 	appConfig := &Config{
 		Server: ServerConfig{
@@ -76,11 +262,92 @@ func Load() (*Config, error) {
 			ConnMaxLifetime: cfg.GetDuration("database.conn_max_lifetime"),
 		},
 		JWT: JWTConfig{
-			Secret:     cfg.GetString("jwt.secret"),
-			Expiration: cfg.GetDuration("jwt.expiration"),
+			Issuer:            cfg.GetString("jwt.issuer"),
+			Audience:          cfg.GetString("jwt.audience"),
+			Expiration:        cfg.GetDuration("jwt.expiration"),
+			RefreshExpiration: cfg.GetDuration("jwt.refresh_expiration"),
+		},
+		Auth: AuthConfig{
+			PublicRegistration: entity.RegistrationMode(cfg.GetString("auth.public_registration")),
+			InvitationTTL:      cfg.GetDuration("auth.invitation_ttl"),
+			MFA: MFAConfig{
+				PendingTTL: cfg.GetDuration("auth.mfa.pending_ttl"),
+				Issuer:     cfg.GetString("auth.mfa.issuer"),
+			},
+			PasswordPolicy: entity.PasswordPolicy{
+				MinLength:     cfg.GetInt("auth.password_policy.min_length"),
+				RequireUpper:  cfg.GetBool("auth.password_policy.require_upper"),
+				RequireLower:  cfg.GetBool("auth.password_policy.require_lower"),
+				RequireDigit:  cfg.GetBool("auth.password_policy.require_digit"),
+				RequireSymbol: cfg.GetBool("auth.password_policy.require_symbol"),
+			},
+			PasswordReset: PasswordResetConfig{
+				Notifier: cfg.GetString("auth.password_reset.notifier"),
+				TokenTTL: cfg.GetDuration("auth.password_reset.token_ttl"),
+				URL:      cfg.GetString("auth.password_reset.url"),
+			},
+			LoginThrottle: LoginThrottleConfig{
+				Store:            cfg.GetString("auth.login_throttle.store"),
+				Window:           cfg.GetDuration("auth.login_throttle.window"),
+				BaseDelay:        cfg.GetDuration("auth.login_throttle.base_delay"),
+				MaxDelay:         cfg.GetDuration("auth.login_throttle.max_delay"),
+				LockoutDuration:  cfg.GetDuration("auth.login_throttle.lockout_duration"),
+				UserFreeAttempts: cfg.GetInt("auth.login_throttle.user_free_attempts"),
+				UserLockoutAfter: cfg.GetInt("auth.login_throttle.user_lockout_after"),
+				IPFreeAttempts:   cfg.GetInt("auth.login_throttle.ip_free_attempts"),
+				IPLockoutAfter:   cfg.GetInt("auth.login_throttle.ip_lockout_after"),
+			},
+		},
+		Reservations: ReservationsConfig{
+			DefaultTTL:     cfg.GetDuration("reservations.default_ttl"),
+			MaxTTL:         cfg.GetDuration("reservations.max_ttl"),
+			ExpiryInterval: cfg.GetDuration("reservations.expiry_interval"),
 		},
 	}
 
+	keys, err := loadKeyRing(cfg)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.JWT.Keys = keys
+
+	mfaKey, err := loadSecretKey(cfg, "auth.mfa.encryption_key")
+	if err != nil {
+		return nil, err
+	}
+	if appConfig.Auth.MFA.Secrets, err = secretbox.New(mfaKey); err != nil {
+		return nil, fmt.Errorf("auth.mfa.encryption_key: %w", err)
+	}
+
+	if appConfig.History.HashKey, err = loadSecretKey(cfg, "history.hash_key"); err != nil {
+		return nil, err
+	}
+
+	permissions, err := loadPermissions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.Permissions = permissions
+
+	sso, err := loadSSO(cfg, permissions)
+	if err != nil {
+		return nil, err
+	}
+	appConfig.Auth.SSO = sso
+
+	for _, role := range cfg.GetStringSlice("auth.mfa.required_roles") {
+		if !permissions.HasRole(entity.Role(role)) {
+			return nil, fmt.Errorf("auth.mfa.required_roles: unknown role %q", role)
+		}
+		appConfig.Auth.MFA.RequiredRoles = append(appConfig.Auth.MFA.RequiredRoles, entity.Role(role))
+	}
+
+	denyList, err := loadPasswordDenyList(cfg.GetString("auth.password_policy.deny_list_file"))
+	if err != nil {
+		return nil, err
+	}
+	appConfig.Auth.PasswordPolicy.DenyList = denyList
+
 	if appConfig.Database.Host == "" {
 		return nil, fmt.Errorf("database.host is required")
 	}
@@ -90,13 +357,227 @@ func Load() (*Config, error) {
 	if appConfig.Database.DBName == "" {
 		return nil, fmt.Errorf("database.dbname is required")
 	}
-	if appConfig.JWT.Secret == "" {
-		return nil, fmt.Errorf("jwt.secret is required")
+	if !appConfig.Auth.PublicRegistration.IsValid() {
+		return nil, fmt.Errorf("auth.public_registration must be %q or %q",
+			entity.RegistrationDisabled, entity.RegistrationViewer)
+	}
+
+	if appConfig.JWT.Issuer == "" || appConfig.JWT.Audience == "" {
+		return nil, fmt.Errorf("jwt.issuer and jwt.audience are required")
+	}
+
+	if store := appConfig.Auth.LoginThrottle.Store; store != ThrottleStoreMemory && store != ThrottleStorePostgres {
+		return nil, fmt.Errorf("auth.login_throttle.store must be %q or %q", ThrottleStoreMemory, ThrottleStorePostgres)
+	}
+
+	if r := appConfig.Reservations; r.DefaultTTL <= 0 || r.MaxTTL < r.DefaultTTL || r.ExpiryInterval <= 0 {
+		return nil, fmt.Errorf("reservations: default_ttl and expiry_interval must be positive and max_ttl at least default_ttl")
+	}
+
+	if appConfig.Auth.PasswordPolicy.MinLength < 1 {
+		return nil, fmt.Errorf("auth.password_policy.min_length must be positive")
+	}
+
+	if appConfig.Auth.PasswordReset.Notifier != NotifierLog {
+		return nil, fmt.Errorf("auth.password_reset.notifier must be %q", NotifierLog)
+	}
+
+	if appConfig.Auth.PublicRegistration == entity.RegistrationViewer && !permissions.HasRole(entity.RoleViewer) {
+		return nil, fmt.Errorf("auth.public_registration %q requires the %q role in permissions.roles",
+			entity.RegistrationViewer, entity.RoleViewer)
 	}
 
 	return appConfig, nil
 }
 
+// loadPermissions reads the permissions.roles section. Without it the built-in
+// admin, manager and viewer roles are used.
+func loadPermissions(cfg *config.Config) (entity.PermissionMatrix, error) {
+	var roles map[string][]string
+	if err := cfg.UnmarshalKey("permissions.roles", &roles); err != nil {
+		return nil, fmt.Errorf("failed to parse permissions.roles: %w", err)
+	}
+
+	if len(roles) == 0 {
+		return entity.DefaultPermissionMatrix(), nil
+	}
+
+	matrix := make(entity.PermissionMatrix, len(roles))
+	for role, perms := range roles {
+		granted := make([]entity.Permission, 0, len(perms))
+		for _, perm := range perms {
+			granted = append(granted, entity.Permission(perm))
+		}
+		matrix[entity.Role(role)] = granted
+	}
+
+	if err := matrix.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid permissions.roles: %w", err)
+	}
+
+	return matrix, nil
+}
+
+// loadSSO reads auth.sso. Mapped roles must exist in the permission matrix.
+func loadSSO(cfg *config.Config, permissions entity.PermissionMatrix) (SSOConfig, error) {
+	sso := SSOConfig{
+		Enabled:       cfg.GetBool("auth.sso.enabled"),
+		IssuerURL:     cfg.GetString("auth.sso.issuer_url"),
+		ClientID:      cfg.GetString("auth.sso.client_id"),
+		ClientSecret:  cfg.GetString("auth.sso.client_secret"),
+		RedirectURL:   cfg.GetString("auth.sso.redirect_url"),
+		Scopes:        cfg.GetStringSlice("auth.sso.scopes"),
+		UsernameClaim: cfg.GetString("auth.sso.username_claim"),
+		GroupsClaim:   cfg.GetString("auth.sso.groups_claim"),
+		RoleMapping: entity.RoleMapping{
+			DefaultRole: entity.Role(cfg.GetString("auth.sso.default_role")),
+		},
+	}
+
+	if !sso.Enabled {
+		return sso, nil
+	}
+
+	if sso.IssuerURL == "" || sso.ClientID == "" || sso.RedirectURL == "" {
+		return sso, fmt.Errorf("auth.sso.issuer_url, client_id and redirect_url are required when SSO is enabled")
+	}
+	if !slices.Contains(sso.Scopes, "openid") {
+		return sso, fmt.Errorf("auth.sso.scopes must contain %q", "openid")
+	}
+
+	var rules []roleMappingRuleConfig
+	if err := cfg.UnmarshalKey("auth.sso.role_mapping", &rules); err != nil {
+		return sso, fmt.Errorf("failed to parse auth.sso.role_mapping: %w", err)
+	}
+
+	for _, rule := range rules {
+		if !permissions.HasRole(entity.Role(rule.Role)) {
+			return sso, fmt.Errorf("auth.sso.role_mapping: unknown role %q", rule.Role)
+		}
+		sso.RoleMapping.Rules = append(sso.RoleMapping.Rules, entity.RoleMappingRule{Value: rule.Value, Role: entity.Role(rule.Role)})
+	}
+
+	if role := sso.RoleMapping.DefaultRole; role != "" && !permissions.HasRole(role) {
+		return sso, fmt.Errorf("auth.sso.default_role: unknown role %q", role)
+	}
+
+	return sso, nil
+}
+
+// loadKeyRing builds the signing keys. jwt.active_key signs new tokens; every
+// other key in jwt.keys only verifies tokens signed before a rotation.
+func loadKeyRing(cfg *config.Config) (*jwt.KeyRing, error) {
+	var specs []jwtKeyConfig
+	if err := cfg.UnmarshalKey("jwt.keys", &specs); err != nil {
+		return nil, fmt.Errorf("failed to parse jwt.keys: %w", err)
+	}
+
+	if len(specs) == 0 {
+		secret := cfg.GetString("jwt.secret")
+		if secret == "" {
+			return nil, fmt.Errorf("jwt.secret or jwt.keys is required")
+		}
+
+		key, err := jwt.NewHMACKey(jwt.DefaultKeyID, secret)
+		if err != nil {
+			return nil, err
+		}
+		return jwt.NewKeyRing(key)
+	}
+
+	activeID := cfg.GetString("jwt.active_key")
+
+	var (
+		active     *jwt.Key
+		verifyOnly []*jwt.Key
+	)
+	for _, spec := range specs {
+		key, err := spec.load()
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwt.keys: %w", err)
+		}
+
+		if key.ID == activeID {
+			active = key
+		} else {
+			verifyOnly = append(verifyOnly, key)
+		}
+	}
+
+	if active == nil {
+		return nil, fmt.Errorf("jwt.active_key %q is not in jwt.keys", activeID)
+	}
+
+	ring, err := jwt.NewKeyRing(active, verifyOnly...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt.keys: %w", err)
+	}
+
+	return ring, nil
+}
+
+// loadSecretKey reads a required base64 key of secretbox.KeySize bytes. The
+// history hash key uses the same size.
+func loadSecretKey(cfg *config.Config, name string) ([]byte, error) {
+	value := cfg.GetString(name)
+	if value == "" {
+		return nil, fmt.Errorf("%s is required", name)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be base64: %w", name, err)
+	}
+	if len(key) != secretbox.KeySize {
+		return nil, fmt.Errorf("%s must be %d bytes, got %d", name, secretbox.KeySize, len(key))
+	}
+
+	return key, nil
+}
+
+func (k jwtKeyConfig) load() (*jwt.Key, error) {
+	if k.ID == "" {
+		return nil, fmt.Errorf("key without id")
+	}
+
+	switch {
+	case k.Algorithm == jwt.AlgHS256:
+		return jwt.NewHMACKey(k.ID, k.Secret)
+	case k.Algorithm != jwt.AlgRS256 && k.Algorithm != jwt.AlgEdDSA:
+		return nil, fmt.Errorf("key %q: algorithm must be %s, %s or %s", k.ID, jwt.AlgHS256, jwt.AlgRS256, jwt.AlgEdDSA)
+	case k.PrivateKeyFile != "":
+		data, err := os.ReadFile(k.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.ID, err)
+		}
+		return jwt.ParsePrivateKeyPEM(k.ID, k.Algorithm, data)
+	case k.PublicKeyFile != "":
+		data, err := os.ReadFile(k.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.ID, err)
+		}
+		return jwt.ParsePublicKeyPEM(k.ID, k.Algorithm, data)
+	default:
+		return nil, fmt.Errorf("key %q: private_key_file or public_key_file is required", k.ID)
+	}
+}
+
+// loadPasswordDenyList merges the built-in deny-list with the file at path,
+// which holds one password per line.
+func loadPasswordDenyList(path string) (map[string]struct{}, error) {
+	passwords := slices.Clone(defaultPasswordDenyList)
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read auth.password_policy.deny_list_file: %w", err)
+		}
+		passwords = append(passwords, strings.Split(string(data), "\n")...)
+	}
+
+	return entity.NewPasswordDenyList(passwords), nil
+}
+
 func (c *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+	"github.com/yokitheyo/WarehouseControl/internal/usecase"
+)
+
+// APIKeyHandler lets users manage their own API keys.
+type APIKeyHandler struct {
+	apiKeyUseCase *usecase.APIKeyUseCase
+}
+
+func NewAPIKeyHandler(apiKeyUseCase *usecase.APIKeyUseCase) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyUseCase: apiKeyUseCase,
+	}
+}
+
+func (h *APIKeyHandler) GetAll(c *ginext.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	keys, err := h.apiKeyUseCase.List(c.Request.Context(), user)
+	if err != nil {
+		if errors.Is(err, entity.ErrForbidden) {
+			response.Error(c, 403, "api keys cannot list api keys")
+			return
+		}
+		response.Error(c, 500, "failed to get api keys")
+		return
+	}
+
+	response.Success(c, 200, keys)
+}
+
+type createAPIKeyRequest struct {
+	Name        string              `json:"name" binding:"required,max=100"`
+	Permissions []entity.Permission `json:"permissions" binding:"required,min=1"`
+	ExpiresAt   *time.Time          `json:"expires_at"`
+}
+
+type apiKeyResponse struct {
+	*entity.APIKey
+	Key string `json:"key"`
+}
+
+func (h *APIKeyHandler) Create(c *ginext.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		response.Error(c, 400, "expires_at must be in the future")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	key, plain, err := h.apiKeyUseCase.Create(c.Request.Context(), user, req.Name, req.Permissions, req.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrInvalidPermission):
+			response.Error(c, 400, err.Error())
+		case errors.Is(err, entity.ErrForbidden):
+			response.Error(c, 403, "api keys cannot create other api keys")
+		case errors.Is(err, entity.ErrAPIKeyExists):
+			response.Error(c, 409, err.Error())
+		default:
+			response.Error(c, 500, "failed to create api key")
+		}
+		return
+	}
+
+	response.Success(c, 201, apiKeyResponse{APIKey: key, Key: plain})
+}
+
+func (h *APIKeyHandler) Revoke(c *ginext.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid api key id")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	if err := h.apiKeyUseCase.Revoke(c.Request.Context(), user, id); err != nil {
+		switch {
+		case errors.Is(err, entity.ErrForbidden):
+			response.Error(c, 403, "api keys cannot revoke api keys")
+		case errors.Is(err, entity.ErrAPIKeyNotFound):
+			response.Error(c, 404, err.Error())
+		default:
+			response.Error(c, 500, "failed to revoke api key")
+		}
+		return
+	}
+
+	response.Success(c, 200, ginext.H{"message": "api key revoked"})
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+const (
+	changeReasonHeader = "X-Change-Reason"
+	maxReasonLength    = 500
+)
+
+func auditMeta(c *ginext.Context, user *entity.User) *entity.AuditMeta {
+	reason := strings.TrimSpace(c.GetHeader(changeReasonHeader))
+	if runes := []rune(reason); len(runes) > maxReasonLength {
+		reason = string(runes[:maxReasonLength])
+	}
+
+	return &entity.AuditMeta{
+		Username:  user.Username,
+		APIKey:    user.APIKeyName,
+		RequestID: middleware.GetRequestID(c),
+		ClientIP:  c.ClientIP(),
+		Reason:    reason,
+	}
+}
@@ -1,7 +1,11 @@
 package handler
 
 import (
+	"math"
+	"strconv"
+
 	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
 	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
 	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
 	"github.com/yokitheyo/WarehouseControl/internal/usecase"
@@ -23,9 +27,19 @@ type loginRequest struct {
 }
 
 type loginResponse struct {
-	Token    string      `json:"token"`
-	Username string      `json:"username"`
-	Role     entity.Role `json:"role"`
+	*entity.TokenPair
+	Username      string              `json:"username"`
+	Role          entity.Role         `json:"role"`
+	Permissions   []entity.Permission `json:"permissions"`
+	RecoveryCodes []string            `json:"recovery_codes,omitempty"`
+	// PasswordChangeRequired means every endpoint but the password change
+	// answers 403 until the password is replaced.
+	PasswordChangeRequired bool `json:"password_change_required"`
+}
+
+type mfaChallengeResponse struct {
+	MFARequired bool `json:"mfa_required"`
+	*entity.MFAChallenge
 }
 
 func (h *AuthHandler) Login(c *ginext.Context) {
@@ -35,33 +49,115 @@ func (h *AuthHandler) Login(c *ginext.Context) {
 		return
 	}
 
-	token, err := h.authUseCase.Login(c.Request.Context(), req.Username, req.Password)
+	result, err := h.authUseCase.Login(c.Request.Context(), req.Username, req.Password, c.ClientIP())
 	if err != nil {
-		if err == entity.ErrInvalidCredentials {
-			response.Error(c, 401, err.Error())
+		if respondThrottled(c, err) {
 			return
 		}
-		response.Error(c, 500, "internal server error")
+
+		switch err {
+		case entity.ErrInvalidCredentials:
+			response.Error(c, 401, err.Error())
+		case entity.ErrUserDisabled:
+			response.Error(c, 403, err.Error())
+		default:
+			response.Error(c, 500, "internal server error")
+		}
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+func respondLogin(c *ginext.Context, result *entity.LoginResult) {
+	if result.MFA != nil {
+		response.Success(c, 200, mfaChallengeResponse{MFARequired: true, MFAChallenge: result.MFA})
+		return
+	}
+
+	response.Success(c, 200, newLoginResponse(result))
+}
+
+func newLoginResponse(result *entity.LoginResult) *loginResponse {
+	return &loginResponse{
+		TokenPair:     result.Tokens,
+		Username:      result.User.Username,
+		Role:          result.User.Role,
+		Permissions:   result.User.Permissions,
+		RecoveryCodes: result.RecoveryCodes,
+
+		PasswordChangeRequired: result.User.PasswordChangeRequired,
+	}
+}
+
+// respondThrottled answers 429 with Retry-After if err means the login has
+// to wait.
+func respondThrottled(c *ginext.Context, err error) bool {
+	throttled, ok := entity.IsLoginThrottled(err)
+	if !ok {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	response.Error(c, 429, entity.ErrTooManyAttempts.Error())
+	return true
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+func (h *AuthHandler) Refresh(c *ginext.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
 		return
 	}
 
-	user, err := h.authUseCase.GetUserInfo(c.Request.Context(), req.Username)
+	pair, user, err := h.authUseCase.Refresh(c.Request.Context(), req.RefreshToken)
 	if err != nil {
+		if err == entity.ErrInvalidToken {
+			response.Error(c, 401, err.Error())
+			return
+		}
 		response.Error(c, 500, "internal server error")
 		return
 	}
 
 	response.Success(c, 200, loginResponse{
-		Token:    token,
-		Username: user.Username,
-		Role:     user.Role,
+		TokenPair:   pair,
+		Username:    user.Username,
+		Role:        user.Role,
+		Permissions: user.Permissions,
 	})
 }
 
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (h *AuthHandler) Logout(c *ginext.Context) {
+	var req logoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, 400, "invalid request body")
+			return
+		}
+	}
+
+	if err := h.authUseCase.Logout(c.Request.Context(), req.RefreshToken, middleware.ExtractToken(c)); err != nil {
+		response.Error(c, 500, "failed to logout")
+		return
+	}
+
+	response.Success(c, 200, ginext.H{"message": "logged out"})
+}
+
 type registerRequest struct {
-	Username string      `json:"username" binding:"required"`
-	Password string      `json:"password" binding:"required,min=6"`
-	Role     entity.Role `json:"role" binding:"required"`
+	Username   string      `json:"username" binding:"required"`
+	Password   string      `json:"password" binding:"required"`
+	Role       entity.Role `json:"role"`
+	InviteCode string      `json:"invite_code"`
 }
 
 func (h *AuthHandler) Register(c *ginext.Context) {
@@ -71,16 +167,61 @@ func (h *AuthHandler) Register(c *ginext.Context) {
 		return
 	}
 
-	if req.Role != entity.RoleAdmin && req.Role != entity.RoleManager && req.Role != entity.RoleViewer {
-		response.Error(c, 400, "invalid role")
+	meta := auditMeta(c, &entity.User{Username: req.Username})
+
+	user, err := h.authUseCase.Register(c.Request.Context(), req.Username, req.Password, req.Role, req.InviteCode, meta)
+	if err != nil {
+		if respondPasswordError(c, err) {
+			return
+		}
+
+		switch err {
+		case entity.ErrRegistrationClosed, entity.ErrForbidden:
+			response.Error(c, 403, err.Error())
+		case entity.ErrInvalidInvitation:
+			response.Error(c, 400, err.Error())
+		case entity.ErrUserExists:
+			response.Error(c, 409, err.Error())
+		default:
+			response.Error(c, 500, "failed to register user")
+		}
 		return
 	}
 
-	err := h.authUseCase.Register(c.Request.Context(), req.Username, req.Password, req.Role)
+	response.Success(c, 201, ginext.H{"message": "user registered successfully", "role": user.Role})
+}
+
+type createInvitationRequest struct {
+	Role entity.Role `json:"role" binding:"required"`
+}
+
+type invitationResponse struct {
+	*entity.Invitation
+	Code string `json:"code"`
+}
+
+func (h *AuthHandler) CreateInvitation(c *ginext.Context) {
+	var req createInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	admin, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	invitation, code, err := h.authUseCase.CreateInvitation(c.Request.Context(), req.Role, admin.Username)
 	if err != nil {
-		response.Error(c, 500, "failed to register user")
+		if err == entity.ErrInvalidRole {
+			response.Error(c, 400, err.Error())
+			return
+		}
+		response.Error(c, 500, "failed to create invitation")
 		return
 	}
 
-	response.Success(c, 201, ginext.H{"message": "user registered successfully"})
+	response.Success(c, 201, invitationResponse{Invitation: invitation, Code: code})
 }
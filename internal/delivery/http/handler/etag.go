@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+)
+
+func itemETag(item *entity.Item) string {
+	return fmt.Sprintf(`"%d"`, item.Version)
+}
+
+// itemsETag is a weak validator for a listing page: it changes whenever any
+// item on the page or the total count changes.
+func itemsETag(items []*entity.Item, total int) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d;", total)
+	for _, item := range items {
+		fmt.Fprintf(h, "%d:%d;", item.ID, item.Version)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// requireIfMatch is parseIfMatch for the item PUT, PATCH and DELETE, which must
+// not run blind: without an If-Match header it answers 428 and returns false.
+// A client that means to overwrite whatever is current has to say so with "*".
+func requireIfMatch(c *ginext.Context) (int, bool) {
+	if strings.TrimSpace(c.GetHeader("If-Match")) == "" {
+		response.Error(c, 428, "If-Match header is required")
+		return 0, false
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return 0, false
+	}
+
+	return version, true
+}
+
+// parseIfMatch returns the item version required by the If-Match header.
+// Zero means the header is absent or "*", so any current version is accepted.
+func parseIfMatch(c *ginext.Context) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, fmt.Errorf("invalid If-Match header")
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid If-Match header")
+	}
+
+	return version, nil
+}
+
+func notModified(c *ginext.Context, etag string) bool {
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
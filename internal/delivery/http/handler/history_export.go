@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+
+	// exportFlushEvery bounds how many rows sit in the response buffer.
+	exportFlushEvery = 500
+)
+
+var historyCSVHeader = []string{
+	"id", "item_id", "action", "username", "api_key", "changed_at",
+	"old_sku", "old_barcodes", "old_name", "old_description", "old_quantity", "old_price",
+	"new_sku", "new_barcodes", "new_name", "new_description", "new_quantity", "new_price",
+	"request_id", "client_ip", "reason", "location_id",
+}
+
+func (h *HistoryHandler) Export(c *ginext.Context) {
+	format := c.DefaultQuery("format", exportFormatCSV)
+	if format != exportFormatCSV && format != exportFormatNDJSON {
+		response.Error(c, 400, "invalid export format")
+		return
+	}
+
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("history-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	var (
+		write func(*entity.ItemHistory) error
+		flush func()
+	)
+
+	switch format {
+	case exportFormatCSV:
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w := csv.NewWriter(c.Writer)
+		if err := w.Write(historyCSVHeader); err != nil {
+			return
+		}
+		write = func(record *entity.ItemHistory) error {
+			return w.Write(historyCSVRow(record))
+		}
+		flush = func() {
+			w.Flush()
+			c.Writer.Flush()
+		}
+	case exportFormatNDJSON:
+		c.Header("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(c.Writer)
+		write = func(record *entity.ItemHistory) error {
+			return enc.Encode(record)
+		}
+		flush = c.Writer.Flush
+	}
+
+	c.Status(http.StatusOK)
+
+	count := 0
+	err = h.historyUseCase.Export(c.Request.Context(), filter, func(record *entity.ItemHistory) error {
+		if err := write(record); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			flush()
+		}
+		return nil
+	})
+	flush()
+
+	// Headers are already sent, so a failure can only cut the stream short.
+	if err != nil {
+		zlog.Logger.Error().Err(err).Int("rows", count).Msg("History export interrupted")
+	}
+}
+
+func historyCSVRow(h *entity.ItemHistory) []string {
+	row := []string{
+		strconv.Itoa(h.ID),
+		strconv.Itoa(h.ItemID),
+		string(h.Action),
+		csvSafe(h.Username),
+		csvSafe(h.APIKey),
+		h.ChangedAt.Format(time.RFC3339),
+	}
+	row = append(row, itemCSVColumns(h.OldData)...)
+	row = append(row, itemCSVColumns(h.NewData)...)
+	row = append(row, h.RequestID, h.ClientIP, csvSafe(h.Reason), optionalInt(h.LocationID))
+	return row
+}
+
+func itemCSVColumns(item *entity.Item) []string {
+	if item == nil {
+		return []string{"", "", "", "", "", ""}
+	}
+	return []string{
+		csvSafe(item.SKU),
+		csvSafe(strings.Join(item.Barcodes, " ")),
+		csvSafe(item.Name),
+		csvSafe(item.Description),
+		strconv.Itoa(item.Quantity),
+		strconv.FormatFloat(item.Price, 'f', 2, 64),
+	}
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// csvSafe keeps spreadsheet applications from treating free text as a formula.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -1,15 +1,25 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
 	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
 	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
 	"github.com/yokitheyo/WarehouseControl/internal/usecase"
 )
 
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
 type HistoryHandler struct {
 	historyUseCase *usecase.HistoryUseCase
 }
@@ -28,16 +38,133 @@ func (h *HistoryHandler) GetByItemID(c *ginext.Context) {
 		return
 	}
 
-	history, err := h.historyUseCase.GetByItemID(c.Request.Context(), id)
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	filter, err = parsePageParams(c, filter)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	page, err := h.historyUseCase.GetByItemID(c.Request.Context(), id, filter)
 	if err != nil {
 		response.Error(c, 500, "failed to get history")
 		return
 	}
 
-	response.Success(c, 200, history)
+	respondHistoryPage(c, page, filter)
 }
 
 func (h *HistoryHandler) GetAll(c *ginext.Context) {
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	filter, err = parsePageParams(c, filter)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	page, err := h.historyUseCase.GetAll(c.Request.Context(), filter)
+	if err != nil {
+		response.Error(c, 500, "failed to get history")
+		return
+	}
+
+	respondHistoryPage(c, page, filter)
+}
+
+func (h *HistoryHandler) Revert(c *ginext.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(c, 400, "invalid history id")
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	result, err := h.historyUseCase.Revert(c.Request.Context(), id, version, dryRun, auditMeta(c, user))
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrHistoryNotFound), errors.Is(err, entity.ErrItemNotFound):
+			response.Error(c, 404, err.Error())
+		case errors.Is(err, entity.ErrNothingToRevert):
+			response.Error(c, 409, err.Error())
+		case errors.Is(err, entity.ErrVersionConflict):
+			response.Error(c, 412, err.Error())
+		case isItemCodeConflict(err):
+			response.Error(c, 409, err.Error())
+		case isInvalidItem(err):
+			response.Error(c, 422, err.Error())
+		default:
+			response.Error(c, 500, "failed to revert item")
+		}
+		return
+	}
+
+	c.Header("ETag", itemETag(result.Item))
+	response.Success(c, 200, result)
+}
+
+// Verify checks the history chain. anchor_count and anchor_hash take the
+// checked and last_hash of an earlier verification; only with them can the
+// check tell that rows were removed together with the head that signed them.
+func (h *HistoryHandler) Verify(c *ginext.Context) {
+	anchor, err := parseChainAnchor(c.Query("anchor_count"), c.Query("anchor_hash"))
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	result, err := h.historyUseCase.VerifyChain(c.Request.Context(), anchor)
+	if err != nil {
+		response.Error(c, 500, "failed to verify history")
+		return
+	}
+
+	response.Success(c, 200, result)
+}
+
+// parseChainAnchor reads an anchor given as a row count and the hex hash of
+// that row. Both are required together; neither means no anchor.
+func parseChainAnchor(countStr, hash string) (*entity.ChainAnchor, error) {
+	if countStr == "" && hash == "" {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count < 1 {
+		return nil, errors.New("anchor_count must be a positive integer")
+	}
+
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size || hash != strings.ToLower(hash) {
+		return nil, errors.New("anchor_hash must be 64 lowercase hex characters")
+	}
+
+	return &entity.ChainAnchor{Count: count, Hash: hash}, nil
+}
+
+func parseHistoryFilter(c *ginext.Context) (*entity.HistoryFilter, error) {
 	filter := &entity.HistoryFilter{}
 
 	if itemIDStr := c.Query("item_id"); itemIDStr != "" {
@@ -47,6 +174,13 @@ func (h *HistoryHandler) GetAll(c *ginext.Context) {
 		}
 	}
 
+	if locationIDStr := c.Query("location_id"); locationIDStr != "" {
+		locationID, err := strconv.Atoi(locationIDStr)
+		if err == nil {
+			filter.LocationID = &locationID
+		}
+	}
+
 	if username := c.Query("username"); username != "" {
 		filter.Username = &username
 	}
@@ -70,13 +204,48 @@ func (h *HistoryHandler) GetAll(c *ginext.Context) {
 		}
 	}
 
+	field, err := parseHistoryField(c)
+	if err != nil {
+		return nil, err
+	}
+	filter.Field = field
+
+	filter.WithChanges, _ = strconv.ParseBool(c.Query("changes"))
+
+	return filter, nil
+}
+
+func parseHistoryField(c *ginext.Context) (*string, error) {
+	field := c.Query("field")
+	if field == "" {
+		return nil, nil
+	}
+
+	if !entity.IsDiffField(field) {
+		return nil, errors.New("invalid field")
+	}
+
+	return &field, nil
+}
+
+// parsePageParams reads limit and either cursor or offset. A cursor takes
+// precedence over offset so that old offset-based clients keep working.
+func parsePageParams(c *ginext.Context, filter *entity.HistoryFilter) (*entity.HistoryFilter, error) {
+	filter.Limit = defaultHistoryLimit
 	if limitStr := c.Query("limit"); limitStr != "" {
 		limit, err := strconv.Atoi(limitStr)
 		if err == nil && limit > 0 {
-			filter.Limit = limit
+			filter.Limit = min(limit, maxHistoryLimit)
+		}
+	}
+
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := entity.DecodeHistoryCursor(cursorStr)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		filter.Limit = 100
+		filter.Cursor = cursor
+		return filter, nil
 	}
 
 	if offsetStr := c.Query("offset"); offsetStr != "" {
@@ -86,11 +255,14 @@ func (h *HistoryHandler) GetAll(c *ginext.Context) {
 		}
 	}
 
-	history, err := h.historyUseCase.GetAll(c.Request.Context(), filter)
-	if err != nil {
-		response.Error(c, 500, "failed to get history")
-		return
+	return filter, nil
+}
+
+func respondHistoryPage(c *ginext.Context, page *entity.HistoryPage, filter *entity.HistoryFilter) {
+	var nextCursor string
+	if page.NextCursor != nil {
+		nextCursor = page.NextCursor.Encode()
 	}
 
-	response.Success(c, 200, history)
+	response.SuccessWithMeta(c, 200, page.Items, response.NewCursorMeta(filter.Limit, nextCursor))
 }
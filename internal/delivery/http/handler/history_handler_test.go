@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+func TestParseChainAnchor(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name    string
+		count   string
+		hash    string
+		want    *entity.ChainAnchor
+		wantErr bool
+	}{
+		{name: "no anchor"},
+		{name: "anchor", count: "42", hash: hash, want: &entity.ChainAnchor{Count: 42, Hash: hash}},
+
+		{name: "count without hash", count: "42", wantErr: true},
+		{name: "hash without count", hash: hash, wantErr: true},
+		{name: "zero count", count: "0", hash: hash, wantErr: true},
+		{name: "negative count", count: "-1", hash: hash, wantErr: true},
+		{name: "count not a number", count: "many", hash: hash, wantErr: true},
+		{name: "hash too short", count: "42", hash: hash[:62], wantErr: true},
+		{name: "hash not hex", count: "42", hash: strings.Repeat("zz", 32), wantErr: true},
+		{name: "uppercase hash", count: "42", hash: strings.ToUpper(hash), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChainAnchor(tt.count, tt.hash)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseChainAnchor() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseChainAnchor() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseChainAnchor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"strconv"
+	"strings"
 
 	"github.com/wb-go/wbf/ginext"
 	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
@@ -21,10 +26,11 @@ func NewItemHandler(itemUseCase *usecase.ItemUseCase) *ItemHandler {
 }
 
 type createItemRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Quantity    int     `json:"quantity" binding:"required,min=0"`
-	Price       float64 `json:"price" binding:"required,min=0"`
+	SKU         string   `json:"sku" binding:"required"`
+	Barcodes    []string `json:"barcodes"`
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Price       float64  `json:"price" binding:"required,min=0"`
 }
 
 func (h *ItemHandler) Create(c *ginext.Context) {
@@ -41,28 +47,114 @@ func (h *ItemHandler) Create(c *ginext.Context) {
 	}
 
 	item := &entity.Item{
+		SKU:         req.SKU,
+		Barcodes:    req.Barcodes,
 		Name:        req.Name,
 		Description: req.Description,
-		Quantity:    req.Quantity,
 		Price:       req.Price,
 	}
 
-	if err := h.itemUseCase.Create(c.Request.Context(), item, user.Username); err != nil {
-		response.Error(c, 500, "failed to create item")
+	if err := h.itemUseCase.Create(c.Request.Context(), item, auditMeta(c, user)); err != nil {
+		switch {
+		case isInvalidItem(err):
+			response.Error(c, 400, err.Error())
+		case isItemCodeConflict(err):
+			response.Error(c, 409, err.Error())
+		default:
+			response.Error(c, 500, "failed to create item")
+		}
 		return
 	}
 
+	c.Header("ETag", itemETag(item))
 	response.Success(c, 201, item)
 }
 
+const (
+	defaultItemLimit = 100
+	maxItemLimit     = 1000
+)
+
 func (h *ItemHandler) GetAll(c *ginext.Context) {
-	items, err := h.itemUseCase.GetAll(c.Request.Context())
+	filter, err := parseItemFilter(c)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	items, total, err := h.itemUseCase.GetAll(c.Request.Context(), filter)
 	if err != nil {
 		response.Error(c, 500, "failed to get items")
 		return
 	}
 
-	response.Success(c, 200, items)
+	etag := itemsETag(items, total)
+	c.Header("ETag", etag)
+	if notModified(c, etag) {
+		c.Status(304)
+		return
+	}
+
+	response.SuccessWithMeta(c, 200, items, response.NewPageMeta(total, filter.Limit, filter.Offset))
+}
+
+func (h *ItemHandler) GetDeleted(c *ginext.Context) {
+	filter, err := parseItemFilter(c)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	items, total, err := h.itemUseCase.GetDeleted(c.Request.Context(), filter)
+	if err != nil {
+		response.Error(c, 500, "failed to get deleted items")
+		return
+	}
+
+	response.SuccessWithMeta(c, 200, items, response.NewPageMeta(total, filter.Limit, filter.Offset))
+}
+
+func parseItemFilter(c *ginext.Context) (*entity.ItemFilter, error) {
+	filter := &entity.ItemFilter{}
+
+	if name := c.Query("name"); name != "" {
+		filter.Name = &name
+	}
+
+	filter.QuantityMin = queryInt(c, "quantity_min")
+	filter.QuantityMax = queryInt(c, "quantity_max")
+	filter.WarehouseID = queryInt(c, "warehouse_id")
+	filter.PriceMin = queryFloat(c, "price_min")
+	filter.PriceMax = queryFloat(c, "price_max")
+	filter.CreatedFrom = queryTime(c, "created_from")
+	filter.CreatedTo = queryTime(c, "created_to")
+	filter.UpdatedFrom = queryTime(c, "updated_from")
+	filter.UpdatedTo = queryTime(c, "updated_to")
+
+	if sortBy := c.Query("sort_by"); sortBy != "" {
+		filter.SortBy = entity.ItemSortField(sortBy)
+		if !filter.SortBy.IsValid() {
+			return nil, errors.New("invalid sort field")
+		}
+	}
+
+	if sortOrder := c.Query("sort_order"); sortOrder != "" {
+		filter.SortOrder = entity.SortOrder(strings.ToLower(sortOrder))
+		if !filter.SortOrder.IsValid() {
+			return nil, errors.New("invalid sort order")
+		}
+	}
+
+	filter.Limit = defaultItemLimit
+	if limit := queryInt(c, "limit"); limit != nil && *limit > 0 {
+		filter.Limit = min(*limit, maxItemLimit)
+	}
+
+	if offset := queryInt(c, "offset"); offset != nil && *offset >= 0 {
+		filter.Offset = *offset
+	}
+
+	return filter, nil
 }
 
 func (h *ItemHandler) GetByID(c *ginext.Context) {
@@ -83,14 +175,44 @@ func (h *ItemHandler) GetByID(c *ginext.Context) {
 		return
 	}
 
+	etag := itemETag(item)
+	c.Header("ETag", etag)
+	if notModified(c, etag) {
+		c.Status(304)
+		return
+	}
+
+	response.Success(c, 200, item)
+}
+
+// GetByCode resolves a scanned barcode or an SKU to the item it belongs to.
+func (h *ItemHandler) GetByCode(c *ginext.Context) {
+	item, err := h.itemUseCase.GetByCode(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		if err == entity.ErrItemNotFound {
+			response.Error(c, 404, err.Error())
+			return
+		}
+		response.Error(c, 500, "failed to get item")
+		return
+	}
+
+	etag := itemETag(item)
+	c.Header("ETag", etag)
+	if notModified(c, etag) {
+		c.Status(304)
+		return
+	}
+
 	response.Success(c, 200, item)
 }
 
 type updateItemRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Quantity    int     `json:"quantity" binding:"required,min=0"`
-	Price       float64 `json:"price" binding:"required,min=0"`
+	SKU         string   `json:"sku" binding:"required"`
+	Barcodes    []string `json:"barcodes"`
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Price       float64  `json:"price" binding:"required,min=0"`
 }
 
 func (h *ItemHandler) Update(c *ginext.Context) {
@@ -101,6 +223,11 @@ func (h *ItemHandler) Update(c *ginext.Context) {
 		return
 	}
 
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
 	var req updateItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, 400, "invalid request body")
@@ -115,24 +242,147 @@ func (h *ItemHandler) Update(c *ginext.Context) {
 
 	item := &entity.Item{
 		ID:          id,
+		SKU:         req.SKU,
+		Barcodes:    req.Barcodes,
 		Name:        req.Name,
 		Description: req.Description,
-		Quantity:    req.Quantity,
 		Price:       req.Price,
+		Version:     version,
 	}
 
-	if err := h.itemUseCase.Update(c.Request.Context(), item, user.Username); err != nil {
-		if err == entity.ErrItemNotFound {
+	if err := h.itemUseCase.Update(c.Request.Context(), item, auditMeta(c, user)); err != nil {
+		switch {
+		case errors.Is(err, entity.ErrItemNotFound):
 			response.Error(c, 404, err.Error())
-			return
+		case errors.Is(err, entity.ErrVersionConflict):
+			h.respondVersionConflict(c, id)
+		case isInvalidItem(err):
+			response.Error(c, 400, err.Error())
+		case isItemCodeConflict(err):
+			response.Error(c, 409, err.Error())
+		default:
+			response.Error(c, 500, "failed to update item")
+		}
+		return
+	}
+
+	c.Header("ETag", itemETag(item))
+	response.Success(c, 200, item)
+}
+
+const mergePatchContentType = "application/merge-patch+json"
+
+func (h *ItemHandler) Patch(c *ginext.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(c, 400, "invalid item id")
+		return
+	}
+
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	if ct := c.ContentType(); ct != mergePatchContentType && ct != "application/json" {
+		response.Error(c, 415, "content type must be "+mergePatchContentType)
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	patch, err := parseItemPatch(body)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	item, err := h.itemUseCase.Patch(c.Request.Context(), id, patch, version, auditMeta(c, user))
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrItemNotFound):
+			response.Error(c, 404, err.Error())
+		case errors.Is(err, entity.ErrVersionConflict):
+			h.respondVersionConflict(c, id)
+		case isInvalidItem(err):
+			response.Error(c, 400, err.Error())
+		case isItemCodeConflict(err):
+			response.Error(c, 409, err.Error())
+		default:
+			response.Error(c, 500, "failed to update item")
 		}
-		response.Error(c, 500, "failed to update item")
 		return
 	}
 
+	c.Header("ETag", itemETag(item))
 	response.Success(c, 200, item)
 }
 
+// parseItemPatch decodes a JSON Merge Patch document. Members set to null are
+// removed from the target, which is only allowed for optional fields.
+func parseItemPatch(body []byte) (*entity.ItemPatch, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil || doc == nil {
+		return nil, entity.ErrInvalidPatch
+	}
+
+	patch := &entity.ItemPatch{}
+	for field, raw := range doc {
+		isNull := string(raw) == "null"
+
+		var err error
+		switch field {
+		case "sku":
+			if isNull {
+				return nil, entity.ErrInvalidSKU
+			}
+			err = json.Unmarshal(raw, &patch.SKU)
+		case "barcodes":
+			if isNull {
+				patch.Barcodes = &[]string{}
+				continue
+			}
+			err = json.Unmarshal(raw, &patch.Barcodes)
+		case "name":
+			if isNull {
+				return nil, entity.ErrInvalidItemName
+			}
+			err = json.Unmarshal(raw, &patch.Name)
+		case "description":
+			if isNull {
+				empty := ""
+				patch.Description = &empty
+				continue
+			}
+			err = json.Unmarshal(raw, &patch.Description)
+		case "price":
+			if isNull {
+				return nil, entity.ErrInvalidPrice
+			}
+			err = json.Unmarshal(raw, &patch.Price)
+		default:
+			return nil, fmt.Errorf("%w: field %q cannot be patched", entity.ErrInvalidPatch, field)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid value for %q", entity.ErrInvalidPatch, field)
+		}
+	}
+
+	return patch, nil
+}
+
 func (h *ItemHandler) Delete(c *ginext.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
@@ -141,20 +391,89 @@ func (h *ItemHandler) Delete(c *ginext.Context) {
 		return
 	}
 
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
 	user, err := middleware.GetUserFromContext(c)
 	if err != nil {
 		response.Error(c, 401, entity.ErrUnauthorized.Error())
 		return
 	}
 
-	if err := h.itemUseCase.Delete(c.Request.Context(), id, user.Username); err != nil {
+	if err := h.itemUseCase.Delete(c.Request.Context(), id, version, auditMeta(c, user)); err != nil {
 		if err == entity.ErrItemNotFound {
 			response.Error(c, 404, err.Error())
 			return
 		}
+		if err == entity.ErrVersionConflict {
+			h.respondVersionConflict(c, id)
+			return
+		}
 		response.Error(c, 500, "failed to delete item")
 		return
 	}
 
 	response.Success(c, 200, ginext.H{"message": "item deleted successfully"})
 }
+
+func (h *ItemHandler) Restore(c *ginext.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.Error(c, 400, "invalid item id")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	item, err := h.itemUseCase.Restore(c.Request.Context(), id, auditMeta(c, user))
+	if err != nil {
+		switch err {
+		case entity.ErrItemNotFound:
+			response.Error(c, 404, err.Error())
+		case entity.ErrItemNotDeleted:
+			response.Error(c, 409, err.Error())
+		default:
+			response.Error(c, 500, "failed to restore item")
+		}
+		return
+	}
+
+	c.Header("ETag", itemETag(item))
+	response.Success(c, 200, item)
+}
+
+func isInvalidItem(err error) bool {
+	return errors.Is(err, entity.ErrInvalidItemName) ||
+		errors.Is(err, entity.ErrInvalidQuantity) ||
+		errors.Is(err, entity.ErrInvalidPrice) ||
+		errors.Is(err, entity.ErrInvalidSKU) ||
+		errors.Is(err, entity.ErrInvalidBarcode)
+}
+
+func isItemCodeConflict(err error) bool {
+	return errors.Is(err, entity.ErrSKUExists) || errors.Is(err, entity.ErrBarcodeExists)
+}
+
+// respondVersionConflict answers 412 with the item as it is stored now, so the
+// client can merge its change and retry with the fresh ETag.
+func (h *ItemHandler) respondVersionConflict(c *ginext.Context, id int) {
+	current, err := h.itemUseCase.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if err == entity.ErrItemNotFound {
+			response.Error(c, 404, err.Error())
+			return
+		}
+		response.Error(c, 412, entity.ErrVersionConflict.Error())
+		return
+	}
+
+	c.Header("ETag", itemETag(current))
+	response.ErrorWithData(c, 412, entity.ErrVersionConflict.Error(), current)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+func TestParseItemPatch(t *testing.T) {
+	str := func(s string) *string { return &s }
+	price := func(p float64) *float64 { return &p }
+
+	tests := []struct {
+		name    string
+		body    string
+		want    *entity.ItemPatch
+		wantErr error
+	}{
+		{name: "empty document", body: `{}`, want: &entity.ItemPatch{}},
+		{name: "absent fields stay nil", body: `{"name":"Bolt"}`, want: &entity.ItemPatch{Name: str("Bolt")}},
+		{
+			name: "all fields",
+			body: `{"name":"Bolt","description":"M8","price":1.5}`,
+			want: &entity.ItemPatch{Name: str("Bolt"), Description: str("M8"), Price: price(1.5)},
+		},
+		{name: "null description clears it", body: `{"description":null}`, want: &entity.ItemPatch{Description: str("")}},
+		{name: "empty description", body: `{"description":""}`, want: &entity.ItemPatch{Description: str("")}},
+		{name: "sku", body: `{"sku":"BOLT-M8"}`, want: &entity.ItemPatch{SKU: str("BOLT-M8")}},
+		{name: "barcodes", body: `{"barcodes":["4006381333931"]}`, want: &entity.ItemPatch{Barcodes: &[]string{"4006381333931"}}},
+		{name: "null barcodes clears them", body: `{"barcodes":null}`, want: &entity.ItemPatch{Barcodes: &[]string{}}},
+
+		{name: "null name", body: `{"name":null}`, wantErr: entity.ErrInvalidItemName},
+		{name: "quantity is set through stock", body: `{"quantity":5}`, wantErr: entity.ErrInvalidPatch},
+		{name: "null sku", body: `{"sku":null}`, wantErr: entity.ErrInvalidSKU},
+		{name: "barcodes not a list", body: `{"barcodes":"4006381333931"}`, wantErr: entity.ErrInvalidPatch},
+		{name: "null price", body: `{"price":null}`, wantErr: entity.ErrInvalidPrice},
+		{name: "unknown field", body: `{"id":5}`, wantErr: entity.ErrInvalidPatch},
+		{name: "wrong value type", body: `{"price":"5"}`, wantErr: entity.ErrInvalidPatch},
+		{name: "not an object", body: `["name"]`, wantErr: entity.ErrInvalidPatch},
+		{name: "null document", body: `null`, wantErr: entity.ErrInvalidPatch},
+		{name: "invalid JSON", body: `{"name":`, wantErr: entity.ErrInvalidPatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseItemPatch([]byte(tt.body))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("parseItemPatch(%s) = %v, want %v", tt.body, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseItemPatch(%s) = %v, want nil", tt.body, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseItemPatch(%s) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
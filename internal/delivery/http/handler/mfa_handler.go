@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+)
+
+type mfaTokenRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// StartMFAEnrollment is the enrollment step of a login for roles that
+// require MFA. It is authorized by the pending token, not by a session.
+func (h *AuthHandler) StartMFAEnrollment(c *ginext.Context) {
+	var req mfaTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	enrollment, err := h.authUseCase.StartMFAEnrollment(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		respondMFAError(c, err, "failed to start mfa enrollment")
+		return
+	}
+
+	response.Success(c, 200, enrollment)
+}
+
+type verifyMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (h *AuthHandler) VerifyMFA(c *ginext.Context) {
+	var req verifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	result, err := h.authUseCase.CompleteMFALogin(c.Request.Context(), req.MFAToken, req.Code, req.RecoveryCode, c.ClientIP())
+	if err != nil {
+		respondMFAError(c, err, "internal server error")
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+func (h *AuthHandler) EnrollMFA(c *ginext.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	enrollment, err := h.authUseCase.BeginMFAEnrollment(c.Request.Context(), user)
+	if err != nil {
+		respondMFAError(c, err, "failed to start mfa enrollment")
+		return
+	}
+
+	response.Success(c, 200, enrollment)
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func (h *AuthHandler) ConfirmMFA(c *ginext.Context) {
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	codes, err := h.authUseCase.ConfirmMFAEnrollment(c.Request.Context(), user, req.Code, c.ClientIP())
+	if err != nil {
+		respondMFAError(c, err, "failed to confirm mfa enrollment")
+		return
+	}
+
+	response.Success(c, 200, ginext.H{"recovery_codes": codes})
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(c *ginext.Context) {
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	codes, err := h.authUseCase.RegenerateRecoveryCodes(c.Request.Context(), user, req.Code, c.ClientIP())
+	if err != nil {
+		respondMFAError(c, err, "failed to regenerate recovery codes")
+		return
+	}
+
+	response.Success(c, 200, ginext.H{"recovery_codes": codes})
+}
+
+func (h *AuthHandler) DisableMFA(c *ginext.Context) {
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	if err := h.authUseCase.DisableMFA(c.Request.Context(), user, req.Code, c.ClientIP()); err != nil {
+		respondMFAError(c, err, "failed to disable mfa")
+		return
+	}
+
+	response.Success(c, 200, ginext.H{"message": "two-factor authentication disabled"})
+}
+
+func respondMFAError(c *ginext.Context, err error, fallback string) {
+	if respondThrottled(c, err) {
+		return
+	}
+
+	switch err {
+	case entity.ErrInvalidToken:
+		response.Error(c, 401, err.Error())
+	case entity.ErrInvalidMFACode, entity.ErrMFANotEnrolled:
+		response.Error(c, 400, err.Error())
+	case entity.ErrMFAAlreadyEnabled:
+		response.Error(c, 409, err.Error())
+	case entity.ErrMFARequired, entity.ErrForbidden, entity.ErrUserDisabled:
+		response.Error(c, 403, err.Error())
+	default:
+		response.Error(c, 500, fallback)
+	}
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword answers with a new session, since all existing ones,
+// including the caller's, are ended.
+func (h *AuthHandler) ChangePassword(c *ginext.Context) {
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	result, err := h.authUseCase.ChangePassword(c.Request.Context(), user, req.CurrentPassword, req.NewPassword, auditMeta(c, user))
+	if err != nil {
+		if respondThrottled(c, err) || respondPasswordError(c, err) {
+			return
+		}
+
+		switch err {
+		case entity.ErrInvalidCredentials:
+			response.Error(c, 400, "current password is incorrect")
+		case entity.ErrForbidden:
+			response.Error(c, 403, err.Error())
+		default:
+			response.Error(c, 500, "failed to change password")
+		}
+		return
+	}
+
+	respondLogin(c, result)
+}
+
+type forgotPasswordRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// ForgotPassword always answers 202, whether the account exists or not.
+func (h *AuthHandler) ForgotPassword(c *ginext.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	if err := h.authUseCase.RequestPasswordReset(c.Request.Context(), req.Username, c.ClientIP()); err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
+		response.Error(c, 500, "failed to request password reset")
+		return
+	}
+
+	response.Success(c, 202, ginext.H{"message": "if the account exists, a password reset link has been sent"})
+}
+
+type resetPasswordWithTokenRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+func (h *AuthHandler) ResetPassword(c *ginext.Context) {
+	var req resetPasswordWithTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	// The username is filled in by the use case once the token is resolved.
+	meta := auditMeta(c, &entity.User{})
+
+	if err := h.authUseCase.ResetPasswordWithToken(c.Request.Context(), req.Token, req.NewPassword, meta); err != nil {
+		if respondPasswordError(c, err) {
+			return
+		}
+
+		if err == entity.ErrInvalidResetToken {
+			response.Error(c, 400, err.Error())
+			return
+		}
+		response.Error(c, 500, "failed to reset password")
+		return
+	}
+
+	response.Success(c, 200, ginext.H{"message": "password has been reset"})
+}
+
+// respondPasswordError answers 400 if err means the new password was rejected.
+func respondPasswordError(c *ginext.Context, err error) bool {
+	if !errors.Is(err, entity.ErrWeakPassword) && err != entity.ErrPasswordReused {
+		return false
+	}
+
+	response.Error(c, 400, err.Error())
+	return true
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/wb-go/wbf/ginext"
+)
+
+func queryInt(c *ginext.Context, key string) *int {
+	str := c.Query(key)
+	if str == "" {
+		return nil
+	}
+
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		return nil
+	}
+
+	return &value
+}
+
+func queryFloat(c *ginext.Context, key string) *float64 {
+	str := c.Query(key)
+	if str == "" {
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &value
+}
+
+func queryTime(c *ginext.Context, key string) *time.Time {
+	str := c.Query(key)
+	if str == "" {
+		return nil
+	}
+
+	value, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+	"github.com/yokitheyo/WarehouseControl/internal/usecase"
+)
+
+type ReservationHandler struct {
+	reservationUseCase *usecase.ReservationUseCase
+}
+
+func NewReservationHandler(reservationUseCase *usecase.ReservationUseCase) *ReservationHandler {
+	return &ReservationHandler{
+		reservationUseCase: reservationUseCase,
+	}
+}
+
+type createReservationRequest struct {
+	ItemID    int        `json:"item_id" binding:"required"`
+	Quantity  int        `json:"quantity" binding:"required"`
+	Reference string     `json:"reference" binding:"required"`
+	Owner     string     `json:"owner"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Create holds stock of an item for an order. Without expires_at the
+// configured default lifetime applies; without owner the caller owns it.
+func (h *ReservationHandler) Create(c *ginext.Context) {
+	var req createReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	reservation := &entity.Reservation{
+		ItemID:    req.ItemID,
+		Quantity:  req.Quantity,
+		Reference: strings.TrimSpace(req.Reference),
+		Owner:     strings.TrimSpace(req.Owner),
+	}
+	if req.ExpiresAt != nil {
+		reservation.ExpiresAt = *req.ExpiresAt
+	}
+
+	if err := h.reservationUseCase.Create(c.Request.Context(), reservation, auditMeta(c, user)); err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	response.Success(c, 201, reservation)
+}
+
+const (
+	defaultReservationLimit = 50
+	maxReservationLimit     = 500
+)
+
+func (h *ReservationHandler) GetAll(c *ginext.Context) {
+	filter := &entity.ReservationFilter{
+		ItemID: queryInt(c, "item_id"),
+		Limit:  defaultReservationLimit,
+	}
+
+	if l := queryInt(c, "limit"); l != nil && *l > 0 {
+		filter.Limit = min(*l, maxReservationLimit)
+	}
+	if o := queryInt(c, "offset"); o != nil && *o >= 0 {
+		filter.Offset = *o
+	}
+	if owner := c.Query("owner"); owner != "" {
+		filter.Owner = &owner
+	}
+	if s := c.Query("status"); s != "" {
+		status := entity.ReservationStatus(s)
+		filter.Status = &status
+	}
+
+	reservations, total, err := h.reservationUseCase.GetAll(c.Request.Context(), filter)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	response.SuccessWithMeta(c, 200, reservations, response.NewPageMeta(total, filter.Limit, filter.Offset))
+}
+
+func (h *ReservationHandler) GetByID(c *ginext.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid reservation id")
+		return
+	}
+
+	reservation, err := h.reservationUseCase.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	response.Success(c, 200, reservation)
+}
+
+// Release ends a reservation before it expires. The reason is taken from the
+// change reason header, as for item changes.
+func (h *ReservationHandler) Release(c *ginext.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid reservation id")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	reservation, err := h.reservationUseCase.Release(c.Request.Context(), id, auditMeta(c, user))
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	response.Success(c, 200, reservation)
+}
+
+func respondReservationError(c *ginext.Context, err error) {
+	switch {
+	case errors.Is(err, entity.ErrReservationMissing), errors.Is(err, entity.ErrItemNotFound):
+		response.Error(c, 404, err.Error())
+	case errors.Is(err, entity.ErrInvalidReservation):
+		response.Error(c, 400, err.Error())
+	case errors.Is(err, entity.ErrStockUnavailable), errors.Is(err, entity.ErrReservationClosed):
+		response.Error(c, 409, err.Error())
+	default:
+		response.Error(c, 500, "failed to process reservation")
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/wb-go/wbf/zlog"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/usecase"
+)
+
+const (
+	ssoStateCookie = "sso_login"
+	ssoCookiePath  = "/api/auth/sso"
+	// ssoStateTTL is how long the user has to log in at the provider.
+	ssoStateTTL = 10 * time.Minute
+)
+
+type SSOHandler struct {
+	ssoUseCase *usecase.SSOUseCase
+}
+
+func NewSSOHandler(ssoUseCase *usecase.SSOUseCase) *SSOHandler {
+	return &SSOHandler{
+		ssoUseCase: ssoUseCase,
+	}
+}
+
+// Login sends the browser to the identity provider. State, nonce and PKCE
+// verifier wait for the callback in a cookie bound to this browser.
+func (h *SSOHandler) Login(c *ginext.Context) {
+	authURL, state, err := h.ssoUseCase.Start(c.Request.Context())
+	if err != nil {
+		zlog.Logger.Error().Err(err).Msg("Failed to start SSO login")
+		renderSSOResult(c, 502, nil, "identity provider is unavailable")
+		return
+	}
+
+	value, err := json.Marshal(state)
+	if err != nil {
+		renderSSOResult(c, 500, nil, "internal server error")
+		return
+	}
+
+	setSSOCookie(c, base64.RawURLEncoding.EncodeToString(value), int(ssoStateTTL.Seconds()))
+	c.Redirect(302, authURL)
+}
+
+// Callback is where the provider sends the browser back. It answers with a
+// page that stores the session like a password login does, or hands the MFA
+// challenge over to the login page.
+func (h *SSOHandler) Callback(c *ginext.Context) {
+	state, ok := readSSOState(c)
+	// The state is single use, whatever happens next.
+	setSSOCookie(c, "", -1)
+
+	if !ok || subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(state.State)) != 1 {
+		renderSSOResult(c, 400, nil, "invalid or expired login state")
+		return
+	}
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		renderSSOResult(c, 401, nil, "identity provider refused the login: "+providerErr)
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		renderSSOResult(c, 400, nil, "missing authorization code")
+		return
+	}
+
+	// The username is filled in once the identity is known.
+	meta := auditMeta(c, &entity.User{})
+
+	result, err := h.ssoUseCase.Finish(c.Request.Context(), code, state, meta)
+	if err != nil {
+		switch {
+		case err == entity.ErrSSONoRole, err == entity.ErrUserDisabled:
+			renderSSOResult(c, 403, nil, err.Error())
+		case err == entity.ErrUserExists:
+			renderSSOResult(c, 409, nil, "username is already used by a local account")
+		case errors.Is(err, entity.ErrSSOFailed):
+			zlog.Logger.Warn().Err(err).Msg("SSO login failed")
+			renderSSOResult(c, 401, nil, entity.ErrSSOFailed.Error())
+		default:
+			zlog.Logger.Error().Err(err).Msg("SSO login failed")
+			renderSSOResult(c, 500, nil, "internal server error")
+		}
+		return
+	}
+
+	if result.MFA != nil {
+		c.Header("Cache-Control", "no-store")
+		c.HTML(200, "sso_callback.html", ginext.H{
+			"MFA": mfaChallengeResponse{MFARequired: true, MFAChallenge: result.MFA},
+		})
+		return
+	}
+
+	renderSSOResult(c, 200, newLoginResponse(result), "")
+}
+
+func readSSOState(c *ginext.Context) (*entity.SSOLoginState, bool) {
+	raw, err := c.Cookie(ssoStateCookie)
+	if err != nil || raw == "" {
+		return nil, false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, false
+	}
+
+	state := &entity.SSOLoginState{}
+	if err := json.Unmarshal(data, state); err != nil || state.State == "" {
+		return nil, false
+	}
+
+	return state, true
+}
+
+// setSSOCookie uses SameSite=Lax: the callback is a cross-site top-level
+// navigation, which Lax still sends the cookie with.
+func setSSOCookie(c *ginext.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, value, maxAge, ssoCookiePath, "", c.Request.TLS != nil, true)
+}
+
+func renderSSOResult(c *ginext.Context, status int, session *loginResponse, errMsg string) {
+	c.Header("Cache-Control", "no-store")
+	c.HTML(status, "sso_callback.html", ginext.H{
+		"Session": session,
+		"Error":   errMsg,
+	})
+}
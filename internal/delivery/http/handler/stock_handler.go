@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+	"github.com/yokitheyo/WarehouseControl/internal/usecase"
+)
+
+type StockHandler struct {
+	stockUseCase *usecase.StockUseCase
+}
+
+func NewStockHandler(stockUseCase *usecase.StockUseCase) *StockHandler {
+	return &StockHandler{
+		stockUseCase: stockUseCase,
+	}
+}
+
+type setStockRequest struct {
+	Quantity *int `json:"quantity" binding:"required,min=0"`
+	// OverrideReservations records a count below the reserved quantity.
+	OverrideReservations bool `json:"override_reservations"`
+}
+
+type createMovementRequest struct {
+	LocationID int    `json:"location_id" binding:"required"`
+	Type       string `json:"type" binding:"required"`
+	Delta      int    `json:"delta" binding:"required"`
+	ReasonCode string `json:"reason_code" binding:"required"`
+	Reference  string `json:"reference"`
+}
+
+// CreateMovement posts a receipt, issue, adjustment, write-off or return to
+// the stock ledger.
+func (h *StockHandler) CreateMovement(c *ginext.Context) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid item id")
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		response.Error(c, 400, err.Error())
+		return
+	}
+
+	var req createMovementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	movement := &entity.StockMovement{
+		ItemID:     itemID,
+		LocationID: req.LocationID,
+		Type:       entity.MovementType(req.Type),
+		Delta:      req.Delta,
+		ReasonCode: strings.ToUpper(strings.TrimSpace(req.ReasonCode)),
+		Reference:  strings.TrimSpace(req.Reference),
+	}
+
+	result, err := h.stockUseCase.Move(c.Request.Context(), movement, version, auditMeta(c, user))
+	if err != nil {
+		respondStockError(c, err)
+		return
+	}
+
+	c.Header("ETag", itemETag(result.Item))
+	response.Success(c, 201, result)
+}
+
+const (
+	defaultMovementLimit = 50
+	maxMovementLimit     = 500
+)
+
+func (h *StockHandler) GetMovements(c *ginext.Context) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid item id")
+		return
+	}
+
+	limit := defaultMovementLimit
+	if l := queryInt(c, "limit"); l != nil && *l > 0 {
+		limit = min(*l, maxMovementLimit)
+	}
+
+	offset := 0
+	if o := queryInt(c, "offset"); o != nil && *o >= 0 {
+		offset = *o
+	}
+
+	movements, total, err := h.stockUseCase.GetMovements(c.Request.Context(), itemID, limit, offset)
+	if err != nil {
+		if errors.Is(err, entity.ErrItemNotFound) {
+			response.Error(c, 404, err.Error())
+			return
+		}
+		response.Error(c, 500, "failed to get stock movements")
+		return
+	}
+
+	response.SuccessWithMeta(c, 200, movements, response.NewPageMeta(total, limit, offset))
+}
+
+// SetLevel records a stock count at one location; the difference is posted
+// as a stocktake adjustment. A count overwrites the level the client last
+// read, so unlike a movement it requires If-Match, as item updates do.
+func (h *StockHandler) SetLevel(c *ginext.Context) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid item id")
+		return
+	}
+
+	locationID, err := strconv.Atoi(c.Param("location_id"))
+	if err != nil {
+		response.Error(c, 400, "invalid location id")
+		return
+	}
+
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req setStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	item, err := h.stockUseCase.SetStock(c.Request.Context(), itemID, locationID, *req.Quantity, version, req.OverrideReservations, auditMeta(c, user))
+	if err != nil {
+		respondStockError(c, err)
+		return
+	}
+
+	c.Header("ETag", itemETag(item))
+	response.Success(c, 200, item)
+}
+
+func respondStockError(c *ginext.Context, err error) {
+	switch {
+	case errors.Is(err, entity.ErrItemNotFound), errors.Is(err, entity.ErrLocationNotFound):
+		response.Error(c, 404, err.Error())
+	case errors.Is(err, entity.ErrVersionConflict):
+		response.Error(c, 412, err.Error())
+	case errors.Is(err, entity.ErrInvalidQuantity), errors.Is(err, entity.ErrInvalidMovement):
+		response.Error(c, 400, err.Error())
+	case errors.Is(err, entity.ErrInsufficientStock), errors.Is(err, entity.ErrStockUnavailable):
+		response.Error(c, 409, err.Error())
+	default:
+		response.Error(c, 500, "failed to change stock")
+	}
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+	"github.com/yokitheyo/WarehouseControl/internal/usecase"
+)
+
+type TransferHandler struct {
+	transferUseCase *usecase.TransferUseCase
+}
+
+func NewTransferHandler(transferUseCase *usecase.TransferUseCase) *TransferHandler {
+	return &TransferHandler{
+		transferUseCase: transferUseCase,
+	}
+}
+
+type transferLineRequest struct {
+	ItemID         int `json:"item_id" binding:"required"`
+	FromLocationID int `json:"from_location_id" binding:"required"`
+	Quantity       int `json:"quantity" binding:"required"`
+}
+
+type createTransferRequest struct {
+	SourceWarehouseID      int                   `json:"source_warehouse_id" binding:"required"`
+	DestinationWarehouseID int                   `json:"destination_warehouse_id" binding:"required"`
+	Note                   string                `json:"note"`
+	Lines                  []transferLineRequest `json:"lines" binding:"required,dive"`
+}
+
+type receiptLineRequest struct {
+	LineID     int    `json:"line_id" binding:"required"`
+	LocationID int    `json:"location_id" binding:"required"`
+	Quantity   *int   `json:"quantity" binding:"required"`
+	Note       string `json:"note"`
+}
+
+type receiveTransferRequest struct {
+	Lines []receiptLineRequest `json:"lines" binding:"required,dive"`
+}
+
+// Create stores a draft transfer order.
+func (h *TransferHandler) Create(c *ginext.Context) {
+	var req createTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	order := &entity.TransferOrder{
+		SourceWarehouseID:      req.SourceWarehouseID,
+		DestinationWarehouseID: req.DestinationWarehouseID,
+		Note:                   strings.TrimSpace(req.Note),
+		Lines:                  make([]entity.TransferLine, 0, len(req.Lines)),
+	}
+	for _, line := range req.Lines {
+		order.Lines = append(order.Lines, entity.TransferLine{
+			ItemID:         line.ItemID,
+			FromLocationID: line.FromLocationID,
+			Quantity:       line.Quantity,
+		})
+	}
+
+	if err := h.transferUseCase.Create(c.Request.Context(), order, auditMeta(c, user)); err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	response.Success(c, 201, order)
+}
+
+const (
+	defaultTransferLimit = 50
+	maxTransferLimit     = 500
+)
+
+func (h *TransferHandler) GetAll(c *ginext.Context) {
+	filter := &entity.TransferFilter{Limit: defaultTransferLimit}
+
+	if l := queryInt(c, "limit"); l != nil && *l > 0 {
+		filter.Limit = min(*l, maxTransferLimit)
+	}
+	if o := queryInt(c, "offset"); o != nil && *o >= 0 {
+		filter.Offset = *o
+	}
+	if s := c.Query("status"); s != "" {
+		status := entity.TransferStatus(s)
+		filter.Status = &status
+	}
+
+	orders, total, err := h.transferUseCase.GetAll(c.Request.Context(), filter)
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	response.SuccessWithMeta(c, 200, orders, response.NewPageMeta(total, filter.Limit, filter.Offset))
+}
+
+func (h *TransferHandler) GetByID(c *ginext.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid transfer id")
+		return
+	}
+
+	order, err := h.transferUseCase.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	response.Success(c, 200, order)
+}
+
+// Dispatch takes the order's goods out of the source warehouse; they stay in
+// transit until the order is received.
+func (h *TransferHandler) Dispatch(c *ginext.Context) {
+	h.transition(c, func(id int, meta *entity.AuditMeta) (*entity.TransferOrder, error) {
+		return h.transferUseCase.Dispatch(c.Request.Context(), id, meta)
+	})
+}
+
+// Receive books the goods into the destination warehouse. There is one receipt
+// per order and it is final: every line must be listed, and a quantity below
+// the dispatched one is recorded as a discrepancy and written off as a
+// transfer loss. Goods that turn up later are booked with a manual receipt.
+func (h *TransferHandler) Receive(c *ginext.Context) {
+	var req receiveTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	receipt := make([]entity.TransferReceiptLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		receipt = append(receipt, entity.TransferReceiptLine{
+			LineID:     line.LineID,
+			LocationID: line.LocationID,
+			Quantity:   *line.Quantity,
+			Note:       strings.TrimSpace(line.Note),
+		})
+	}
+
+	h.transition(c, func(id int, meta *entity.AuditMeta) (*entity.TransferOrder, error) {
+		return h.transferUseCase.Receive(c.Request.Context(), id, receipt, meta)
+	})
+}
+
+func (h *TransferHandler) Cancel(c *ginext.Context) {
+	h.transition(c, func(id int, meta *entity.AuditMeta) (*entity.TransferOrder, error) {
+		return h.transferUseCase.Cancel(c.Request.Context(), id, meta)
+	})
+}
+
+func (h *TransferHandler) transition(c *ginext.Context, run func(id int, meta *entity.AuditMeta) (*entity.TransferOrder, error)) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid transfer id")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	order, err := run(id, auditMeta(c, user))
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	response.Success(c, 200, order)
+}
+
+func (h *TransferHandler) GetHistory(c *ginext.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid transfer id")
+		return
+	}
+
+	limit := defaultTransferLimit
+	if l := queryInt(c, "limit"); l != nil && *l > 0 {
+		limit = min(*l, maxTransferLimit)
+	}
+
+	offset := 0
+	if o := queryInt(c, "offset"); o != nil && *o >= 0 {
+		offset = *o
+	}
+
+	history, total, err := h.transferUseCase.GetHistory(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		respondTransferError(c, err)
+		return
+	}
+
+	response.SuccessWithMeta(c, 200, history, response.NewPageMeta(total, limit, offset))
+}
+
+func respondTransferError(c *ginext.Context, err error) {
+	switch {
+	case errors.Is(err, entity.ErrTransferNotFound), errors.Is(err, entity.ErrItemNotFound),
+		errors.Is(err, entity.ErrLocationNotFound), errors.Is(err, entity.ErrWarehouseNotFound):
+		response.Error(c, 404, err.Error())
+	case errors.Is(err, entity.ErrInvalidTransfer), errors.Is(err, entity.ErrInvalidReceipt):
+		response.Error(c, 400, err.Error())
+	case errors.Is(err, entity.ErrTransferStatus), errors.Is(err, entity.ErrInsufficientStock),
+		errors.Is(err, entity.ErrStockUnavailable):
+		response.Error(c, 409, err.Error())
+	default:
+		response.Error(c, 500, "failed to process transfer order")
+	}
+}
@@ -0,0 +1,337 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+	"github.com/yokitheyo/WarehouseControl/internal/usecase"
+)
+
+// UserHandler serves the admin user-management API.
+type UserHandler struct {
+	authUseCase *usecase.AuthUseCase
+}
+
+func NewUserHandler(authUseCase *usecase.AuthUseCase) *UserHandler {
+	return &UserHandler{
+		authUseCase: authUseCase,
+	}
+}
+
+const (
+	defaultUserLimit = 100
+	maxUserLimit     = 1000
+)
+
+func (h *UserHandler) GetAll(c *ginext.Context) {
+	filter := &entity.UserFilter{}
+
+	if username := c.Query("username"); username != "" {
+		filter.Username = &username
+	}
+
+	if roleStr := c.Query("role"); roleStr != "" {
+		role := entity.Role(roleStr)
+		filter.Role = &role
+	}
+
+	if disabledStr := c.Query("disabled"); disabledStr != "" {
+		disabled, err := strconv.ParseBool(disabledStr)
+		if err != nil {
+			response.Error(c, 400, "invalid disabled flag")
+			return
+		}
+		filter.Disabled = &disabled
+	}
+
+	filter.Limit, filter.Offset = parseUserPage(c)
+
+	users, total, err := h.authUseCase.ListUsers(c.Request.Context(), filter)
+	if err != nil {
+		response.Error(c, 500, "failed to get users")
+		return
+	}
+
+	response.SuccessWithMeta(c, 200, users, response.NewPageMeta(total, filter.Limit, filter.Offset))
+}
+
+func (h *UserHandler) GetByID(c *ginext.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.authUseCase.GetUser(c.Request.Context(), id)
+	if err != nil {
+		respondUserError(c, err, "failed to get user")
+		return
+	}
+
+	response.Success(c, 200, user)
+}
+
+func (h *UserHandler) GetHistory(c *ginext.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	limit, offset := parseUserPage(c)
+
+	history, total, err := h.authUseCase.GetUserHistory(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		response.Error(c, 500, "failed to get user history")
+		return
+	}
+
+	response.SuccessWithMeta(c, 200, history, response.NewPageMeta(total, limit, offset))
+}
+
+type createUserRequest struct {
+	Username string      `json:"username" binding:"required"`
+	Password string      `json:"password" binding:"required"`
+	Role     entity.Role `json:"role" binding:"required"`
+}
+
+func (h *UserHandler) Create(c *ginext.Context) {
+	var req createUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	admin, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	user, err := h.authUseCase.CreateUser(c.Request.Context(), req.Username, req.Password, req.Role, auditMeta(c, admin))
+	if err != nil {
+		if respondPasswordError(c, err) {
+			return
+		}
+
+		switch err {
+		case entity.ErrInvalidRole:
+			response.Error(c, 400, err.Error())
+		case entity.ErrUserExists:
+			response.Error(c, 409, err.Error())
+		default:
+			response.Error(c, 500, "failed to create user")
+		}
+		return
+	}
+
+	response.Success(c, 201, user)
+}
+
+type updateRoleRequest struct {
+	Role entity.Role `json:"role" binding:"required"`
+}
+
+func (h *UserHandler) UpdateRole(c *ginext.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req updateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	admin, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	user, err := h.authUseCase.UpdateRole(c.Request.Context(), id, req.Role, auditMeta(c, admin))
+	if err != nil {
+		respondUserError(c, err, "failed to update role")
+		return
+	}
+
+	response.Success(c, 200, user)
+}
+
+func (h *UserHandler) Disable(c *ginext.Context) {
+	h.setDisabled(c, true)
+}
+
+func (h *UserHandler) Enable(c *ginext.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *UserHandler) setDisabled(c *ginext.Context, disabled bool) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	admin, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	user, err := h.authUseCase.SetDisabled(c.Request.Context(), id, disabled, auditMeta(c, admin))
+	if err != nil {
+		respondUserError(c, err, "failed to update user status")
+		return
+	}
+
+	response.Success(c, 200, user)
+}
+
+func (h *UserHandler) Unlock(c *ginext.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	admin, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	user, err := h.authUseCase.UnlockUser(c.Request.Context(), id, auditMeta(c, admin))
+	if err != nil {
+		respondUserError(c, err, "failed to unlock user")
+		return
+	}
+
+	response.Success(c, 200, user)
+}
+
+func (h *UserHandler) ResetMFA(c *ginext.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	admin, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	user, err := h.authUseCase.ResetMFA(c.Request.Context(), id, auditMeta(c, admin))
+	if err != nil {
+		respondUserError(c, err, "failed to reset mfa")
+		return
+	}
+
+	response.Success(c, 200, user)
+}
+
+type resetPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+func (h *UserHandler) ResetPassword(c *ginext.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	admin, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	if _, err := h.authUseCase.ResetPassword(c.Request.Context(), id, req.Password, auditMeta(c, admin)); err != nil {
+		respondUserError(c, err, "failed to reset password")
+		return
+	}
+
+	response.Success(c, 200, ginext.H{"message": "password reset"})
+}
+
+func (h *UserHandler) Delete(c *ginext.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	admin, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		response.Error(c, 401, entity.ErrUnauthorized.Error())
+		return
+	}
+
+	if err := h.authUseCase.DeleteUser(c.Request.Context(), id, auditMeta(c, admin)); err != nil {
+		respondUserError(c, err, "failed to delete user")
+		return
+	}
+
+	response.Success(c, 200, ginext.H{"message": "user deleted successfully"})
+}
+
+func (h *UserHandler) RevokeSessions(c *ginext.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authUseCase.RevokeSessions(c.Request.Context(), id); err != nil {
+		respondUserError(c, err, "failed to revoke sessions")
+		return
+	}
+
+	response.Success(c, 200, ginext.H{"message": "sessions revoked"})
+}
+
+func parseUserID(c *ginext.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.Error(c, 400, "invalid user id")
+		return 0, false
+	}
+
+	return id, true
+}
+
+func parseUserPage(c *ginext.Context) (int, int) {
+	limit := defaultUserLimit
+	if l := queryInt(c, "limit"); l != nil && *l > 0 {
+		limit = min(*l, maxUserLimit)
+	}
+
+	offset := 0
+	if o := queryInt(c, "offset"); o != nil && *o >= 0 {
+		offset = *o
+	}
+
+	return limit, offset
+}
+
+func respondUserError(c *ginext.Context, err error, fallback string) {
+	if respondPasswordError(c, err) {
+		return
+	}
+
+	switch err {
+	case entity.ErrUserNotFound:
+		response.Error(c, 404, err.Error())
+	case entity.ErrInvalidRole:
+		response.Error(c, 400, err.Error())
+	case entity.ErrSelfModification:
+		response.Error(c, 409, err.Error())
+	default:
+		response.Error(c, 500, fallback)
+	}
+}
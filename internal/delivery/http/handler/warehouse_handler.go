@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+	"github.com/yokitheyo/WarehouseControl/internal/usecase"
+)
+
+type WarehouseHandler struct {
+	warehouseUseCase *usecase.WarehouseUseCase
+}
+
+func NewWarehouseHandler(warehouseUseCase *usecase.WarehouseUseCase) *WarehouseHandler {
+	return &WarehouseHandler{
+		warehouseUseCase: warehouseUseCase,
+	}
+}
+
+type createWarehouseRequest struct {
+	Code    string `json:"code" binding:"required"`
+	Name    string `json:"name" binding:"required"`
+	Address string `json:"address"`
+}
+
+func (h *WarehouseHandler) Create(c *ginext.Context) {
+	var req createWarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	warehouse := &entity.Warehouse{
+		Code:    req.Code,
+		Name:    req.Name,
+		Address: req.Address,
+	}
+
+	if err := h.warehouseUseCase.CreateWarehouse(c.Request.Context(), warehouse); err != nil {
+		switch {
+		case errors.Is(err, entity.ErrInvalidWarehouse):
+			response.Error(c, 400, err.Error())
+		case errors.Is(err, entity.ErrWarehouseExists):
+			response.Error(c, 409, err.Error())
+		default:
+			response.Error(c, 500, "failed to create warehouse")
+		}
+		return
+	}
+
+	response.Success(c, 201, warehouse)
+}
+
+func (h *WarehouseHandler) GetAll(c *ginext.Context) {
+	warehouses, err := h.warehouseUseCase.GetWarehouses(c.Request.Context())
+	if err != nil {
+		response.Error(c, 500, "failed to get warehouses")
+		return
+	}
+
+	response.Success(c, 200, warehouses)
+}
+
+type createLocationRequest struct {
+	WarehouseID int    `json:"warehouse_id" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+	Zone        string `json:"zone"`
+}
+
+func (h *WarehouseHandler) CreateLocation(c *ginext.Context) {
+	var req createLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, 400, "invalid request body")
+		return
+	}
+
+	location := &entity.Location{
+		WarehouseID: req.WarehouseID,
+		Code:        req.Code,
+		Zone:        req.Zone,
+	}
+
+	if err := h.warehouseUseCase.CreateLocation(c.Request.Context(), location); err != nil {
+		switch {
+		case errors.Is(err, entity.ErrInvalidLocation):
+			response.Error(c, 400, err.Error())
+		case errors.Is(err, entity.ErrWarehouseNotFound):
+			response.Error(c, 404, err.Error())
+		case errors.Is(err, entity.ErrLocationExists):
+			response.Error(c, 409, err.Error())
+		default:
+			response.Error(c, 500, "failed to create location")
+		}
+		return
+	}
+
+	response.Success(c, 201, location)
+}
+
+func (h *WarehouseHandler) GetLocations(c *ginext.Context) {
+	locations, err := h.warehouseUseCase.GetLocations(c.Request.Context(), queryInt(c, "warehouse_id"))
+	if err != nil {
+		response.Error(c, 500, "failed to get locations")
+		return
+	}
+
+	response.Success(c, 200, locations)
+}
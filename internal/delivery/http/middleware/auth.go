@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"strings"
 
 	"github.com/wb-go/wbf/ginext"
@@ -11,44 +12,75 @@ import (
 
 const (
 	authorizationHeader = "Authorization"
+	apiKeyHeader        = "X-API-Key"
 	userContextKey      = "user"
 )
 
-func AuthMiddleware(jwtManager *jwt.Manager) ginext.HandlerFunc {
-	return func(c *ginext.Context) {
-		var tokenString string
-
-		authHeader := c.GetHeader(authorizationHeader)
-		if authHeader != "" {
-			parts := strings.Split(authHeader, " ")
-			if len(parts) == 2 && parts[0] == "Bearer" {
-				tokenString = parts[1]
-			}
+// ExtractToken returns the bearer token from the Authorization header or,
+// failing that, from the token cookie.
+func ExtractToken(c *ginext.Context) string {
+	authHeader := c.GetHeader(authorizationHeader)
+	if authHeader != "" {
+		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			return parts[1]
 		}
+	}
 
-		if tokenString == "" {
-			token, err := c.Cookie("token")
-			if err == nil && token != "" {
-				tokenString = token
+	token, err := c.Cookie("token")
+	if err == nil && token != "" {
+		return token
+	}
+
+	return ""
+}
+
+// UserProvider resolves the account behind a verified token. It fails for
+// accounts that were disabled or deleted after the token was issued.
+type UserProvider interface {
+	GetActiveUser(ctx context.Context, username string) (*entity.User, error)
+}
+
+// APIKeyAuthenticator resolves a personal API key to its owner, limited to the
+// permissions of the key.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*entity.User, error)
+}
+
+// AuthMiddleware accepts either an API key in the X-API-Key header or a JWT.
+func AuthMiddleware(jwtManager *jwt.Manager, users UserProvider, apiKeys APIKeyAuthenticator) ginext.HandlerFunc {
+	return func(c *ginext.Context) {
+		if key := c.GetHeader(apiKeyHeader); key != "" {
+			user, err := apiKeys.Authenticate(c.Request.Context(), key)
+			if err != nil {
+				respondAuthError(c, err)
+				return
 			}
+			c.Set(userContextKey, user)
+			c.Next()
+			return
 		}
 
+		tokenString := ExtractToken(c)
 		if tokenString == "" {
 			response.Error(c, 401, entity.ErrUnauthorized.Error())
 			c.Abort()
 			return
 		}
 
-		claims, err := jwtManager.Verify(tokenString)
+		claims, err := jwtManager.Verify(c.Request.Context(), tokenString)
 		if err != nil {
 			response.Error(c, 401, "invalid token")
 			c.Abort()
 			return
 		}
 
-		user := &entity.User{
-			Username: claims.Username,
-			Role:     claims.Role,
+		// The stored account wins over the claims, so role changes apply to
+		// tokens that were issued before them.
+		user, err := users.GetActiveUser(c.Request.Context(), claims.Username)
+		if err != nil {
+			respondAuthError(c, err)
+			return
 		}
 		c.Set(userContextKey, user)
 
@@ -56,6 +88,18 @@ func AuthMiddleware(jwtManager *jwt.Manager) ginext.HandlerFunc {
 	}
 }
 
+func respondAuthError(c *ginext.Context, err error) {
+	switch err {
+	case entity.ErrUserDisabled:
+		response.Error(c, 401, err.Error())
+	case entity.ErrUserNotFound, entity.ErrInvalidToken:
+		response.Error(c, 401, "invalid token")
+	default:
+		response.Error(c, 500, "internal server error")
+	}
+	c.Abort()
+}
+
 func GetUserFromContext(c *ginext.Context) (*entity.User, error) {
 	value, exists := c.Get(userContextKey)
 	if !exists {
@@ -79,7 +123,7 @@ func AuthPageMiddleware(jwtManager *jwt.Manager) ginext.HandlerFunc {
 			return
 		}
 
-		claims, err := jwtManager.Verify(token)
+		claims, err := jwtManager.Verify(c.Request.Context(), token)
 		if err != nil {
 			c.Redirect(302, "/login")
 			c.Abort()
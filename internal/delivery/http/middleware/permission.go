@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"github.com/wb-go/wbf/ginext"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/response"
+)
+
+// RequirePermission lets the request through only if the authenticated user's
+// role grants perm. It must run after AuthMiddleware.
+func RequirePermission(perm entity.Permission) ginext.HandlerFunc {
+	return func(c *ginext.Context) {
+		user, err := GetUserFromContext(c)
+		if err != nil {
+			response.Error(c, 401, entity.ErrUnauthorized.Error())
+			c.Abort()
+			return
+		}
+
+		if !user.Can(perm) {
+			response.Error(c, 403, entity.ErrForbidden.Error())
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePasswordChanged blocks users who still have to replace their password,
+// such as seeded accounts. It must run after AuthMiddleware; the password
+// change endpoint is registered outside of it.
+func RequirePasswordChanged() ginext.HandlerFunc {
+	return func(c *ginext.Context) {
+		user, err := GetUserFromContext(c)
+		if err != nil {
+			response.Error(c, 401, entity.ErrUnauthorized.Error())
+			c.Abort()
+			return
+		}
+
+		if user.PasswordChangeRequired {
+			response.Error(c, 403, entity.ErrPasswordExpired.Error())
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
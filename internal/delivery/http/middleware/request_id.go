@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/wb-go/wbf/ginext"
+)
+
+const (
+	requestIDHeader     = "X-Request-ID"
+	requestIDContextKey = "request_id"
+	maxRequestIDLength  = 64
+)
+
+// RequestID tags every request with an ID, reusing a sane one sent by the
+// client or a proxy, and echoes it back in the response.
+func RequestID() ginext.HandlerFunc {
+	return func(c *ginext.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(requestIDContextKey, requestID)
+		c.Header(requestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+func GetRequestID(c *ginext.Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -4,53 +4,164 @@ import (
 	"github.com/wb-go/wbf/ginext"
 	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/handler"
 	"github.com/yokitheyo/WarehouseControl/internal/delivery/http/middleware"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
 	"github.com/yokitheyo/WarehouseControl/internal/pkg/jwt"
 )
 
 func SetupRouter(
 	engine *ginext.Engine,
 	authHandler *handler.AuthHandler,
+	ssoHandler *handler.SSOHandler,
+	userHandler *handler.UserHandler,
+	apiKeyHandler *handler.APIKeyHandler,
 	itemHandler *handler.ItemHandler,
+	stockHandler *handler.StockHandler,
+	warehouseHandler *handler.WarehouseHandler,
+	transferHandler *handler.TransferHandler,
+	reservationHandler *handler.ReservationHandler,
 	historyHandler *handler.HistoryHandler,
 	jwtManager *jwt.Manager,
+	users middleware.UserProvider,
+	apiKeys middleware.APIKeyAuthenticator,
 ) {
+	engine.Use(middleware.RequestID())
+
 	engine.Static("/static", "./web/static")
 	engine.LoadHTMLGlob("web/templates/*")
 
 	engine.GET("/login", func(c *ginext.Context) {
-		c.HTML(200, "login.html", nil)
+		c.HTML(200, "login.html", ginext.H{"SSOEnabled": ssoHandler != nil})
 	})
 
 	engine.GET("/register", func(c *ginext.Context) {
 		c.HTML(200, "register.html", nil)
 	})
 
+	engine.GET("/reset-password", func(c *ginext.Context) {
+		c.HTML(200, "reset_password.html", nil)
+	})
+
 	engine.GET("/", func(c *ginext.Context) {
 		c.HTML(200, "app.html", nil)
 	})
 
+	// Public keys for services that verify our access tokens themselves.
+	engine.GET("/.well-known/jwks.json", func(c *ginext.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, jwtManager.JWKS())
+	})
+
+	authenticate := middleware.AuthMiddleware(jwtManager, users, apiKeys)
+
 	auth := engine.Group("/api/auth")
 	{
 		auth.POST("/login", authHandler.Login)
 		auth.POST("/register", authHandler.Register)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/logout", authHandler.Logout)
+		auth.POST("/mfa/enroll", authHandler.StartMFAEnrollment)
+		auth.POST("/mfa/verify", authHandler.VerifyMFA)
+		auth.POST("/password/forgot", authHandler.ForgotPassword)
+		auth.POST("/password/reset", authHandler.ResetPassword)
+		// Left open to users who must change their password first.
+		auth.POST("/password", authenticate, authHandler.ChangePassword)
+
+		// ssoHandler is nil unless SSO is enabled in config.
+		if ssoHandler != nil {
+			auth.GET("/sso/login", ssoHandler.Login)
+			auth.GET("/sso/callback", ssoHandler.Callback)
+		}
 	}
 
 	api := engine.Group("/api")
-	api.Use(middleware.AuthMiddleware(jwtManager))
+	api.Use(authenticate, middleware.RequirePasswordChanged())
 	{
 		items := api.Group("/items")
 		{
-			items.GET("", itemHandler.GetAll)
-			items.GET("/:id", itemHandler.GetByID)
-			items.POST("", middleware.RequireCreatePermission(), itemHandler.Create)
-			items.PUT("/:id", middleware.RequireUpdatePermission(), itemHandler.Update)
-			items.DELETE("/:id", middleware.RequireDeletePermission(), itemHandler.Delete)
+			items.GET("", middleware.RequirePermission(entity.PermItemsRead), itemHandler.GetAll)
+			items.GET("/deleted", middleware.RequirePermission(entity.PermItemsRestore), itemHandler.GetDeleted)
+			items.GET("/by-code/:code", middleware.RequirePermission(entity.PermItemsRead), itemHandler.GetByCode)
+			items.GET("/:id", middleware.RequirePermission(entity.PermItemsRead), itemHandler.GetByID)
+			items.POST("", middleware.RequirePermission(entity.PermItemsCreate), itemHandler.Create)
+			items.PUT("/:id", middleware.RequirePermission(entity.PermItemsUpdate), itemHandler.Update)
+			items.PATCH("/:id", middleware.RequirePermission(entity.PermItemsUpdate), itemHandler.Patch)
+			items.DELETE("/:id", middleware.RequirePermission(entity.PermItemsDelete), itemHandler.Delete)
+			items.POST("/:id/restore", middleware.RequirePermission(entity.PermItemsRestore), itemHandler.Restore)
+			items.PUT("/:id/stock/:location_id", middleware.RequirePermission(entity.PermStockUpdate), stockHandler.SetLevel)
+			items.GET("/:id/movements", middleware.RequirePermission(entity.PermItemsRead), stockHandler.GetMovements)
+			items.POST("/:id/movements", middleware.RequirePermission(entity.PermStockUpdate), stockHandler.CreateMovement)
+		}
+
+		warehouses := api.Group("/warehouses")
+		{
+			warehouses.GET("", middleware.RequirePermission(entity.PermWarehousesRead), warehouseHandler.GetAll)
+			warehouses.POST("", middleware.RequirePermission(entity.PermWarehousesManage), warehouseHandler.Create)
+		}
+
+		locations := api.Group("/locations")
+		{
+			locations.GET("", middleware.RequirePermission(entity.PermWarehousesRead), warehouseHandler.GetLocations)
+			locations.POST("", middleware.RequirePermission(entity.PermWarehousesManage), warehouseHandler.CreateLocation)
+		}
+
+		transfers := api.Group("/transfers")
+		{
+			transfers.GET("", middleware.RequirePermission(entity.PermTransfersRead), transferHandler.GetAll)
+			transfers.POST("", middleware.RequirePermission(entity.PermTransfersManage), transferHandler.Create)
+			transfers.GET("/:id", middleware.RequirePermission(entity.PermTransfersRead), transferHandler.GetByID)
+			transfers.GET("/:id/history", middleware.RequirePermission(entity.PermTransfersRead), transferHandler.GetHistory)
+			transfers.POST("/:id/dispatch", middleware.RequirePermission(entity.PermTransfersManage), transferHandler.Dispatch)
+			transfers.POST("/:id/receive", middleware.RequirePermission(entity.PermTransfersManage), transferHandler.Receive)
+			transfers.POST("/:id/cancel", middleware.RequirePermission(entity.PermTransfersManage), transferHandler.Cancel)
+		}
+
+		reservations := api.Group("/reservations")
+		{
+			reservations.GET("", middleware.RequirePermission(entity.PermReservationsRead), reservationHandler.GetAll)
+			reservations.POST("", middleware.RequirePermission(entity.PermReservationsManage), reservationHandler.Create)
+			reservations.GET("/:id", middleware.RequirePermission(entity.PermReservationsRead), reservationHandler.GetByID)
+			reservations.POST("/:id/release", middleware.RequirePermission(entity.PermReservationsManage), reservationHandler.Release)
+		}
+
+		mfa := api.Group("/mfa")
+		{
+			mfa.POST("/enroll", authHandler.EnrollMFA)
+			mfa.POST("/confirm", authHandler.ConfirmMFA)
+			mfa.POST("/recovery-codes", authHandler.RegenerateRecoveryCodes)
+			mfa.DELETE("", authHandler.DisableMFA)
+		}
+
+		keys := api.Group("/api-keys")
+		{
+			keys.GET("", apiKeyHandler.GetAll)
+			keys.POST("", apiKeyHandler.Create)
+			keys.DELETE("/:id", apiKeyHandler.Revoke)
+		}
+
+		admin := api.Group("/admin", middleware.RequirePermission(entity.PermUsersAdmin))
+		{
+			admin.GET("/users", userHandler.GetAll)
+			admin.POST("/users", userHandler.Create)
+			admin.GET("/users/:id", userHandler.GetByID)
+			admin.GET("/users/:id/history", userHandler.GetHistory)
+			admin.PUT("/users/:id/role", userHandler.UpdateRole)
+			admin.POST("/users/:id/disable", userHandler.Disable)
+			admin.POST("/users/:id/enable", userHandler.Enable)
+			admin.POST("/users/:id/unlock", userHandler.Unlock)
+			admin.POST("/users/:id/mfa/reset", userHandler.ResetMFA)
+			admin.PUT("/users/:id/password", userHandler.ResetPassword)
+			admin.DELETE("/users/:id", userHandler.Delete)
+			admin.DELETE("/users/:id/sessions", userHandler.RevokeSessions)
+			admin.POST("/invitations", authHandler.CreateInvitation)
 		}
 
 		history := api.Group("/history")
 		{
-			history.GET("", historyHandler.GetAll)
-			history.GET("/items/:id", historyHandler.GetByItemID)
+			history.GET("", middleware.RequirePermission(entity.PermHistoryRead), historyHandler.GetAll)
+			history.GET("/items/:id", middleware.RequirePermission(entity.PermHistoryRead), historyHandler.GetByItemID)
+			history.GET("/export", middleware.RequirePermission(entity.PermHistoryExport), historyHandler.Export)
+			history.GET("/verify", middleware.RequirePermission(entity.PermHistoryVerify), historyHandler.Verify)
+			history.POST("/:id/revert", middleware.RequirePermission(entity.PermHistoryRevert), historyHandler.Revert)
 		}
 	}
 }
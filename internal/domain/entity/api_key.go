@@ -0,0 +1,37 @@
+package entity
+
+import "time"
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to recognise.
+const APIKeyPrefix = "whk_"
+
+// APIKey lets scripts act on behalf of a user without a password. It is
+// limited to Permissions, and to what the owner's role still grants.
+type APIKey struct {
+	ID          int          `json:"id"`
+	UserID      int          `json:"user_id"`
+	Name        string       `json:"name"`
+	Prefix      string       `json:"prefix"`
+	Permissions []Permission `json:"permissions"`
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time   `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	RevokedAt   *time.Time   `json:"revoked_at,omitempty"`
+
+	// Username of the owner, filled in when the key is looked up by hash.
+	Username string `json:"-"`
+}
+
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Grants reports whether the key itself allows perm.
+func (k *APIKey) Grants(perm Permission) bool {
+	for _, p := range k.Permissions {
+		if p.Grants(perm) {
+			return true
+		}
+	}
+	return false
+}
@@ -7,7 +7,56 @@ var (
 	ErrInvalidItemName    = errors.New("invalid item name")
 	ErrInvalidQuantity    = errors.New("quantity cannot be negative")
 	ErrInvalidPrice       = errors.New("price cannot be negative")
+	ErrInvalidSKU         = errors.New("invalid sku")
+	ErrInvalidBarcode     = errors.New("invalid barcode")
+	ErrSKUExists          = errors.New("item with this sku already exists")
+	ErrBarcodeExists      = errors.New("barcode is already assigned to another item")
+	ErrWarehouseNotFound  = errors.New("warehouse not found")
+	ErrWarehouseExists    = errors.New("warehouse with this code already exists")
+	ErrInvalidWarehouse   = errors.New("invalid warehouse code or name")
+	ErrLocationNotFound   = errors.New("location not found")
+	ErrLocationExists     = errors.New("location with this code already exists in the warehouse")
+	ErrInvalidLocation    = errors.New("invalid location code or zone")
+	ErrInvalidMovement    = errors.New("invalid stock movement")
+	ErrInsufficientStock  = errors.New("insufficient stock at location")
+	ErrTransferNotFound   = errors.New("transfer order not found")
+	ErrInvalidTransfer    = errors.New("invalid transfer order")
+	ErrInvalidReceipt     = errors.New("invalid transfer receipt")
+	ErrTransferStatus     = errors.New("transfer order status does not allow this action")
+	ErrReservationMissing = errors.New("reservation not found")
+	ErrInvalidReservation = errors.New("invalid reservation")
+	ErrReservationClosed  = errors.New("reservation is no longer active")
+	ErrStockUnavailable   = errors.New("not enough available stock")
 	ErrUnauthorized       = errors.New("unauthorized")
 	ErrForbidden          = errors.New("forbidden")
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidCursor      = errors.New("invalid cursor")
+	ErrInvalidPatch       = errors.New("invalid patch document")
+	ErrVersionConflict    = errors.New("item was modified by another request")
+	ErrItemNotDeleted     = errors.New("item is not deleted")
+	ErrHistoryNotFound    = errors.New("history record not found")
+	ErrNothingToRevert    = errors.New("history record has no item snapshot")
+	ErrTokenRevoked       = errors.New("token has been revoked")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("username is already taken")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrRegistrationClosed = errors.New("public registration is disabled")
+	ErrInvalidInvitation  = errors.New("invalid or expired invitation code")
+	ErrUserDisabled       = errors.New("user is disabled")
+	ErrSelfModification   = errors.New("admins cannot change their own role or status")
+	ErrTooManyAttempts    = errors.New("too many login attempts")
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrAPIKeyExists       = errors.New("api key with this name already exists")
+	ErrInvalidPermission  = errors.New("invalid or not granted permission")
+	ErrInvalidMFACode     = errors.New("invalid verification code")
+	ErrMFANotEnrolled     = errors.New("two-factor authentication is not set up")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrMFARequired        = errors.New("two-factor authentication is required for this role")
+	ErrWeakPassword       = errors.New("password does not meet the password policy")
+	ErrPasswordReused     = errors.New("new password must differ from the current one")
+	ErrPasswordExpired    = errors.New("password change required")
+	ErrInvalidResetToken  = errors.New("invalid or expired password reset token")
+	ErrSSOFailed          = errors.New("single sign-on failed")
+	ErrSSONoRole          = errors.New("no role is mapped for this identity")
 )
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// Invitation is a one-time code issued by an admin. Whoever registers with it
+// gets the role chosen by the admin.
+type Invitation struct {
+	ID        int        `json:"id"`
+	Role      Role       `json:"role"`
+	CreatedBy string     `json:"created_by"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	UsedBy    *string    `json:"used_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (i *Invitation) IsUsable(now time.Time) bool {
+	return i.UsedAt == nil && now.Before(i.ExpiresAt)
+}
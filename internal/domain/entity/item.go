@@ -1,18 +1,64 @@
 package entity
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"time"
+)
 
+// Item is a product kept in stock. Its stock is tracked per location, so
+// Quantity is derived and cannot be written directly. Goods on dispatched
+// transfer orders are in no location and count only towards InTransit.
+// Active reservations do not move stock; they only lower Available.
 type Item struct {
-	ID          int       `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Quantity    int       `json:"quantity"`
-	Price       float64   `json:"price"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int          `json:"id"`
+	SKU         string       `json:"sku"`
+	Barcodes    []string     `json:"barcodes"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Quantity    int          `json:"quantity"` // sum of Stock, read-only
+	Stock       []StockLevel `json:"stock"`
+	InTransit   int          `json:"in_transit"` // read-only
+	OnHand      int          `json:"on_hand"`    // equals Quantity, read-only
+	Reserved    int          `json:"reserved"`   // read-only
+	Available   int          `json:"available"`  // OnHand - Reserved, read-only
+	Price       float64      `json:"price"`
+	Version     int          `json:"version"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	DeletedAt   *time.Time   `json:"deleted_at,omitempty"`
+	DeletedBy   *string      `json:"deleted_by,omitempty"`
+}
+
+// CopyStock takes the read-only stock figures from current, so that writing
+// an item never changes them.
+func (i *Item) CopyStock(current *Item) {
+	i.Quantity = current.Quantity
+	i.Stock = current.Stock
+	i.InTransit = current.InTransit
+	i.OnHand = current.OnHand
+	i.Reserved = current.Reserved
+	i.Available = current.Available
+}
+
+// Normalize brings the SKU and barcodes to the form they are stored in.
+func (i *Item) Normalize() {
+	i.SKU = NormalizeSKU(i.SKU)
+	i.Barcodes = NormalizeBarcodes(i.Barcodes)
 }
 
 func (i *Item) Validate() error {
+	if err := ValidateSKU(i.SKU); err != nil {
+		return err
+	}
+	if len(i.Barcodes) > MaxItemBarcodes {
+		return fmt.Errorf("%w: at most %d barcodes per item", ErrInvalidBarcode, MaxItemBarcodes)
+	}
+	for _, code := range i.Barcodes {
+		if err := ValidateBarcode(code); err != nil {
+			return err
+		}
+	}
 	if i.Name == "" {
 		return ErrInvalidItemName
 	}
@@ -24,3 +70,92 @@ func (i *Item) Validate() error {
 	}
 	return nil
 }
+
+type ItemSortField string
+
+const (
+	ItemSortByID        ItemSortField = "id"
+	ItemSortByName      ItemSortField = "name"
+	ItemSortByQuantity  ItemSortField = "quantity"
+	ItemSortByPrice     ItemSortField = "price"
+	ItemSortByCreatedAt ItemSortField = "created_at"
+	ItemSortByUpdatedAt ItemSortField = "updated_at"
+	ItemSortByDeletedAt ItemSortField = "deleted_at"
+)
+
+func (f ItemSortField) IsValid() bool {
+	switch f {
+	case ItemSortByID, ItemSortByName, ItemSortByQuantity,
+		ItemSortByPrice, ItemSortByCreatedAt, ItemSortByUpdatedAt, ItemSortByDeletedAt:
+		return true
+	}
+	return false
+}
+
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+func (o SortOrder) IsValid() bool {
+	return o == SortAsc || o == SortDesc
+}
+
+type ItemFilter struct {
+	Name        *string
+	QuantityMin *int
+	QuantityMax *int
+	// WarehouseID keeps items that have stock in that warehouse.
+	WarehouseID *int
+	PriceMin    *float64
+	PriceMax    *float64
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	Deleted     bool
+	SortBy      ItemSortField
+	SortOrder   SortOrder
+	Limit       int
+	Offset      int
+}
+
+// ItemPatch holds the fields of a JSON Merge Patch (RFC 7396) document.
+// A nil field is left untouched when the patch is applied.
+type ItemPatch struct {
+	SKU         *string
+	Barcodes    *[]string
+	Name        *string
+	Description *string
+	Price       *float64
+}
+
+func (i *Item) Apply(p *ItemPatch) *Item {
+	patched := *i
+	if p.SKU != nil {
+		patched.SKU = *p.SKU
+	}
+	if p.Barcodes != nil {
+		patched.Barcodes = *p.Barcodes
+	}
+	if p.Name != nil {
+		patched.Name = *p.Name
+	}
+	if p.Description != nil {
+		patched.Description = *p.Description
+	}
+	if p.Price != nil {
+		patched.Price = *p.Price
+	}
+	return &patched
+}
+
+func (i *Item) SameContent(other *Item) bool {
+	return i.SKU == other.SKU &&
+		slices.Equal(i.Barcodes, other.Barcodes) &&
+		i.Name == other.Name &&
+		i.Description == other.Description &&
+		i.Price == other.Price
+}
@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	MaxSKULength     = 64
+	MaxBarcodeLength = 48
+	MaxItemBarcodes  = 20
+)
+
+// NormalizeSKU trims the SKU and upper-cases it, so lookups and the uniqueness
+// check do not depend on how the ERP or a user typed it.
+func NormalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
+
+// NormalizeBarcodes trims, de-duplicates and sorts barcodes. The result is
+// never nil, so an item without barcodes encodes as an empty list.
+func NormalizeBarcodes(barcodes []string) []string {
+	normalized := make([]string, 0, len(barcodes))
+	for _, code := range barcodes {
+		if code = strings.TrimSpace(code); code != "" {
+			normalized = append(normalized, code)
+		}
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+func ValidateSKU(sku string) error {
+	if !isCode(sku, MaxSKULength) {
+		return ErrInvalidSKU
+	}
+	return nil
+}
+
+// isCode reports whether s is a non-empty identifier of letters, digits and
+// "-_./", as used for SKUs, warehouse and location codes.
+func isCode(s string, maxLen int) bool {
+	if s == "" || len(s) > maxLen {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-_./", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateBarcode accepts GTIN family codes (EAN-8, UPC-A, EAN-13, GTIN-14)
+// with a correct check digit, and any other printable ASCII as Code 128.
+// An all-digit code of a GTIN length is always treated as a GTIN, so a typo
+// in a scanned EAN is caught instead of being stored as Code 128.
+func ValidateBarcode(code string) error {
+	if code == "" || len(code) > MaxBarcodeLength {
+		return fmt.Errorf("%w: %q", ErrInvalidBarcode, code)
+	}
+
+	if isGTIN(code) {
+		if !validGTINCheckDigit(code) {
+			return fmt.Errorf("%w: %q has a wrong check digit", ErrInvalidBarcode, code)
+		}
+		return nil
+	}
+
+	for i := 0; i < len(code); i++ {
+		if code[i] < 0x20 || code[i] > 0x7e {
+			return fmt.Errorf("%w: %q", ErrInvalidBarcode, code)
+		}
+	}
+	return nil
+}
+
+func isGTIN(code string) bool {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validGTINCheckDigit applies the GS1 mod-10 rule: counting from the digit
+// next to the check digit, digits are weighted 3, 1, 3, ...
+func validGTINCheckDigit(code string) bool {
+	sum := 0
+	for i, weight := len(code)-2, 3; i >= 0; i, weight = i-1, 4-weight {
+		sum += int(code[i]-'0') * weight
+	}
+	return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateBarcode(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		wantErr bool
+	}{
+		{name: "EAN-8", code: "73513537"},
+		{name: "UPC-A", code: "036000291452"},
+		{name: "EAN-13", code: "4006381333931"},
+		{name: "GTIN-14", code: "10614141000415"},
+		{name: "check digit zero", code: "0000000000000"},
+		{name: "EAN-8 wrong check digit", code: "73513538", wantErr: true},
+		{name: "UPC-A wrong check digit", code: "036000291453", wantErr: true},
+		{name: "EAN-13 wrong check digit", code: "4006381333932", wantErr: true},
+		{name: "GTIN-14 wrong check digit", code: "10614141000416", wantErr: true},
+		{name: "EAN-13 swapped digits", code: "4006381339331", wantErr: true},
+		{name: "digits of other length are Code 128", code: "1234567"},
+		{name: "Code 128", code: "ABC-123/x"},
+		{name: "letters in a GTIN length", code: "4006381A33931"},
+		{name: "empty", code: "", wantErr: true},
+		{name: "control character", code: "ABC\t123", wantErr: true},
+		{name: "non-ASCII", code: "ÄBC123", wantErr: true},
+		{name: "too long", code: strings.Repeat("A", MaxBarcodeLength+1), wantErr: true},
+		{name: "longest", code: strings.Repeat("A", MaxBarcodeLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBarcode(tt.code)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidBarcode) {
+					t.Fatalf("ValidateBarcode(%q) = %v, want ErrInvalidBarcode", tt.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateBarcode(%q) = %v, want nil", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestValidGTINCheckDigit(t *testing.T) {
+	// Every other final digit of a valid code must be rejected.
+	for _, code := range []string{"73513537", "036000291452", "4006381333931", "10614141000415"} {
+		for d := byte('0'); d <= '9'; d++ {
+			candidate := code[:len(code)-1] + string(d)
+			if got, want := validGTINCheckDigit(candidate), candidate == code; got != want {
+				t.Errorf("validGTINCheckDigit(%q) = %v, want %v", candidate, got, want)
+			}
+		}
+	}
+}
+
+func TestValidateSKU(t *testing.T) {
+	tests := []struct {
+		sku     string
+		wantErr bool
+	}{
+		{sku: "ABC-123"},
+		{sku: "a_b.c/d"},
+		{sku: "", wantErr: true},
+		{sku: "ABC 123", wantErr: true},
+		{sku: "ABC#1", wantErr: true},
+		{sku: strings.Repeat("A", MaxSKULength)},
+		{sku: strings.Repeat("A", MaxSKULength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateSKU(tt.sku); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateSKU(%q) = %v, wantErr %v", tt.sku, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeBarcodes(t *testing.T) {
+	got := NormalizeBarcodes([]string{" B ", "A", "", "B", "  "})
+	if strings.Join(got, ",") != "A,B" {
+		t.Errorf("NormalizeBarcodes = %q, want [A B]", got)
+	}
+
+	if got := NormalizeBarcodes(nil); got == nil || len(got) != 0 {
+		t.Errorf("NormalizeBarcodes(nil) = %#v, want empty non-nil slice", got)
+	}
+}
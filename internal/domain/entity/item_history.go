@@ -1,31 +1,234 @@
 package entity
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"time"
+)
 
 type HistoryAction string
 
 const (
-	ActionInsert HistoryAction = "INSERT"
-	ActionUpdate HistoryAction = "UPDATE"
-	ActionDelete HistoryAction = "DELETE"
+	ActionInsert  HistoryAction = "INSERT"
+	ActionUpdate  HistoryAction = "UPDATE"
+	ActionDelete  HistoryAction = "DELETE"
+	ActionRestore HistoryAction = "RESTORE"
+	// ActionStock records a stock change at one location, see LocationID.
+	ActionStock HistoryAction = "STOCK"
 )
 
 type ItemHistory struct {
-	ID        int           `json:"id"`
-	ItemID    int           `json:"item_id"`
-	Action    HistoryAction `json:"action"`
-	Username  string        `json:"username"`
-	OldData   *Item         `json:"old_data,omitempty"`
-	NewData   *Item         `json:"new_data,omitempty"`
-	ChangedAt time.Time     `json:"changed_at"`
+	ID         int           `json:"id"`
+	ItemID     int           `json:"item_id"`
+	LocationID *int          `json:"location_id,omitempty"`
+	Action     HistoryAction `json:"action"`
+	Username   string        `json:"username"`
+	APIKey     string        `json:"api_key,omitempty"`
+	OldData    *Item         `json:"old_data,omitempty"`
+	NewData    *Item         `json:"new_data,omitempty"`
+	Changes    []FieldChange `json:"changes,omitempty"`
+	RequestID  string        `json:"request_id,omitempty"`
+	ClientIP   string        `json:"client_ip,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+	PrevHash   string        `json:"prev_hash,omitempty"`
+	Hash       string        `json:"hash,omitempty"`
+	ChangedAt  time.Time     `json:"changed_at"`
+}
+
+// HistoryHashKeyed is the first hash version computed with the application's
+// key. Rows of older versions are plain SHA-256 and anyone with write access
+// to the database can recompute them.
+const HistoryHashKeyed = 3
+
+// ChainLink is what the integrity check needs from one history row: the
+// stored hashes and the hash recomputed from the row as it is now.
+type ChainLink struct {
+	ID       int
+	Version  int
+	PrevHash string
+	Hash     string
+	Expected string
+}
+
+// ChainHead is the signed end of the chain: how many rows it has and the hash
+// of the last one. Rows deleted from the end leave it pointing past them.
+type ChainHead struct {
+	Count    int
+	LastID   int
+	LastHash string
+	MAC      string
+	Expected string
+}
+
+// ChainAnchor is the count and last hash of an earlier verification. A copy of
+// the database that rolled the head back together with the rows still passes
+// the head check, but no longer holds the anchor.
+type ChainAnchor struct {
+	Count int
+	Hash  string
+}
+
+type ChainBreak struct {
+	ID     int    `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// ChainVerification is the result of a chain check. Checked and LastHash are
+// what a caller keeps as the anchor of the next check; Anchored reports
+// whether this one was checked against an anchor.
+type ChainVerification struct {
+	Valid    bool        `json:"valid"`
+	Anchored bool        `json:"anchored"`
+	Checked  int         `json:"checked"`
+	LastHash string      `json:"last_hash,omitempty"`
+	BrokenAt *ChainBreak `json:"broken_at,omitempty"`
+}
+
+// AuditMeta describes who made a change, from where and why. It is recorded
+// next to every history row.
+type AuditMeta struct {
+	Username  string
+	APIKey    string // name of the API key Username acted through, if any
+	RequestID string
+	ClientIP  string
+	Reason    string
+}
+
+func NewItemHistory(itemID int, action HistoryAction, oldData, newData *Item, meta *AuditMeta) *ItemHistory {
+	return &ItemHistory{
+		ItemID:    itemID,
+		Action:    action,
+		Username:  meta.Username,
+		APIKey:    meta.APIKey,
+		OldData:   oldData,
+		NewData:   newData,
+		RequestID: meta.RequestID,
+		ClientIP:  meta.ClientIP,
+		Reason:    meta.Reason,
+	}
+}
+
+// Snapshot returns the item state recorded by this row: the state after the
+// change, or the last known state for a deletion.
+func (h *ItemHistory) Snapshot() *Item {
+	if h.NewData != nil {
+		return h.NewData
+	}
+	return h.OldData
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// DiffFields are the item fields that history diffs compare.
+var DiffFields = []string{"sku", "barcodes", "name", "description", "quantity", "price"}
+
+func IsDiffField(field string) bool {
+	for _, f := range DiffFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Item) fieldValue(field string) interface{} {
+	switch field {
+	case "sku":
+		return i.SKU
+	case "barcodes":
+		if i.Barcodes == nil {
+			return []string{}
+		}
+		return i.Barcodes
+	case "name":
+		return i.Name
+	case "description":
+		return i.Description
+	case "quantity":
+		return i.Quantity
+	case "price":
+		return i.Price
+	}
+	return nil
+}
+
+// DiffItems lists the fields that differ between two item states. A nil side
+// (creation or deletion) reports every field with a null counterpart.
+// Identity and timestamps are ignored.
+func DiffItems(old, new *Item) []FieldChange {
+	changes := []FieldChange{}
+	for _, field := range DiffFields {
+		var oldValue, newValue interface{}
+		if old != nil {
+			oldValue = old.fieldValue(field)
+		}
+		if new != nil {
+			newValue = new.fieldValue(field)
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, FieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+type RevertResult struct {
+	Item    *Item         `json:"item"`
+	Changes []FieldChange `json:"changes"`
+	Applied bool          `json:"applied"`
 }
 
 type HistoryFilter struct {
-	ItemID   *int
-	Username *string
-	Action   *HistoryAction
-	DateFrom *time.Time
-	DateTo   *time.Time
-	Limit    int
-	Offset   int
+	ItemID     *int
+	LocationID *int
+	Username   *string
+	Action     *HistoryAction
+	DateFrom   *time.Time
+	DateTo     *time.Time
+	Field      *string
+	Cursor     *HistoryCursor
+	Limit      int
+	Offset     int
+	// WithChanges asks for the computed field-level diff of every row.
+	WithChanges bool
+}
+
+// HistoryCursor points at the last row of a page in (changed_at, id) order.
+// Clients only ever see it in its encoded, opaque form.
+type HistoryCursor struct {
+	ChangedAt time.Time `json:"t"`
+	ID        int       `json:"id"`
+}
+
+func NewHistoryCursor(h *ItemHistory) *HistoryCursor {
+	return &HistoryCursor{ChangedAt: h.ChangedAt, ID: h.ID}
+}
+
+func (c *HistoryCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeHistoryCursor(s string) (*HistoryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &HistoryCursor{}
+	if err := json.Unmarshal(data, c); err != nil || c.ID <= 0 || c.ChangedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+type HistoryPage struct {
+	Items      []*ItemHistory
+	NextCursor *HistoryCursor
 }
@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHistoryCursorRoundTrip(t *testing.T) {
+	tests := []*ItemHistory{
+		{ID: 1, ChangedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{ID: 42, ChangedAt: time.Date(2025, 3, 1, 12, 0, 0, 123456789, time.UTC)},
+		{ID: 7, ChangedAt: time.Date(2025, 3, 1, 15, 30, 0, 0, time.FixedZone("MSK", 3*60*60))},
+	}
+
+	for _, h := range tests {
+		encoded := NewHistoryCursor(h).Encode()
+
+		got, err := DecodeHistoryCursor(encoded)
+		if err != nil {
+			t.Fatalf("DecodeHistoryCursor(%q) = %v", encoded, err)
+		}
+		if got.ID != h.ID || !got.ChangedAt.Equal(h.ChangedAt) {
+			t.Errorf("round trip of (%s, %d) = (%s, %d)", h.ChangedAt, h.ID, got.ChangedAt, got.ID)
+		}
+	}
+}
+
+func TestDecodeHistoryCursorInvalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "empty", cursor: ""},
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"t":"2025-03-01T12:00:00Z","id":1}`))},
+		{name: "not JSON", cursor: encode("2025-03-01T12:00:00Z,1")},
+		{name: "JSON array", cursor: encode(`["2025-03-01T12:00:00Z",1]`)},
+		{name: "bad time", cursor: encode(`{"t":"yesterday","id":1}`)},
+		{name: "no time", cursor: encode(`{"id":1}`)},
+		{name: "no id", cursor: encode(`{"t":"2025-03-01T12:00:00Z"}`)},
+		{name: "zero id", cursor: encode(`{"t":"2025-03-01T12:00:00Z","id":0}`)},
+		{name: "negative id", cursor: encode(`{"t":"2025-03-01T12:00:00Z","id":-1}`)},
+		{name: "id not a number", cursor: encode(`{"t":"2025-03-01T12:00:00Z","id":"1"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, err := DecodeHistoryCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeHistoryCursor(%q) = %+v, %v; want ErrInvalidCursor", tt.cursor, c, err)
+			}
+		})
+	}
+}
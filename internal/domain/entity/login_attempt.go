@@ -0,0 +1,98 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LoginAttempts counts recent failed logins for one key: a username or a
+// client IP.
+type LoginAttempts struct {
+	Key         string
+	Failures    int
+	LastFailure time.Time
+}
+
+// AddFailure returns the counter with one more failure at now. A counter
+// whose last failure is older than window starts over.
+func (a LoginAttempts) AddFailure(now time.Time, window time.Duration) LoginAttempts {
+	if a.Failures == 0 || now.Sub(a.LastFailure) >= window {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.LastFailure = now
+
+	return a
+}
+
+func UsernameAttemptKey(username string) string {
+	return "user:" + username
+}
+
+func IPAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// PasswordResetAttemptKey turns a login attempt key into the key counting
+// password reset requests, so those do not count as failed logins.
+func PasswordResetAttemptKey(key string) string {
+	return "reset:" + key
+}
+
+// LoginThrottlePolicy turns a failure count into a wait time. The first
+// FreeAttempts failures cost nothing, then the delay doubles from BaseDelay up
+// to MaxDelay, and from LockoutAfter failures on the key is locked for
+// LockoutDuration. Failures older than Window are forgotten.
+type LoginThrottlePolicy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// RetryAfter returns how long the key has to wait before the next attempt,
+// or zero if it may try now.
+func (p LoginThrottlePolicy) RetryAfter(a *LoginAttempts, now time.Time) time.Duration {
+	if a == nil || a.Failures == 0 || now.Sub(a.LastFailure) >= p.Window {
+		return 0
+	}
+
+	var wait time.Duration
+	switch {
+	case p.LockoutAfter > 0 && a.Failures >= p.LockoutAfter:
+		wait = p.LockoutDuration
+	case a.Failures > p.FreeAttempts:
+		wait = p.BaseDelay
+		for i := p.FreeAttempts + 1; i < a.Failures && wait < p.MaxDelay; i++ {
+			wait *= 2
+		}
+		wait = min(wait, p.MaxDelay)
+	default:
+		return 0
+	}
+
+	return max(a.LastFailure.Add(wait).Sub(now), 0)
+}
+
+// LoginThrottledError is returned instead of checking credentials while a
+// username or client IP has to wait. It matches ErrTooManyAttempts.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+func IsLoginThrottled(err error) (*LoginThrottledError, bool) {
+	var throttled *LoginThrottledError
+	ok := errors.As(err, &throttled)
+	return throttled, ok
+}
@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoginThrottlePolicyRetryAfter(t *testing.T) {
+	policy := LoginThrottlePolicy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		failures int
+		since    time.Duration
+		want     time.Duration
+	}{
+		{name: "no failures", failures: 0, want: 0},
+		{name: "within free attempts", failures: 3, want: 0},
+		{name: "first delayed failure", failures: 4, want: time.Second},
+		{name: "delay doubles", failures: 5, want: 2 * time.Second},
+		{name: "delay doubles again", failures: 6, want: 4 * time.Second},
+		{name: "delay capped", failures: 9, want: 30 * time.Second},
+		{name: "lockout", failures: 10, want: 15 * time.Minute},
+		{name: "beyond lockout", failures: 50, want: 15 * time.Minute},
+		{name: "time already waited counts", failures: 6, since: 3 * time.Second, want: time.Second},
+		{name: "wait over", failures: 6, since: 4 * time.Second, want: 0},
+		{name: "lockout partly served", failures: 10, since: 10 * time.Minute, want: 5 * time.Minute},
+		{name: "failures outside window forgotten", failures: 10, since: time.Hour, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &LoginAttempts{Failures: tt.failures, LastFailure: now.Add(-tt.since)}
+			if got := policy.RetryAfter(a, now); got != tt.want {
+				t.Errorf("RetryAfter(%d failures, %s ago) = %s, want %s", tt.failures, tt.since, got, tt.want)
+			}
+		})
+	}
+
+	if got := policy.RetryAfter(nil, now); got != 0 {
+		t.Errorf("RetryAfter(nil) = %s, want 0", got)
+	}
+}
+
+func TestLoginThrottlePolicyWithoutLockout(t *testing.T) {
+	policy := LoginThrottlePolicy{FreeAttempts: 0, BaseDelay: time.Second, MaxDelay: 8 * time.Second, Window: time.Hour}
+	now := time.Now()
+
+	// A large count must not overflow the doubling.
+	for failures, want := range map[int]time.Duration{1: time.Second, 4: 8 * time.Second, 1000: 8 * time.Second} {
+		if got := policy.RetryAfter(&LoginAttempts{Failures: failures, LastFailure: now}, now); got != want {
+			t.Errorf("RetryAfter(%d failures) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestLoginAttemptsAddFailure(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	const window = time.Hour
+
+	tests := []struct {
+		name    string
+		current LoginAttempts
+		want    int
+	}{
+		{name: "first failure", current: LoginAttempts{}, want: 1},
+		{name: "within window", current: LoginAttempts{Failures: 4, LastFailure: now.Add(-time.Minute)}, want: 5},
+		{name: "window passed", current: LoginAttempts{Failures: 4, LastFailure: now.Add(-window)}, want: 1},
+	}
+
+	for _, tt := range tests {
+		got := tt.current.AddFailure(now, window)
+		if got.Failures != tt.want || !got.LastFailure.Equal(now) {
+			t.Errorf("%s: AddFailure() = %d at %s, want %d at %s", tt.name, got.Failures, got.LastFailure, tt.want, now)
+		}
+	}
+}
+
+func TestLoginThrottledError(t *testing.T) {
+	var err error = &LoginThrottledError{RetryAfter: 1500 * time.Millisecond}
+
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Error("LoginThrottledError does not match ErrTooManyAttempts")
+	}
+
+	throttled, ok := IsLoginThrottled(err)
+	if !ok || throttled.RetryAfter != 1500*time.Millisecond {
+		t.Errorf("IsLoginThrottled() = %v, %v", throttled, ok)
+	}
+
+	if _, ok := IsLoginThrottled(ErrInvalidCredentials); ok {
+		t.Error("IsLoginThrottled(ErrInvalidCredentials) = true")
+	}
+}
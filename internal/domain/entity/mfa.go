@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// MFA is a user's TOTP enrollment. It only protects logins once EnabledAt is
+// set, i.e. after the user proved the authenticator works.
+type MFA struct {
+	UserID    int
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+func (m *MFA) IsEnabled() bool {
+	return m != nil && m.EnabledAt != nil
+}
+
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFAChallenge is returned by the first login step when a second factor is
+// still needed. Token is only good for finishing this login.
+type MFAChallenge struct {
+	Token              string    `json:"mfa_token"`
+	ExpiresAt          time.Time `json:"mfa_expires_at"`
+	EnrollmentRequired bool      `json:"enrollment_required"`
+}
+
+// LoginResult holds either issued tokens or, when MFA applies, a challenge.
+type LoginResult struct {
+	Tokens *TokenPair
+	User   *User
+	MFA    *MFAChallenge
+	// RecoveryCodes are returned once, when a login finished an enrollment.
+	RecoveryCodes []string
+}
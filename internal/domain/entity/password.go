@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxPasswordBytes is where bcrypt stops reading; longer passwords would be
+// silently truncated.
+const MaxPasswordBytes = 72
+
+// PasswordPolicy is checked whenever a password is set. Existing passwords are
+// not rechecked when the policy gets stricter.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DenyList holds common passwords in lowercase; matching ignores case.
+	DenyList map[string]struct{}
+}
+
+// NewPasswordDenyList builds a deny-list, ignoring blank entries and case.
+func NewPasswordDenyList(passwords []string) map[string]struct{} {
+	list := make(map[string]struct{}, len(passwords))
+	for _, p := range passwords {
+		if p = strings.TrimSpace(p); p != "" {
+			list[strings.ToLower(p)] = struct{}{}
+		}
+	}
+	return list
+}
+
+// Validate returns a PasswordPolicyError listing every rule the password
+// breaks, or nil.
+func (p *PasswordPolicy) Validate(password, username string) error {
+	var problems []string
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if len(password) > MaxPasswordBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes long", MaxPasswordBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+
+	if p.RequireUpper && !upper {
+		problems = append(problems, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		problems = append(problems, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if _, denied := p.DenyList[lowered]; denied {
+		problems = append(problems, "is too common")
+	}
+	if username != "" && strings.Contains(lowered, strings.ToLower(username)) {
+		problems = append(problems, "must not contain the username")
+	}
+
+	if len(problems) > 0 {
+		return &PasswordPolicyError{Problems: problems}
+	}
+
+	return nil
+}
+
+// PasswordPolicyError lists the rules a new password breaks. It matches
+// ErrWeakPassword.
+type PasswordPolicyError struct {
+	Problems []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrWeakPassword.Error() + ": password " + strings.Join(e.Problems, ", ")
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
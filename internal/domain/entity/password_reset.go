@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// PasswordReset is a one-time token that lets a user set a new password
+// without knowing the current one. Only the token hash is stored, and the row
+// is deleted once the token is used.
+type PasswordReset struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func (r *PasswordReset) IsUsable(now time.Time) bool {
+	return now.Before(r.ExpiresAt)
+}
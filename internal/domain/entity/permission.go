@@ -0,0 +1,123 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Permission string
+
+const (
+	PermItemsRead          Permission = "items:read"
+	PermItemsCreate        Permission = "items:create"
+	PermItemsUpdate        Permission = "items:update"
+	PermItemsDelete        Permission = "items:delete"
+	PermItemsRestore       Permission = "items:restore"
+	PermStockUpdate        Permission = "stock:update"
+	PermWarehousesRead     Permission = "warehouses:read"
+	PermWarehousesManage   Permission = "warehouses:manage"
+	PermTransfersRead      Permission = "transfers:read"
+	PermTransfersManage    Permission = "transfers:manage"
+	PermReservationsRead   Permission = "reservations:read"
+	PermReservationsManage Permission = "reservations:manage"
+	PermHistoryRead        Permission = "history:read"
+	PermHistoryExport      Permission = "history:export"
+	PermHistoryRevert      Permission = "history:revert"
+	PermHistoryVerify      Permission = "history:verify"
+	PermUsersAdmin         Permission = "users:admin"
+
+	// PermAll grants every permission. "<resource>:*" grants every
+	// permission of one resource, e.g. "items:*".
+	PermAll Permission = "*"
+)
+
+var AllPermissions = []Permission{
+	PermItemsRead,
+	PermItemsCreate,
+	PermItemsUpdate,
+	PermItemsDelete,
+	PermItemsRestore,
+	PermStockUpdate,
+	PermWarehousesRead,
+	PermWarehousesManage,
+	PermTransfersRead,
+	PermTransfersManage,
+	PermReservationsRead,
+	PermReservationsManage,
+	PermHistoryRead,
+	PermHistoryExport,
+	PermHistoryRevert,
+	PermHistoryVerify,
+	PermUsersAdmin,
+}
+
+func (p Permission) IsValid() bool {
+	for _, known := range AllPermissions {
+		if p.Grants(known) {
+			return true
+		}
+	}
+	return false
+}
+
+// Grants reports whether holding p allows an action that requires other.
+func (p Permission) Grants(other Permission) bool {
+	if p == PermAll || p == other {
+		return true
+	}
+
+	if resource, ok := strings.CutSuffix(string(p), ":*"); ok {
+		return strings.HasPrefix(string(other), resource+":")
+	}
+
+	return false
+}
+
+// PermissionMatrix maps every known role to the permissions it grants. A role
+// exists only if it is listed here.
+type PermissionMatrix map[Role][]Permission
+
+// DefaultPermissionMatrix is used when the configuration has no permissions
+// section. It matches the built-in admin, manager and viewer roles.
+func DefaultPermissionMatrix() PermissionMatrix {
+	return PermissionMatrix{
+		RoleAdmin: {PermAll},
+		RoleManager: {
+			PermItemsRead, PermItemsCreate, PermItemsUpdate, PermStockUpdate, PermWarehousesRead,
+			PermTransfersRead, PermTransfersManage, PermReservationsRead, PermReservationsManage,
+			PermHistoryRead, PermHistoryExport, PermHistoryRevert,
+		},
+		RoleViewer: {
+			PermItemsRead, PermWarehousesRead, PermTransfersRead, PermReservationsRead,
+			PermHistoryRead, PermHistoryExport,
+		},
+	}
+}
+
+func (m PermissionMatrix) Validate() error {
+	if len(m) == 0 {
+		return fmt.Errorf("no roles defined")
+	}
+
+	for role, perms := range m {
+		if role == "" {
+			return fmt.Errorf("empty role name")
+		}
+		for _, perm := range perms {
+			if !perm.IsValid() {
+				return fmt.Errorf("role %q: unknown permission %q", role, perm)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m PermissionMatrix) HasRole(role Role) bool {
+	_, ok := m[role]
+	return ok
+}
+
+func (m PermissionMatrix) Permissions(role Role) []Permission {
+	return m[role]
+}
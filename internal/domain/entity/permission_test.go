@@ -0,0 +1,80 @@
+package entity
+
+import "testing"
+
+func TestPermissionGrants(t *testing.T) {
+	tests := []struct {
+		held     Permission
+		required Permission
+		want     bool
+	}{
+		{PermItemsRead, PermItemsRead, true},
+		{PermItemsRead, PermItemsUpdate, false},
+		{PermAll, PermItemsDelete, true},
+		{PermAll, PermUsersAdmin, true},
+		{"items:*", PermItemsRead, true},
+		{"items:*", PermItemsRestore, true},
+		{"items:*", PermHistoryRead, false},
+		{"history:*", PermHistoryVerify, true},
+		{"history:*", PermItemsRead, false},
+		// The resource has to match in full, not just as a prefix.
+		{"item:*", PermItemsRead, false},
+		{"items*", PermItemsRead, false},
+		{"", PermItemsRead, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.held.Grants(tt.required); got != tt.want {
+			t.Errorf("%q.Grants(%q) = %v, want %v", tt.held, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestPermissionIsValid(t *testing.T) {
+	tests := []struct {
+		perm Permission
+		want bool
+	}{
+		{PermItemsRead, true},
+		{PermUsersAdmin, true},
+		{PermAll, true},
+		{"items:*", true},
+		{"history:*", true},
+		{"users:*", true},
+		{"items:archive", false},
+		{"orders:*", false},
+		{"items", false},
+		{"*:read", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.perm.IsValid(); got != tt.want {
+			t.Errorf("%q.IsValid() = %v, want %v", tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestPermissionMatrixValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		matrix  PermissionMatrix
+		wantErr bool
+	}{
+		{name: "default", matrix: DefaultPermissionMatrix()},
+		{name: "wildcards", matrix: PermissionMatrix{"auditor": {"history:*"}, "root": {PermAll}}},
+		{name: "role without permissions", matrix: PermissionMatrix{"guest": {}}},
+		{name: "empty", matrix: PermissionMatrix{}, wantErr: true},
+		{name: "empty role name", matrix: PermissionMatrix{"": {PermItemsRead}}, wantErr: true},
+		{name: "unknown permission", matrix: PermissionMatrix{"clerk": {PermItemsRead, "items:archive"}}, wantErr: true},
+		{name: "unknown resource wildcard", matrix: PermissionMatrix{"clerk": {"orders:*"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.matrix.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+type ReservationStatus string
+
+const (
+	ReservationActive   ReservationStatus = "active"
+	ReservationReleased ReservationStatus = "released"
+	// ReservationExpired is reported as soon as ExpiresAt passes, even before
+	// the expiry job has released the row.
+	ReservationExpired ReservationStatus = "expired"
+)
+
+func (s ReservationStatus) IsValid() bool {
+	switch s {
+	case ReservationActive, ReservationReleased, ReservationExpired:
+		return true
+	}
+	return false
+}
+
+const (
+	MaxReservationReferenceLength = 100
+	maxReservationOwnerLength     = 255
+)
+
+// Reservation holds stock of an item for a pending order. Active
+// reservations count against the item's available stock until they are
+// released or expire; they do not tie stock to a location.
+type Reservation struct {
+	ID            int               `json:"id"`
+	ItemID        int               `json:"item_id"`
+	Owner         string            `json:"owner"`
+	Reference     string            `json:"reference"`
+	Quantity      int               `json:"quantity"`
+	Status        ReservationStatus `json:"status"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	CreatedBy     string            `json:"created_by"`
+	APIKey        string            `json:"api_key,omitempty"`
+	RequestID     string            `json:"request_id,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	ReleasedAt    *time.Time        `json:"released_at,omitempty"`
+	ReleasedBy    *string           `json:"released_by,omitempty"`
+	ReleaseReason string            `json:"release_reason,omitempty"`
+}
+
+// Validate checks a new reservation. ExpiresAt must lie in the future but no
+// later than maxTTL from now.
+func (r *Reservation) Validate(now time.Time, maxTTL time.Duration) error {
+	if r.ItemID <= 0 || r.Quantity <= 0 {
+		return fmt.Errorf("%w: an item and a positive quantity are required", ErrInvalidReservation)
+	}
+	if r.Reference == "" || len(r.Reference) > MaxReservationReferenceLength {
+		return fmt.Errorf("%w: reference is required and at most %d characters", ErrInvalidReservation, MaxReservationReferenceLength)
+	}
+	if r.Owner == "" || len(r.Owner) > maxReservationOwnerLength {
+		return fmt.Errorf("%w: owner is required", ErrInvalidReservation)
+	}
+	if !r.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: expiry must be in the future", ErrInvalidReservation)
+	}
+	if r.ExpiresAt.After(now.Add(maxTTL)) {
+		return fmt.Errorf("%w: expiry must be within %s", ErrInvalidReservation, maxTTL)
+	}
+	return nil
+}
+
+type ReservationFilter struct {
+	ItemID *int
+	Owner  *string
+	Status *ReservationStatus
+	Limit  int
+	Offset int
+}
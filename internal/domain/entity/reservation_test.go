@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReservationValidate(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	const maxTTL = 24 * time.Hour
+
+	valid := func() Reservation {
+		return Reservation{ItemID: 1, Owner: "sales", Reference: "SO-1", Quantity: 2, ExpiresAt: now.Add(time.Hour)}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *Reservation)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(r *Reservation) {}},
+		{name: "expires at max TTL", modify: func(r *Reservation) { r.ExpiresAt = now.Add(maxTTL) }},
+		{name: "expires just after now", modify: func(r *Reservation) { r.ExpiresAt = now.Add(time.Nanosecond) }},
+		{name: "other time zone within TTL", modify: func(r *Reservation) {
+			r.ExpiresAt = now.Add(maxTTL).In(time.FixedZone("UTC+5", 5*60*60))
+		}},
+		{name: "longest reference", modify: func(r *Reservation) { r.Reference = strings.Repeat("R", MaxReservationReferenceLength) }},
+
+		{name: "no item", modify: func(r *Reservation) { r.ItemID = 0 }, wantErr: true},
+		{name: "zero quantity", modify: func(r *Reservation) { r.Quantity = 0 }, wantErr: true},
+		{name: "negative quantity", modify: func(r *Reservation) { r.Quantity = -1 }, wantErr: true},
+		{name: "no reference", modify: func(r *Reservation) { r.Reference = "" }, wantErr: true},
+		{name: "reference too long", modify: func(r *Reservation) { r.Reference = strings.Repeat("R", MaxReservationReferenceLength+1) }, wantErr: true},
+		{name: "no owner", modify: func(r *Reservation) { r.Owner = "" }, wantErr: true},
+		{name: "owner too long", modify: func(r *Reservation) { r.Owner = strings.Repeat("o", maxReservationOwnerLength+1) }, wantErr: true},
+		{name: "expires now", modify: func(r *Reservation) { r.ExpiresAt = now }, wantErr: true},
+		{name: "expired", modify: func(r *Reservation) { r.ExpiresAt = now.Add(-time.Minute) }, wantErr: true},
+		{name: "beyond max TTL", modify: func(r *Reservation) { r.ExpiresAt = now.Add(maxTTL + time.Second) }, wantErr: true},
+		{name: "no expiry", modify: func(r *Reservation) { r.ExpiresAt = time.Time{} }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(&r)
+
+			err := r.Validate(now, maxTTL)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidReservation) {
+					t.Fatalf("Validate() = %v, want ErrInvalidReservation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+		})
+	}
+}
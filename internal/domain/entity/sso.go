@@ -0,0 +1,43 @@
+package entity
+
+// ExternalIdentity is a user as asserted by the identity provider.
+type ExternalIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	// Groups are the values of the claim roles are mapped from.
+	Groups []string
+}
+
+type RoleMappingRule struct {
+	Value string
+	Role  Role
+}
+
+// RoleMapping turns identity provider claim values into a role. Rules are
+// checked in order and the first one matching any value wins.
+type RoleMapping struct {
+	Rules []RoleMappingRule
+	// DefaultRole applies when no rule matches; empty denies the login.
+	DefaultRole Role
+}
+
+func (m *RoleMapping) Resolve(values []string) (Role, bool) {
+	for _, rule := range m.Rules {
+		for _, v := range values {
+			if v == rule.Value {
+				return rule.Role, true
+			}
+		}
+	}
+
+	return m.DefaultRole, m.DefaultRole != ""
+}
+
+// SSOLoginState has to survive the round trip to the identity provider. The
+// browser keeps it in an HttpOnly cookie.
+type SSOLoginState struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
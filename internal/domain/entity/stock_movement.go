@@ -0,0 +1,131 @@
+package entity
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+type MovementType string
+
+const (
+	MovementReceipt    MovementType = "receipt"
+	MovementIssue      MovementType = "issue"
+	MovementAdjustment MovementType = "adjustment"
+	MovementWriteOff   MovementType = "write_off"
+	MovementReturn     MovementType = "return"
+
+	// Transfer movements are posted by transfer orders only. A transfer
+	// loss writes off what was dispatched but did not arrive.
+	MovementTransferOut  MovementType = "transfer_out"
+	MovementTransferIn   MovementType = "transfer_in"
+	MovementTransferLoss MovementType = "transfer_loss"
+)
+
+const (
+	ReasonStocktake      = "STOCKTAKE"
+	ReasonOpeningBalance = "OPENING_BALANCE"
+	// ReasonStocktakeOverride records a count that leaves less on hand than
+	// is reserved. It is the only way to take stock below the reservations.
+	ReasonStocktakeOverride = "STOCKTAKE_OVERRIDE"
+)
+
+// MaxMovementReferenceLength bounds the reference document number.
+const MaxMovementReferenceLength = 100
+
+// movementReasons lists the reason codes each movement type accepts.
+var movementReasons = map[MovementType][]string{
+	MovementReceipt:      {"PURCHASE", "PRODUCTION", "OTHER"},
+	MovementIssue:        {"SALE", "INTERNAL_USE", "OTHER"},
+	MovementAdjustment:   {ReasonStocktake, "CORRECTION", ReasonOpeningBalance, ReasonStocktakeOverride},
+	MovementWriteOff:     {"DAMAGE", "EXPIRED", "LOSS"},
+	MovementReturn:       {"CUSTOMER_RETURN", "OTHER"},
+	MovementTransferOut:  {ReasonTransfer},
+	MovementTransferIn:   {ReasonTransfer},
+	MovementTransferLoss: {ReasonTransfer},
+}
+
+// ReasonCodes returns the reason codes accepted for a movement type.
+func (t MovementType) ReasonCodes() []string {
+	return movementReasons[t]
+}
+
+func (t MovementType) IsValid() bool {
+	_, ok := movementReasons[t]
+	return ok
+}
+
+// IsManual reports whether the type may be posted directly rather than by a
+// transfer order.
+func (t MovementType) IsManual() bool {
+	return t.IsValid() && t != MovementTransferOut && t != MovementTransferIn && t != MovementTransferLoss
+}
+
+// validDelta reports whether delta has the sign the type implies: receipts,
+// returns and incoming transfers add stock, issues, write-offs, outgoing
+// transfers and transfer losses remove it, adjustments go either way.
+func (t MovementType) validDelta(delta int) bool {
+	switch t {
+	case MovementReceipt, MovementReturn, MovementTransferIn:
+		return delta > 0
+	case MovementIssue, MovementWriteOff, MovementTransferOut, MovementTransferLoss:
+		return delta < 0
+	}
+	return delta != 0
+}
+
+// StockMovement is one entry of the append-only stock ledger. The stock of an
+// item at a location is the sum of the deltas of its movements there.
+type StockMovement struct {
+	ID           int          `json:"id"`
+	ItemID       int          `json:"item_id"`
+	LocationID   int          `json:"location_id"`
+	Type         MovementType `json:"type"`
+	Delta        int          `json:"delta"`
+	BalanceAfter int          `json:"balance_after"`
+	ReasonCode   string       `json:"reason_code"`
+	Reference    string       `json:"reference,omitempty"`
+	Username     string       `json:"username"`
+	APIKey       string       `json:"api_key,omitempty"`
+	RequestID    string       `json:"request_id,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+func (m *StockMovement) Validate() error {
+	if !m.Type.IsValid() {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidMovement, m.Type)
+	}
+	if !m.Type.validDelta(m.Delta) {
+		return fmt.Errorf("%w: delta %d does not fit type %q", ErrInvalidMovement, m.Delta, m.Type)
+	}
+	if !slices.Contains(m.Type.ReasonCodes(), m.ReasonCode) {
+		return fmt.Errorf("%w: reason code %q does not fit type %q", ErrInvalidMovement, m.ReasonCode, m.Type)
+	}
+	if len(m.Reference) > MaxMovementReferenceLength {
+		return fmt.Errorf("%w: reference is too long", ErrInvalidMovement)
+	}
+	return nil
+}
+
+// OverridesReservations reports whether the movement may take on-hand stock
+// below the reserved quantity. A transfer loss only removes goods that were
+// in transit, which no reservation could count on.
+func (m *StockMovement) OverridesReservations() bool {
+	return m.ReasonCode == ReasonStocktakeOverride || m.Type == MovementTransferLoss
+}
+
+// Describe summarises the movement for the history row it produces.
+func (m *StockMovement) Describe() string {
+	s := fmt.Sprintf("%s %s", m.Type, m.ReasonCode)
+	if m.Reference != "" {
+		s += ", ref " + m.Reference
+	}
+	return s
+}
+
+// MovementResult is what posting a movement returns: the ledger entry and the
+// item with its new stock.
+type MovementResult struct {
+	Movement *StockMovement `json:"movement"`
+	Item     *Item          `json:"item"`
+}
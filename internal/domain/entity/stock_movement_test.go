@@ -0,0 +1,108 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStockMovementValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		typ       MovementType
+		delta     int
+		reason    string
+		reference string
+		wantErr   bool
+	}{
+		{name: "receipt", typ: MovementReceipt, delta: 5, reason: "PURCHASE"},
+		{name: "issue", typ: MovementIssue, delta: -5, reason: "SALE"},
+		{name: "write-off", typ: MovementWriteOff, delta: -1, reason: "DAMAGE"},
+		{name: "return", typ: MovementReturn, delta: 2, reason: "CUSTOMER_RETURN"},
+		{name: "adjustment up", typ: MovementAdjustment, delta: 3, reason: ReasonStocktake},
+		{name: "adjustment down", typ: MovementAdjustment, delta: -3, reason: "CORRECTION"},
+		{name: "transfer out", typ: MovementTransferOut, delta: -4, reason: ReasonTransfer},
+		{name: "transfer in", typ: MovementTransferIn, delta: 4, reason: ReasonTransfer},
+		{name: "transfer loss", typ: MovementTransferLoss, delta: -1, reason: ReasonTransfer},
+		{name: "reference at limit", typ: MovementReceipt, delta: 1, reason: "OTHER", reference: strings.Repeat("R", MaxMovementReferenceLength)},
+
+		{name: "unknown type", typ: "gift", delta: 1, reason: "OTHER", wantErr: true},
+		{name: "empty type", typ: "", delta: 1, reason: "OTHER", wantErr: true},
+		{name: "negative receipt", typ: MovementReceipt, delta: -5, reason: "PURCHASE", wantErr: true},
+		{name: "positive issue", typ: MovementIssue, delta: 5, reason: "SALE", wantErr: true},
+		{name: "positive write-off", typ: MovementWriteOff, delta: 1, reason: "LOSS", wantErr: true},
+		{name: "negative return", typ: MovementReturn, delta: -2, reason: "CUSTOMER_RETURN", wantErr: true},
+		{name: "zero adjustment", typ: MovementAdjustment, delta: 0, reason: ReasonStocktake, wantErr: true},
+		{name: "zero receipt", typ: MovementReceipt, delta: 0, reason: "PURCHASE", wantErr: true},
+		{name: "positive transfer out", typ: MovementTransferOut, delta: 4, reason: ReasonTransfer, wantErr: true},
+		{name: "negative transfer in", typ: MovementTransferIn, delta: -4, reason: ReasonTransfer, wantErr: true},
+		{name: "positive transfer loss", typ: MovementTransferLoss, delta: 1, reason: ReasonTransfer, wantErr: true},
+		{name: "reason of another type", typ: MovementIssue, delta: -1, reason: "PURCHASE", wantErr: true},
+		{name: "missing reason", typ: MovementReceipt, delta: 1, reason: "", wantErr: true},
+		{name: "lower-case reason", typ: MovementReceipt, delta: 1, reason: "purchase", wantErr: true},
+		{name: "transfer reason on a receipt", typ: MovementReceipt, delta: 1, reason: ReasonTransfer, wantErr: true},
+		{name: "reference too long", typ: MovementReceipt, delta: 1, reason: "OTHER", reference: strings.Repeat("R", MaxMovementReferenceLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &StockMovement{ItemID: 1, LocationID: 1, Type: tt.typ, Delta: tt.delta, ReasonCode: tt.reason, Reference: tt.reference}
+
+			err := m.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidMovement) {
+					t.Fatalf("Validate() = %v, want ErrInvalidMovement", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestMovementTypeIsManual(t *testing.T) {
+	tests := []struct {
+		typ  MovementType
+		want bool
+	}{
+		{MovementReceipt, true},
+		{MovementIssue, true},
+		{MovementAdjustment, true},
+		{MovementWriteOff, true},
+		{MovementReturn, true},
+		{MovementTransferOut, false},
+		{MovementTransferIn, false},
+		{MovementTransferLoss, false},
+		{"gift", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.typ.IsManual(); got != tt.want {
+			t.Errorf("%q.IsManual() = %v, want %v", tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestStockMovementOverridesReservations(t *testing.T) {
+	tests := []struct {
+		name   string
+		typ    MovementType
+		reason string
+		want   bool
+	}{
+		{name: "stocktake override", typ: MovementAdjustment, reason: ReasonStocktakeOverride, want: true},
+		{name: "transfer loss", typ: MovementTransferLoss, reason: ReasonTransfer, want: true},
+		{name: "stocktake", typ: MovementAdjustment, reason: ReasonStocktake},
+		{name: "loss write-off", typ: MovementWriteOff, reason: "LOSS"},
+		{name: "transfer out", typ: MovementTransferOut, reason: ReasonTransfer},
+	}
+
+	for _, tt := range tests {
+		m := &StockMovement{Type: tt.typ, ReasonCode: tt.reason}
+		if got := m.OverridesReservations(); got != tt.want {
+			t.Errorf("%s: OverridesReservations() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
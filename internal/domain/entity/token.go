@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// RefreshToken is one link of a rotating refresh token family. Every refresh
+// revokes the presented token and issues the next one in the same family.
+type RefreshToken struct {
+	ID              int
+	UserID          int
+	TokenHash       string
+	FamilyID        string
+	AccessJTI       string
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	CreatedAt       time.Time
+	RevokedAt       *time.Time
+}
+
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	AccessExpiresAt  time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
@@ -0,0 +1,177 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+type TransferStatus string
+
+const (
+	TransferDraft      TransferStatus = "draft"
+	TransferDispatched TransferStatus = "dispatched"
+	TransferReceived   TransferStatus = "received"
+	TransferCancelled  TransferStatus = "cancelled"
+)
+
+func (s TransferStatus) IsValid() bool {
+	switch s {
+	case TransferDraft, TransferDispatched, TransferReceived, TransferCancelled:
+		return true
+	}
+	return false
+}
+
+// transferTransitions lists the statuses each status may move to. Goods are
+// only in transit while an order is dispatched, so a dispatched order cannot
+// be cancelled: it is received, with discrepancies if goods went missing.
+var transferTransitions = map[TransferStatus][]TransferStatus{
+	TransferDraft:      {TransferDispatched, TransferCancelled},
+	TransferDispatched: {TransferReceived},
+}
+
+const (
+	MaxTransferLines     = 500
+	ReasonTransfer       = "TRANSFER"
+	maxTransferNoteBytes = 1000
+)
+
+// TransferLine is one item moved by a transfer order. The receipt fields are
+// set when the order is received.
+type TransferLine struct {
+	ID               int    `json:"id"`
+	ItemID           int    `json:"item_id"`
+	FromLocationID   int    `json:"from_location_id"`
+	Quantity         int    `json:"quantity"`
+	ToLocationID     *int   `json:"to_location_id,omitempty"`
+	ReceivedQuantity *int   `json:"received_quantity,omitempty"`
+	Discrepancy      int    `json:"discrepancy"`
+	DiscrepancyNote  string `json:"discrepancy_note,omitempty"`
+}
+
+// TransferOrder moves stock between warehouses in two steps: dispatch takes it
+// out of the source locations, receipt puts it into destination locations.
+// In between the goods are in transit and count towards no location.
+type TransferOrder struct {
+	ID                     int            `json:"id"`
+	Number                 string         `json:"number"`
+	SourceWarehouseID      int            `json:"source_warehouse_id"`
+	DestinationWarehouseID int            `json:"destination_warehouse_id"`
+	Status                 TransferStatus `json:"status"`
+	Note                   string         `json:"note,omitempty"`
+	Lines                  []TransferLine `json:"lines"`
+	CreatedBy              string         `json:"created_by"`
+	CreatedAt              time.Time      `json:"created_at"`
+	UpdatedAt              time.Time      `json:"updated_at"`
+	DispatchedAt           *time.Time     `json:"dispatched_at,omitempty"`
+	ReceivedAt             *time.Time     `json:"received_at,omitempty"`
+	CancelledAt            *time.Time     `json:"cancelled_at,omitempty"`
+}
+
+// TransferNumber is the document number shown to people and used as the
+// reference of the order's stock movements.
+func TransferNumber(id int) string {
+	return fmt.Sprintf("TR-%06d", id)
+}
+
+func (o *TransferOrder) Validate() error {
+	if o.SourceWarehouseID <= 0 || o.DestinationWarehouseID <= 0 {
+		return fmt.Errorf("%w: source and destination warehouses are required", ErrInvalidTransfer)
+	}
+	if o.SourceWarehouseID == o.DestinationWarehouseID {
+		return fmt.Errorf("%w: source and destination must differ", ErrInvalidTransfer)
+	}
+	if len(o.Lines) == 0 || len(o.Lines) > MaxTransferLines {
+		return fmt.Errorf("%w: an order needs 1 to %d lines", ErrInvalidTransfer, MaxTransferLines)
+	}
+	if len(o.Note) > maxTransferNoteBytes {
+		return fmt.Errorf("%w: note is too long", ErrInvalidTransfer)
+	}
+
+	type lineKey struct{ item, location int }
+	seen := make(map[lineKey]bool, len(o.Lines))
+	for _, line := range o.Lines {
+		if line.ItemID <= 0 || line.FromLocationID <= 0 || line.Quantity <= 0 {
+			return fmt.Errorf("%w: every line needs an item, a source location and a positive quantity", ErrInvalidTransfer)
+		}
+		key := lineKey{line.ItemID, line.FromLocationID}
+		if seen[key] {
+			return fmt.Errorf("%w: item %d is listed twice for location %d", ErrInvalidTransfer, line.ItemID, line.FromLocationID)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+func (o *TransferOrder) CanMoveTo(status TransferStatus) bool {
+	for _, next := range transferTransitions[o.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone copies the order deeply enough to serve as a history snapshot.
+func (o *TransferOrder) Clone() *TransferOrder {
+	c := *o
+	c.Lines = append([]TransferLine(nil), o.Lines...)
+	return &c
+}
+
+// TransferReceiptLine says how much of a line arrived and where it was put.
+type TransferReceiptLine struct {
+	LineID     int
+	LocationID int
+	Quantity   int
+	Note       string
+}
+
+// ApplyReceipt fills the receipt fields of every line. The receipt must cover
+// each line exactly once and never exceed the dispatched quantity; a shortfall
+// is kept as the line's discrepancy.
+func (o *TransferOrder) ApplyReceipt(receipt []TransferReceiptLine) error {
+	byLine := make(map[int]TransferReceiptLine, len(receipt))
+	for _, r := range receipt {
+		if _, dup := byLine[r.LineID]; dup {
+			return fmt.Errorf("%w: line %d is listed twice", ErrInvalidReceipt, r.LineID)
+		}
+		byLine[r.LineID] = r
+	}
+
+	if len(byLine) != len(o.Lines) {
+		return fmt.Errorf("%w: every line of the order must be received", ErrInvalidReceipt)
+	}
+
+	for i := range o.Lines {
+		line := &o.Lines[i]
+		r, ok := byLine[line.ID]
+		if !ok {
+			return fmt.Errorf("%w: line %d is missing", ErrInvalidReceipt, line.ID)
+		}
+		if r.Quantity < 0 || r.Quantity > line.Quantity {
+			return fmt.Errorf("%w: line %d can receive 0 to %d", ErrInvalidReceipt, line.ID, line.Quantity)
+		}
+		if r.LocationID <= 0 {
+			return fmt.Errorf("%w: line %d needs a destination location", ErrInvalidReceipt, line.ID)
+		}
+		if len(r.Note) > maxTransferNoteBytes {
+			return fmt.Errorf("%w: note of line %d is too long", ErrInvalidReceipt, line.ID)
+		}
+
+		location, quantity := r.LocationID, r.Quantity
+		line.ToLocationID = &location
+		line.ReceivedQuantity = &quantity
+		line.Discrepancy = line.Quantity - quantity
+		line.DiscrepancyNote = r.Note
+	}
+
+	return nil
+}
+
+type TransferFilter struct {
+	Status *TransferStatus
+	Limit  int
+	Offset int
+}
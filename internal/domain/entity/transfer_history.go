@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+type TransferAction string
+
+const (
+	TransferActionCreate   TransferAction = "CREATE"
+	TransferActionDispatch TransferAction = "DISPATCH"
+	TransferActionReceive  TransferAction = "RECEIVE"
+	TransferActionCancel   TransferAction = "CANCEL"
+)
+
+// TransferHistory is an audit row for a state change of a transfer order.
+type TransferHistory struct {
+	ID         int            `json:"id"`
+	TransferID int            `json:"transfer_id"`
+	Action     TransferAction `json:"action"`
+	Username   string         `json:"username"`
+	APIKey     string         `json:"api_key,omitempty"`
+	OldData    *TransferOrder `json:"old_data,omitempty"`
+	NewData    *TransferOrder `json:"new_data,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	ClientIP   string         `json:"client_ip,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+	ChangedAt  time.Time      `json:"changed_at"`
+}
+
+func NewTransferHistory(transferID int, action TransferAction, oldData, newData *TransferOrder, meta *AuditMeta) *TransferHistory {
+	return &TransferHistory{
+		TransferID: transferID,
+		Action:     action,
+		Username:   meta.Username,
+		APIKey:     meta.APIKey,
+		OldData:    oldData,
+		NewData:    newData,
+		RequestID:  meta.RequestID,
+		ClientIP:   meta.ClientIP,
+		Reason:     meta.Reason,
+	}
+}
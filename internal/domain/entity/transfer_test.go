@@ -0,0 +1,201 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTransferOrderCanMoveTo(t *testing.T) {
+	tests := []struct {
+		from, to TransferStatus
+		want     bool
+	}{
+		{TransferDraft, TransferDispatched, true},
+		{TransferDraft, TransferCancelled, true},
+		{TransferDraft, TransferReceived, false},
+		{TransferDraft, TransferDraft, false},
+		{TransferDispatched, TransferReceived, true},
+		{TransferDispatched, TransferCancelled, false},
+		{TransferDispatched, TransferDraft, false},
+		{TransferDispatched, TransferDispatched, false},
+		{TransferReceived, TransferDispatched, false},
+		{TransferReceived, TransferCancelled, false},
+		{TransferCancelled, TransferDraft, false},
+		{TransferCancelled, TransferDispatched, false},
+		{"lost", TransferReceived, false},
+	}
+
+	for _, tt := range tests {
+		order := &TransferOrder{Status: tt.from}
+		if got := order.CanMoveTo(tt.to); got != tt.want {
+			t.Errorf("%s -> %s: CanMoveTo() = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func dispatchedOrder() *TransferOrder {
+	return &TransferOrder{
+		Status: TransferDispatched,
+		Lines: []TransferLine{
+			{ID: 1, ItemID: 10, FromLocationID: 100, Quantity: 5},
+			{ID: 2, ItemID: 20, FromLocationID: 100, Quantity: 3},
+		},
+	}
+}
+
+func TestTransferOrderApplyReceipt(t *testing.T) {
+	tests := []struct {
+		name            string
+		receipt         []TransferReceiptLine
+		wantErr         bool
+		wantDiscrepancy map[int]int
+	}{
+		{
+			name: "in full",
+			receipt: []TransferReceiptLine{
+				{LineID: 1, LocationID: 200, Quantity: 5},
+				{LineID: 2, LocationID: 201, Quantity: 3},
+			},
+			wantDiscrepancy: map[int]int{1: 0, 2: 0},
+		},
+		{
+			name: "short",
+			receipt: []TransferReceiptLine{
+				{LineID: 2, LocationID: 200, Quantity: 1, Note: "broken"},
+				{LineID: 1, LocationID: 200, Quantity: 4},
+			},
+			wantDiscrepancy: map[int]int{1: 1, 2: 2},
+		},
+		{
+			name: "nothing arrived",
+			receipt: []TransferReceiptLine{
+				{LineID: 1, LocationID: 200, Quantity: 0},
+				{LineID: 2, LocationID: 200, Quantity: 0},
+			},
+			wantDiscrepancy: map[int]int{1: 5, 2: 3},
+		},
+		{
+			name:    "line missing",
+			receipt: []TransferReceiptLine{{LineID: 1, LocationID: 200, Quantity: 5}},
+			wantErr: true,
+		},
+		{
+			name: "line listed twice",
+			receipt: []TransferReceiptLine{
+				{LineID: 1, LocationID: 200, Quantity: 2},
+				{LineID: 1, LocationID: 200, Quantity: 3},
+				{LineID: 2, LocationID: 200, Quantity: 3},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown line",
+			receipt: []TransferReceiptLine{
+				{LineID: 1, LocationID: 200, Quantity: 5},
+				{LineID: 3, LocationID: 200, Quantity: 3},
+			},
+			wantErr: true,
+		},
+		{
+			name: "more than dispatched",
+			receipt: []TransferReceiptLine{
+				{LineID: 1, LocationID: 200, Quantity: 6},
+				{LineID: 2, LocationID: 200, Quantity: 3},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative quantity",
+			receipt: []TransferReceiptLine{
+				{LineID: 1, LocationID: 200, Quantity: -1},
+				{LineID: 2, LocationID: 200, Quantity: 3},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no location",
+			receipt: []TransferReceiptLine{
+				{LineID: 1, Quantity: 5},
+				{LineID: 2, LocationID: 200, Quantity: 3},
+			},
+			wantErr: true,
+		},
+		{
+			name: "note too long",
+			receipt: []TransferReceiptLine{
+				{LineID: 1, LocationID: 200, Quantity: 4, Note: strings.Repeat("n", maxTransferNoteBytes+1)},
+				{LineID: 2, LocationID: 200, Quantity: 3},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := dispatchedOrder()
+
+			err := order.ApplyReceipt(tt.receipt)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidReceipt) {
+					t.Fatalf("ApplyReceipt() = %v, want ErrInvalidReceipt", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyReceipt() = %v, want nil", err)
+			}
+
+			received := make(map[int]TransferReceiptLine, len(tt.receipt))
+			for _, r := range tt.receipt {
+				received[r.LineID] = r
+			}
+
+			for _, line := range order.Lines {
+				r := received[line.ID]
+				if line.ReceivedQuantity == nil || *line.ReceivedQuantity != r.Quantity {
+					t.Errorf("line %d: received quantity = %v, want %d", line.ID, line.ReceivedQuantity, r.Quantity)
+				}
+				if line.ToLocationID == nil || *line.ToLocationID != r.LocationID {
+					t.Errorf("line %d: location = %v, want %d", line.ID, line.ToLocationID, r.LocationID)
+				}
+				if line.Discrepancy != tt.wantDiscrepancy[line.ID] {
+					t.Errorf("line %d: discrepancy = %d, want %d", line.ID, line.Discrepancy, tt.wantDiscrepancy[line.ID])
+				}
+				if line.DiscrepancyNote != r.Note {
+					t.Errorf("line %d: note = %q, want %q", line.ID, line.DiscrepancyNote, r.Note)
+				}
+			}
+		})
+	}
+}
+
+func TestTransferOrderValidate(t *testing.T) {
+	line := TransferLine{ItemID: 1, FromLocationID: 1, Quantity: 1}
+
+	tests := []struct {
+		name    string
+		order   TransferOrder
+		wantErr bool
+	}{
+		{name: "valid", order: TransferOrder{SourceWarehouseID: 1, DestinationWarehouseID: 2, Lines: []TransferLine{line}}},
+		{name: "same warehouse", order: TransferOrder{SourceWarehouseID: 1, DestinationWarehouseID: 1, Lines: []TransferLine{line}}, wantErr: true},
+		{name: "no destination", order: TransferOrder{SourceWarehouseID: 1, Lines: []TransferLine{line}}, wantErr: true},
+		{name: "no lines", order: TransferOrder{SourceWarehouseID: 1, DestinationWarehouseID: 2}, wantErr: true},
+		{name: "zero quantity", order: TransferOrder{SourceWarehouseID: 1, DestinationWarehouseID: 2, Lines: []TransferLine{{ItemID: 1, FromLocationID: 1}}}, wantErr: true},
+		{name: "duplicate line", order: TransferOrder{SourceWarehouseID: 1, DestinationWarehouseID: 2, Lines: []TransferLine{line, line}}, wantErr: true},
+		{name: "same item from two locations", order: TransferOrder{SourceWarehouseID: 1, DestinationWarehouseID: 2, Lines: []TransferLine{line, {ItemID: 1, FromLocationID: 2, Quantity: 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.order.Validate()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTransfer) {
+				t.Fatalf("Validate() = %v, want ErrInvalidTransfer", err)
+			}
+		})
+	}
+}
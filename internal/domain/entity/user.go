@@ -10,30 +10,57 @@ const (
 	RoleViewer  Role = "viewer"
 )
 
-type User struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"`
-	Role      Role      `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-}
+type RegistrationMode string
+
+const (
+	RegistrationDisabled RegistrationMode = "disabled"
+	RegistrationViewer   RegistrationMode = "viewer"
+)
 
-func (u *User) CanCreate() bool {
-	return u.Role == RoleAdmin || u.Role == RoleManager
+func (m RegistrationMode) IsValid() bool {
+	return m == RegistrationDisabled || m == RegistrationViewer
 }
 
-func (u *User) CanUpdate() bool {
-	return u.Role == RoleAdmin || u.Role == RoleManager
+type User struct {
+	ID         int        `json:"id"`
+	Username   string     `json:"username"`
+	Password   string     `json:"-"`
+	Role       Role       `json:"role"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+
+	// PasswordChangeRequired is set for seeded accounts and after an admin
+	// chose the password. Until it is changed the account can do nothing else.
+	PasswordChangeRequired bool `json:"password_change_required"`
+	// SSOIssuer and SSOSubject link the account to an identity provider user.
+	// Such accounts are provisioned on first SSO login and have no password.
+	SSOIssuer  string `json:"sso_issuer,omitempty"`
+	SSOSubject string `json:"-"`
+
+	// Permissions are resolved from the role when the user is authenticated.
+	Permissions []Permission `json:"-"`
+	// APIKeyName is set when the request was authenticated with an API key.
+	APIKeyName string `json:"-"`
 }
 
-func (u *User) CanDelete() bool {
-	return u.Role == RoleAdmin
+// Can reports whether any of the user's permissions grants perm.
+func (u *User) Can(perm Permission) bool {
+	for _, p := range u.Permissions {
+		if p.Grants(perm) {
+			return true
+		}
+	}
+	return false
 }
 
-func (u *User) CanView() bool {
-	return true
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
 }
 
-func (u *User) CanViewHistory() bool {
-	return true
+type UserFilter struct {
+	Username *string
+	Role     *Role
+	Disabled *bool
+	Limit    int
+	Offset   int
 }
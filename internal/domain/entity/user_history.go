@@ -0,0 +1,47 @@
+package entity
+
+import "time"
+
+type UserAction string
+
+const (
+	UserActionCreate         UserAction = "CREATE"
+	UserActionUpdateRole     UserAction = "UPDATE_ROLE"
+	UserActionDisable        UserAction = "DISABLE"
+	UserActionEnable         UserAction = "ENABLE"
+	UserActionResetPassword  UserAction = "RESET_PASSWORD"
+	UserActionDelete         UserAction = "DELETE"
+	UserActionUnlock         UserAction = "UNLOCK"
+	UserActionResetMFA       UserAction = "RESET_MFA"
+	UserActionChangePassword UserAction = "CHANGE_PASSWORD"
+)
+
+// UserHistory is an audit row for a change made to an account. Snapshots never
+// contain the password hash.
+type UserHistory struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Action    UserAction `json:"action"`
+	Username  string     `json:"username"`
+	APIKey    string     `json:"api_key,omitempty"`
+	OldData   *User      `json:"old_data,omitempty"`
+	NewData   *User      `json:"new_data,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+	ClientIP  string     `json:"client_ip,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	ChangedAt time.Time  `json:"changed_at"`
+}
+
+func NewUserHistory(userID int, action UserAction, oldData, newData *User, meta *AuditMeta) *UserHistory {
+	return &UserHistory{
+		UserID:    userID,
+		Action:    action,
+		Username:  meta.Username,
+		APIKey:    meta.APIKey,
+		OldData:   oldData,
+		NewData:   newData,
+		RequestID: meta.RequestID,
+		ClientIP:  meta.ClientIP,
+		Reason:    meta.Reason,
+	}
+}
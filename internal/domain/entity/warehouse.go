@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	MaxWarehouseCodeLength = 32
+	MaxLocationCodeLength  = 64
+	MaxZoneLength          = 32
+)
+
+type Warehouse struct {
+	ID        int       `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (w *Warehouse) Normalize() {
+	w.Code = strings.ToUpper(strings.TrimSpace(w.Code))
+	w.Name = strings.TrimSpace(w.Name)
+}
+
+func (w *Warehouse) Validate() error {
+	if !isCode(w.Code, MaxWarehouseCodeLength) || w.Name == "" {
+		return ErrInvalidWarehouse
+	}
+	return nil
+}
+
+// Location is a place inside a warehouse where stock is kept, usually a bin.
+// Zone groups locations, e.g. receiving, cold storage or an aisle.
+type Location struct {
+	ID            int       `json:"id"`
+	WarehouseID   int       `json:"warehouse_id"`
+	WarehouseCode string    `json:"warehouse_code"`
+	Code          string    `json:"code"`
+	Zone          string    `json:"zone"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (l *Location) Normalize() {
+	l.Code = strings.ToUpper(strings.TrimSpace(l.Code))
+	l.Zone = strings.ToUpper(strings.TrimSpace(l.Zone))
+}
+
+func (l *Location) Validate() error {
+	if l.WarehouseID <= 0 || !isCode(l.Code, MaxLocationCodeLength) {
+		return ErrInvalidLocation
+	}
+	if l.Zone != "" && !isCode(l.Zone, MaxZoneLength) {
+		return ErrInvalidLocation
+	}
+	return nil
+}
+
+// StockLevel is how much of an item is kept at one location. An item's
+// Quantity is the sum of its stock levels.
+type StockLevel struct {
+	LocationID    int    `json:"location_id"`
+	LocationCode  string `json:"location_code"`
+	Zone          string `json:"zone"`
+	WarehouseID   int    `json:"warehouse_id"`
+	WarehouseCode string `json:"warehouse_code"`
+	Quantity      int    `json:"quantity"`
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *entity.APIKey, keyHash string) error
+	GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+	GetByUserID(ctx context.Context, userID int) ([]*entity.APIKey, error)
+	Revoke(ctx context.Context, id, userID int) error
+	TouchLastUsed(ctx context.Context, id int, at time.Time) error
+}
@@ -7,6 +7,11 @@ import (
 )
 
 type HistoryRepository interface {
-	GetByItemID(ctx context.Context, itemID int) ([]*entity.ItemHistory, error)
+	Record(ctx context.Context, h *entity.ItemHistory) error
+	GetByID(ctx context.Context, id int) (*entity.ItemHistory, error)
 	GetAll(ctx context.Context, filter *entity.HistoryFilter) ([]*entity.ItemHistory, error)
+	Stream(ctx context.Context, filter *entity.HistoryFilter, fn func(*entity.ItemHistory) error) error
+	// StreamChain walks the chain in order and returns its head, both read
+	// from the same snapshot.
+	StreamChain(ctx context.Context, fn func(*entity.ChainLink) error) (*entity.ChainHead, error)
 }
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *entity.Invitation, codeHash string) error
+	GetByCodeHashForUpdate(ctx context.Context, codeHash string) (*entity.Invitation, error)
+	MarkUsed(ctx context.Context, id int, username string) error
+}
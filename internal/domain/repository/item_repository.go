@@ -9,7 +9,11 @@ import (
 type ItemRepository interface {
 	Create(ctx context.Context, item *entity.Item, username string) error
 	GetByID(ctx context.Context, id int) (*entity.Item, error)
-	GetAll(ctx context.Context) ([]*entity.Item, error)
+	GetByIDForUpdate(ctx context.Context, id int) (*entity.Item, error)
+	GetByCode(ctx context.Context, code string) (*entity.Item, error)
+	GetAll(ctx context.Context, filter *entity.ItemFilter) ([]*entity.Item, int, error)
 	Update(ctx context.Context, item *entity.Item, username string) error
-	Delete(ctx context.Context, id int, username string) error
+	Touch(ctx context.Context, id int, username string) error
+	Delete(ctx context.Context, id int, version int, username string) error
+	Restore(ctx context.Context, id int, username string) (*entity.Item, error)
 }
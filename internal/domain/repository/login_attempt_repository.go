@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+// LoginAttemptRepository stores failed login counters. The in-memory store is
+// enough for a single instance; several instances must share the Postgres one.
+type LoginAttemptRepository interface {
+	// Reserve counts an attempt as failed before its credentials are checked.
+	// Deciding whether the attempt may be made and counting it is one atomic
+	// step, so parallel guesses cannot all pass the check before the first of
+	// them is counted. While policy makes the key wait, nothing is counted and
+	// the wait is returned instead.
+	Reserve(ctx context.Context, key string, now time.Time, policy entity.LoginThrottlePolicy) (*entity.LoginAttempts, time.Duration, error)
+	// Release takes back a reserved attempt that turned out to succeed.
+	Release(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type MFARepository interface {
+	GetByUserID(ctx context.Context, userID int) (*entity.MFA, error)
+	GetByUserIDForUpdate(ctx context.Context, userID int) (*entity.MFA, error)
+	// SavePending stores a new secret that is not enabled yet, replacing an
+	// earlier unconfirmed one.
+	SavePending(ctx context.Context, userID int, secret string) error
+	Enable(ctx context.Context, userID int, step int64) error
+	SetLastStep(ctx context.Context, userID int, step int64) error
+	Delete(ctx context.Context, userID int) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+}
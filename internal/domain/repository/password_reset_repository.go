@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *entity.PasswordReset, tokenHash string) error
+	GetByTokenHashForUpdate(ctx context.Context, tokenHash string) (*entity.PasswordReset, error)
+	DeleteByUserID(ctx context.Context, userID int) error
+}
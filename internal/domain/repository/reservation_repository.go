@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type ReservationRepository interface {
+	Create(ctx context.Context, reservation *entity.Reservation) error
+	GetByID(ctx context.Context, id int) (*entity.Reservation, error)
+	GetByIDForUpdate(ctx context.Context, id int) (*entity.Reservation, error)
+	GetAll(ctx context.Context, filter *entity.ReservationFilter) ([]*entity.Reservation, int, error)
+	// Release marks an active reservation released by username.
+	Release(ctx context.Context, reservation *entity.Reservation, username, reason string) error
+	// ReleaseExpired marks every active reservation past its expiry as
+	// expired and returns how many there were.
+	ReleaseExpired(ctx context.Context) (int, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+// StockRepository keeps the current stock levels, which are the running sum
+// of the ledger. It must only be written together with a movement.
+type StockRepository interface {
+	GetLevel(ctx context.Context, itemID, locationID int) (int, error)
+	// SetLevel stores the quantity of an item at a location. Zero removes
+	// the level.
+	SetLevel(ctx context.Context, itemID, locationID, quantity int) error
+}
+
+type StockMovementRepository interface {
+	Create(ctx context.Context, movement *entity.StockMovement) error
+	GetByItemID(ctx context.Context, itemID, limit, offset int) ([]*entity.StockMovement, int, error)
+}
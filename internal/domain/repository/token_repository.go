@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error
+	GetRefreshTokenForUpdate(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id int) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUserSessions(ctx context.Context, userID int) error
+	DenyAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type TransferRepository interface {
+	// Create stores a draft order with its lines.
+	Create(ctx context.Context, order *entity.TransferOrder) error
+	GetByID(ctx context.Context, id int) (*entity.TransferOrder, error)
+	GetByIDForUpdate(ctx context.Context, id int) (*entity.TransferOrder, error)
+	GetAll(ctx context.Context, filter *entity.TransferFilter) ([]*entity.TransferOrder, int, error)
+	// Update stores the order status, stamping the time of the transition,
+	// and the receipt fields of its lines.
+	Update(ctx context.Context, order *entity.TransferOrder) error
+}
+
+type TransferHistoryRepository interface {
+	Record(ctx context.Context, h *entity.TransferHistory) error
+	GetByTransferID(ctx context.Context, transferID, limit, offset int) ([]*entity.TransferHistory, int, error)
+}
@@ -7,6 +7,19 @@ import (
 )
 
 type UserRepository interface {
+	GetByID(ctx context.Context, id int) (*entity.User, error)
+	GetByIDForUpdate(ctx context.Context, id int) (*entity.User, error)
 	GetByUsername(ctx context.Context, username string) (*entity.User, error)
+	GetBySSOSubject(ctx context.Context, issuer, subject string) (*entity.User, error)
+	GetAll(ctx context.Context, filter *entity.UserFilter) ([]*entity.User, int, error)
 	Create(ctx context.Context, user *entity.User) error
+	UpdateRole(ctx context.Context, id int, role entity.Role) error
+	SetDisabled(ctx context.Context, id int, disabled bool) error
+	UpdatePassword(ctx context.Context, id int, password string, changeRequired bool) error
+	Delete(ctx context.Context, id int) error
+}
+
+type UserHistoryRepository interface {
+	Record(ctx context.Context, h *entity.UserHistory) error
+	GetByUserID(ctx context.Context, userID, limit, offset int) ([]*entity.UserHistory, int, error)
 }
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type WarehouseRepository interface {
+	Create(ctx context.Context, warehouse *entity.Warehouse) error
+	GetByID(ctx context.Context, id int) (*entity.Warehouse, error)
+	GetAll(ctx context.Context) ([]*entity.Warehouse, error)
+}
+
+type LocationRepository interface {
+	Create(ctx context.Context, location *entity.Location) error
+	GetByID(ctx context.Context, id int) (*entity.Location, error)
+	// GetAll lists locations of one warehouse, or of all of them when
+	// warehouseID is nil.
+	GetAll(ctx context.Context, warehouseID *int) ([]*entity.Location, error)
+}
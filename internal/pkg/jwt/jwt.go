@@ -1,6 +1,9 @@
 package jwt
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -11,47 +14,112 @@ import (
 type Claims struct {
 	Username string      `json:"username"`
 	Role     entity.Role `json:"role"`
+	// Purpose is empty for access tokens. Tokens issued for anything else,
+	// such as finishing an MFA login, are never accepted as access tokens.
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
+const purposeMFA = "mfa"
+
+// mfaAudienceSuffix gives MFA-pending tokens their own audience, so a service
+// that only checks aud cannot take one for an access token.
+const mfaAudienceSuffix = "/mfa"
+
+// Denylist reports access tokens revoked before they expire.
+type Denylist interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type Token struct {
+	Value     string
+	ID        string
+	ExpiresAt time.Time
+}
+
 type Manager struct {
-	secret     string
+	keys       *KeyRing
+	issuer     string
+	audience   string
 	expiration time.Duration
+	denylist   Denylist
 }
 
-func NewManager(secret string, expiration time.Duration) *Manager {
+// NewManager creates a manager that issues tokens as issuer for audience and
+// accepts only tokens that carry both.
+func NewManager(keys *KeyRing, issuer, audience string, expiration time.Duration, denylist Denylist) *Manager {
 	return &Manager{
-		secret:     secret,
+		keys:       keys,
+		issuer:     issuer,
+		audience:   audience,
 		expiration: expiration,
+		denylist:   denylist,
 	}
 }
 
-func (m *Manager) Generate(username string, role entity.Role) (string, error) {
+// JWKS returns the public keys other services need to verify our tokens.
+func (m *Manager) JWKS() JWKSet {
+	return m.keys.JWKS()
+}
+
+func (m *Manager) Generate(username string, role entity.Role) (*Token, error) {
+	return m.generate(username, role, "", m.expiration)
+}
+
+// GenerateMFA issues the short-lived token that proves the password step of a
+// login succeeded and a second factor is still expected.
+func (m *Manager) GenerateMFA(username string, ttl time.Duration) (*Token, error) {
+	return m.generate(username, "", purposeMFA, ttl)
+}
+
+func (m *Manager) generate(username string, role entity.Role, purpose string, ttl time.Duration) (*Token, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
 	claims := &Claims{
 		Username: username,
 		Role:     role,
+		Purpose:  purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.expiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        id,
+			Issuer:    m.issuer,
+			Audience:  jwt.ClaimStrings{m.audienceFor(purpose)},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(m.secret))
+	key := m.keys.Active()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+
+	tokenString, err := token.SignedString(key.signer)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
+		return nil, fmt.Errorf("failed to sign token: %w", err)
 	}
 
-	return tokenString, nil
+	return &Token{Value: tokenString, ID: id, ExpiresAt: expiresAt}, nil
 }
 
-func (m *Manager) Verify(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(m.secret), nil
-	})
+func (m *Manager) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	return m.verify(ctx, tokenString, "")
+}
+
+func (m *Manager) VerifyMFA(ctx context.Context, tokenString string) (*Claims, error) {
+	return m.verify(ctx, tokenString, purposeMFA)
+}
+
+func (m *Manager) verify(ctx context.Context, tokenString, purpose string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.verificationKey,
+		jwt.WithIssuer(m.issuer),
+		jwt.WithAudience(m.audienceFor(purpose)),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -62,5 +130,59 @@ func (m *Manager) Verify(tokenString string) (*Claims, error) {
 		return nil, fmt.Errorf("invalid token")
 	}
 
+	if claims.Purpose != purpose {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	// Tokens without an ID cannot be revoked, so they are not accepted.
+	if claims.ID == "" {
+		return nil, entity.ErrTokenRevoked
+	}
+
+	if m.denylist != nil {
+		revoked, err := m.denylist.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, entity.ErrTokenRevoked
+		}
+	}
+
 	return claims, nil
 }
+
+func (m *Manager) audienceFor(purpose string) string {
+	if purpose == purposeMFA {
+		return m.audience + mfaAudienceSuffix
+	}
+	return m.audience
+}
+
+// verificationKey picks the key by the token's kid. The algorithm has to be
+// the one of that key, so a public key can never be used as an HMAC secret.
+func (m *Manager) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = DefaultKeyID
+	}
+
+	key, ok := m.keys.Get(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.Algorithm() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,277 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+const (
+	testIssuer   = "warehouse-control"
+	testAudience = "warehouse-control-api"
+)
+
+func newHMACKey(t *testing.T, id, secret string) *Key {
+	t.Helper()
+
+	key, err := NewHMACKey(id, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newKeyRing(t *testing.T, active *Key, verifyOnly ...*Key) *KeyRing {
+	t.Helper()
+
+	ring, err := NewKeyRing(active, verifyOnly...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+// newEd25519Key returns a signing key and its verify-only public half.
+func newEd25519Key(t *testing.T, id string) (*Key, *Key) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signing, err := ParsePrivateKeyPEM(id, AlgEdDSA, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := ParsePublicKeyPEM(id, AlgEdDSA, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signing, public
+}
+
+func validClaims() *Claims {
+	return &Claims{
+		Username: "jane",
+		Role:     entity.RoleManager,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "0123456789abcdef",
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+}
+
+// sign signs claims with key the way another issuer could, with any kid and
+// method.
+func sign(t *testing.T, method jwt.SigningMethod, secret interface{}, kid string, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	s, err := token.SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestManagerRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	for _, alg := range []string{AlgHS256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			key := newHMACKey(t, "k1", "secret")
+			if alg == AlgEdDSA {
+				key, _ = newEd25519Key(t, "k1")
+			}
+			m := NewManager(newKeyRing(t, key), testIssuer, testAudience, time.Hour, nil)
+
+			token, err := m.Generate("jane", entity.RoleManager)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims, err := m.Verify(ctx, token.Value)
+			if err != nil {
+				t.Fatalf("Verify() = %v", err)
+			}
+			if claims.Username != "jane" || claims.Role != entity.RoleManager || claims.ID != token.ID {
+				t.Errorf("Verify() = %+v", claims)
+			}
+
+			if _, err := m.VerifyMFA(ctx, token.Value); err == nil {
+				t.Error("VerifyMFA accepted an access token")
+			}
+
+			mfa, err := m.GenerateMFA("jane", time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := m.Verify(ctx, mfa.Value); err == nil {
+				t.Error("Verify accepted an MFA token")
+			}
+			if _, err := m.VerifyMFA(ctx, mfa.Value); err != nil {
+				t.Errorf("VerifyMFA() = %v", err)
+			}
+		})
+	}
+}
+
+func TestManagerKeySelection(t *testing.T) {
+	oldKey := newHMACKey(t, "old", "old-secret")
+	defaultKey := newHMACKey(t, DefaultKeyID, "default-secret")
+	edKey, edPublic := newEd25519Key(t, "ed")
+	m := NewManager(newKeyRing(t, newHMACKey(t, "new", "new-secret"), oldKey, defaultKey, edPublic), testIssuer, testAudience, time.Hour, nil)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "retired HMAC key", token: sign(t, jwt.SigningMethodHS256, []byte("old-secret"), "old", validClaims())},
+		{name: "retired Ed25519 key", token: sign(t, jwt.SigningMethodEdDSA, edKey.signer, "ed", validClaims())},
+		{name: "no kid uses the default key", token: sign(t, jwt.SigningMethodHS256, []byte("default-secret"), "", validClaims())},
+		{name: "kid of another key", token: sign(t, jwt.SigningMethodHS256, []byte("old-secret"), "new", validClaims()), wantErr: true},
+		{name: "no kid signed by another key", token: sign(t, jwt.SigningMethodHS256, []byte("old-secret"), "", validClaims()), wantErr: true},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodHS256, []byte("old-secret"), "gone", validClaims()), wantErr: true},
+		{name: "unknown secret", token: sign(t, jwt.SigningMethodHS256, []byte("guessed"), "new", validClaims()), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.Verify(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManagerRejectsAlgorithmMismatch(t *testing.T) {
+	edKey, edPublic := newEd25519Key(t, "ed")
+	m := NewManager(newKeyRing(t, edKey, newHMACKey(t, "hmac", "secret")), testIssuer, testAudience, time.Hour, nil)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		// The classic confusion attack: the public key used as an HMAC secret.
+		{name: "public key as HMAC secret", token: sign(t, jwt.SigningMethodHS256, []byte(edPublic.public.(ed25519.PublicKey)), "ed", validClaims())},
+		{name: "HS384 with the HMAC key", token: sign(t, jwt.SigningMethodHS384, []byte("secret"), "hmac", validClaims())},
+		{name: "none", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "hmac", validClaims())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Verify(context.Background(), tt.token); err == nil {
+				t.Error("Verify() accepted the token")
+			}
+		})
+	}
+
+	if _, err := ParsePrivateKeyPEM("ed", AlgRS256, pemOf(t, edKey)); err == nil || !strings.Contains(err.Error(), AlgEdDSA) {
+		t.Errorf("ParsePrivateKeyPEM with the wrong algorithm = %v", err)
+	}
+}
+
+func pemOf(t *testing.T, key *Key) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+type revokedIDs map[string]bool
+
+func (r revokedIDs) IsRevoked(_ context.Context, jti string) (bool, error) {
+	return r[jti], nil
+}
+
+func TestManagerRequiresTokenID(t *testing.T) {
+	claims := validClaims()
+	claims.ID = ""
+	token := sign(t, jwt.SigningMethodHS256, []byte("secret"), "k1", claims)
+
+	m := NewManager(newKeyRing(t, newHMACKey(t, "k1", "secret")), testIssuer, testAudience, time.Hour, nil)
+	if _, err := m.Verify(context.Background(), token); !errors.Is(err, entity.ErrTokenRevoked) {
+		t.Errorf("Verify() without jti = %v, want ErrTokenRevoked", err)
+	}
+
+	revoked := sign(t, jwt.SigningMethodHS256, []byte("secret"), "k1", validClaims())
+	m = NewManager(newKeyRing(t, newHMACKey(t, "k1", "secret")), testIssuer, testAudience, time.Hour, revokedIDs{validClaims().ID: true})
+	if _, err := m.Verify(context.Background(), revoked); !errors.Is(err, entity.ErrTokenRevoked) {
+		t.Errorf("Verify() of a revoked token = %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestManagerIssuerAudience(t *testing.T) {
+	m := NewManager(newKeyRing(t, newHMACKey(t, "k1", "secret")), testIssuer, testAudience, time.Hour, nil)
+
+	tests := []struct {
+		name    string
+		modify  func(c *Claims)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(c *Claims) {}},
+		{name: "one of several audiences", modify: func(c *Claims) { c.Audience = jwt.ClaimStrings{"reports", testAudience} }},
+		{name: "other issuer", modify: func(c *Claims) { c.Issuer = "someone-else" }, wantErr: true},
+		{name: "no issuer", modify: func(c *Claims) { c.Issuer = "" }, wantErr: true},
+		{name: "other audience", modify: func(c *Claims) { c.Audience = jwt.ClaimStrings{"reports"} }, wantErr: true},
+		{name: "no audience", modify: func(c *Claims) { c.Audience = nil }, wantErr: true},
+		{name: "MFA audience", modify: func(c *Claims) { c.Audience = jwt.ClaimStrings{testAudience + mfaAudienceSuffix} }, wantErr: true},
+		{name: "no expiry", modify: func(c *Claims) { c.ExpiresAt = nil }, wantErr: true},
+		{name: "expired", modify: func(c *Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.modify(claims)
+
+			_, err := m.Verify(context.Background(), sign(t, jwt.SigningMethodHS256, []byte("secret"), "k1", claims))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// An MFA token carries its own audience and is checked against it.
+	mfa, err := m.GenerateMFA("jane", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(mfa.Value, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aud, _ := parsed.Claims.GetAudience(); len(aud) != 1 || aud[0] != testAudience+mfaAudienceSuffix {
+		t.Errorf("MFA token audience = %v", aud)
+	}
+}
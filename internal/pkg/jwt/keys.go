@@ -0,0 +1,216 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// DefaultKeyID is the kid of the key built from a plain secret. Tokens signed
+// before key IDs were introduced carry no kid and are checked against it.
+const DefaultKeyID = "default"
+
+// minRSABits is the smallest RSA modulus accepted for signing or verifying.
+const minRSABits = 2048
+
+// Key is one entry of a KeyRing. Keys built from a public key can only
+// verify.
+type Key struct {
+	ID     string
+	method jwt.SigningMethod
+	signer interface{}
+	public interface{}
+}
+
+func NewHMACKey(id, secret string) (*Key, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("key %q: empty secret", id)
+	}
+
+	return &Key{ID: id, method: jwt.SigningMethodHS256, signer: []byte(secret), public: []byte(secret)}, nil
+}
+
+// ParsePrivateKeyPEM reads an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8)
+// private key. The algorithm must match the key type.
+func ParsePrivateKeyPEM(id, alg string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM block found", id)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		rsaKey, pkcs1Err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if pkcs1Err != nil {
+			return nil, fmt.Errorf("key %q: failed to parse private key: %w", id, err)
+		}
+		parsed = rsaKey
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key %q: unsupported private key type %T", id, parsed)
+	}
+
+	key, err := newAsymmetricKey(id, alg, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.signer = signer
+
+	return key, nil
+}
+
+// ParsePublicKeyPEM reads a PKIX public key. The result only verifies tokens,
+// e.g. ones signed with a retired private key.
+func ParsePublicKeyPEM(id, alg string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM block found", id)
+	}
+
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: failed to parse public key: %w", id, err)
+	}
+
+	return newAsymmetricKey(id, alg, public)
+}
+
+func newAsymmetricKey(id, alg string, public interface{}) (*Key, error) {
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if alg != AlgRS256 {
+			return nil, fmt.Errorf("key %q: RSA key requires algorithm %s, got %q", id, AlgRS256, alg)
+		}
+		if pub.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("key %q: RSA key must be at least %d bits", id, minRSABits)
+		}
+		return &Key{ID: id, method: jwt.SigningMethodRS256, public: pub}, nil
+	case ed25519.PublicKey:
+		if alg != AlgEdDSA {
+			return nil, fmt.Errorf("key %q: Ed25519 key requires algorithm %s, got %q", id, AlgEdDSA, alg)
+		}
+		return &Key{ID: id, method: jwt.SigningMethodEdDSA, public: pub}, nil
+	default:
+		return nil, fmt.Errorf("key %q: unsupported public key type %T", id, public)
+	}
+}
+
+func (k *Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+func (k *Key) CanSign() bool {
+	return k.signer != nil
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwk returns the public form of the key. HMAC keys are secret and have none.
+func (k *Key) jwk() (JWK, bool) {
+	jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm()}
+
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// KeyRing holds the key new tokens are signed with and any number of keys
+// that are only used to verify tokens signed before a rotation.
+type KeyRing struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeyRing(active *Key, verifyOnly ...*Key) (*KeyRing, error) {
+	if active == nil {
+		return nil, errors.New("no active signing key")
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active key %q has no private key", active.ID)
+	}
+
+	ring := &KeyRing{active: active, keys: map[string]*Key{active.ID: active}}
+	for _, key := range verifyOnly {
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	return ring, nil
+}
+
+func (r *KeyRing) Active() *Key {
+	return r.active
+}
+
+func (r *KeyRing) Get(id string) (*Key, bool) {
+	key, ok := r.keys[id]
+	return key, ok
+}
+
+// JWKS lists the public keys of the ring, active key first, so other services
+// can verify tokens without a shared secret.
+func (r *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	if jwk, ok := r.active.jwk(); ok {
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(r.keys)) {
+		if id == r.active.ID {
+			continue
+		}
+		if jwk, ok := r.keys[id].jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	return set
+}
@@ -0,0 +1,38 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/wb-go/wbf/zlog"
+)
+
+// PasswordResetMessage tells a user how to set a new password.
+type PasswordResetMessage struct {
+	Username  string
+	Link      string
+	ExpiresAt time.Time
+}
+
+// Notifier delivers messages to users outside the API. The sink is chosen in
+// config, so mail or chat delivery can be added without touching callers.
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, msg PasswordResetMessage) error
+}
+
+type logNotifier struct{}
+
+// NewLogNotifier writes messages to the application log. Anyone who can read
+// the log can reset passwords, so it is meant for development only.
+func NewLogNotifier() *logNotifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) SendPasswordReset(_ context.Context, msg PasswordResetMessage) error {
+	zlog.Logger.Warn().
+		Str("username", msg.Username).
+		Str("link", msg.Link).
+		Time("expires_at", msg.ExpiresAt).
+		Msg("Password reset requested")
+	return nil
+}
@@ -0,0 +1,86 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// parseJWK turns a signing key of a JWKS document into a crypto public key.
+func parseJWK(raw json.RawMessage) (string, interface{}, error) {
+	var k jwk
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return "", nil, err
+	}
+
+	if k.Use != "" && k.Use != "sig" {
+		return "", nil, fmt.Errorf("key %q is not a signing key", k.KeyID)
+	}
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return "", nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return "", nil, err
+		}
+		return k.KeyID, &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return "", nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return "", nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return "", nil, err
+		}
+		return k.KeyID, &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return "", nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return "", nil, fmt.Errorf("invalid Ed25519 key %q", k.KeyID)
+		}
+		return k.KeyID, ed25519.PublicKey(x), nil
+	default:
+		return "", nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -7,9 +7,40 @@ import (
 type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    interface{} `json:"meta,omitempty"`
 	Error   string      `json:"error,omitempty"`
 }
 
+type PageMeta struct {
+	Total   int  `json:"total"`
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	HasMore bool `json:"has_more"`
+}
+
+func NewPageMeta(total, limit, offset int) *PageMeta {
+	return &PageMeta{
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: limit > 0 && offset+limit < total,
+	}
+}
+
+type CursorMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+func NewCursorMeta(limit int, nextCursor string) *CursorMeta {
+	return &CursorMeta{
+		Limit:      limit,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
+	}
+}
+
 func Success(c *ginext.Context, statusCode int, data interface{}) {
 	c.JSON(statusCode, Response{
 		Success: true,
@@ -17,9 +48,25 @@ func Success(c *ginext.Context, statusCode int, data interface{}) {
 	})
 }
 
+func SuccessWithMeta(c *ginext.Context, statusCode int, data interface{}, meta interface{}) {
+	c.JSON(statusCode, Response{
+		Success: true,
+		Data:    data,
+		Meta:    meta,
+	})
+}
+
 func Error(c *ginext.Context, statusCode int, err string) {
 	c.JSON(statusCode, Response{
 		Success: false,
 		Error:   err,
 	})
 }
+
+func ErrorWithData(c *ginext.Context, statusCode int, err string, data interface{}) {
+	c.JSON(statusCode, Response{
+		Success: false,
+		Data:    data,
+		Error:   err,
+	})
+}
@@ -0,0 +1,79 @@
+// Package secretbox encrypts short secrets, such as TOTP keys, that have to be
+// stored in a form the application can read back. It uses AES-256-GCM.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the length of the key in bytes.
+const KeySize = 32
+
+// Prefix starts every sealed value. It names the format, so values stored
+// before encryption was introduced can be told apart and found.
+const Prefix = "v1:"
+
+var ErrInvalidSealed = errors.New("invalid sealed value")
+
+type Box struct {
+	aead cipher.AEAD
+}
+
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plain under a random nonce. binding is authenticated but not
+// stored: Open needs the same binding, so a sealed value copied to another
+// row, e.g. another user's, does not open.
+func (b *Box) Seal(plain string, binding []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(plain), binding)
+	return Prefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value made by Seal with the same binding. Anything else,
+// including a value that was never sealed, fails with ErrInvalidSealed.
+func (b *Box) Open(sealed string, binding []byte) (string, error) {
+	encoded, ok := strings.CutPrefix(sealed, Prefix)
+	if !ok {
+		return "", ErrInvalidSealed
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrInvalidSealed
+	}
+
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plain, err := b.aead.Open(nil, nonce, ciphertext, binding)
+	if err != nil {
+		return "", ErrInvalidSealed
+	}
+
+	return string(plain), nil
+}
@@ -0,0 +1,86 @@
+package secretbox
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newBox(t *testing.T, fill byte) *Box {
+	t.Helper()
+
+	box, err := New(bytes.Repeat([]byte{fill}, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return box
+}
+
+func TestSealOpen(t *testing.T) {
+	box := newBox(t, 1)
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	sealed, err := box.Seal(secret, []byte("user:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, Prefix) || strings.Contains(sealed, secret) {
+		t.Fatalf("Seal() = %q", sealed)
+	}
+
+	got, err := box.Open(sealed, []byte("user:1"))
+	if err != nil || got != secret {
+		t.Fatalf("Open() = %q, %v; want %q", got, err, secret)
+	}
+
+	again, err := box.Seal(secret, []byte("user:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Error("sealing twice gave the same value; the nonce is not random")
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	box := newBox(t, 1)
+	sealed, err := box.Seal("GEZDGNBVGY3TQOJQ", []byte("user:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := []byte(sealed)
+	flipped[len(flipped)/2] ^= 'A' ^ 'B'
+
+	tests := []struct {
+		name    string
+		box     *Box
+		sealed  string
+		binding string
+	}{
+		{name: "other binding", box: box, sealed: sealed, binding: "user:2"},
+		{name: "other key", box: newBox(t, 2), sealed: sealed, binding: "user:1"},
+		{name: "tampered", box: box, sealed: string(flipped), binding: "user:1"},
+		{name: "truncated", box: box, sealed: sealed[:len(Prefix)+8], binding: "user:1"},
+		{name: "plain text", box: box, sealed: "GEZDGNBVGY3TQOJQ", binding: "user:1"},
+		{name: "prefix only", box: box, sealed: Prefix, binding: "user:1"},
+		{name: "not base64", box: box, sealed: Prefix + "%%%", binding: "user:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := tt.box.Open(tt.sealed, []byte(tt.binding)); !errors.Is(err, ErrInvalidSealed) {
+				t.Errorf("Open() = %q, %v; want ErrInvalidSealed", got, err)
+			}
+		})
+	}
+}
+
+func TestNewKeySize(t *testing.T) {
+	for _, size := range []int{0, 16, KeySize - 1, KeySize + 1} {
+		if _, err := New(make([]byte, size)); err == nil {
+			t.Errorf("New accepted a %d byte key", size)
+		}
+	}
+}
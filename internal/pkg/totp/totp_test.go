@@ -0,0 +1,137 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit ones are their last 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeSecretFormat(t *testing.T) {
+	want, err := Code(rfcSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{strings.ToLower(rfcSecret), " " + rfcSecret + "\n"} {
+		got, err := Code(secret, 1)
+		if err != nil || got != want {
+			t.Errorf("Code(%q) = %q, %v; want %q", secret, got, err, want)
+		}
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+
+	codeAt := func(offset int64) string {
+		code, err := Code(rfcSecret, step+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: codeAt(0), skew: 1, wantStep: step, wantOK: true},
+		{name: "previous step within skew", code: codeAt(-1), skew: 1, wantStep: step - 1, wantOK: true},
+		{name: "next step within skew", code: codeAt(1), skew: 1, wantStep: step + 1, wantOK: true},
+		{name: "surrounding spaces", code: " " + codeAt(0) + " ", skew: 0, wantStep: step, wantOK: true},
+		{name: "previous step without skew", code: codeAt(-1), skew: 0},
+		{name: "two steps back", code: codeAt(-2), skew: 1},
+		{name: "two steps ahead", code: codeAt(2), skew: 1},
+		{name: "wrong code", code: "000000", skew: 1},
+		{name: "too short", code: codeAt(0)[:5], skew: 1},
+		{name: "too long", code: codeAt(0) + "0", skew: 1},
+		{name: "empty", code: "", skew: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.wantOK {
+				t.Fatalf("Validate(%q) ok = %v, want %v", tt.code, ok, tt.wantOK)
+			}
+			if ok && gotStep != tt.wantStep {
+				t.Errorf("Validate(%q) step = %d, want %d", tt.code, gotStep, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a == b {
+		t.Error("GenerateSecret returned the same secret twice")
+	}
+	if _, err := Code(a, 0); err != nil {
+		t.Errorf("generated secret is not usable: %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Warehouse Control", "jane doe", rfcSecret)
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("URI = %s, want otpauth://totp/...", uri)
+	}
+	if strings.Contains(uri, "+") {
+		t.Errorf("URI = %s, spaces must be encoded as %%20", uri)
+	}
+
+	q := u.Query()
+	if q.Get("secret") != rfcSecret || q.Get("issuer") != "Warehouse Control" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("URI query = %v", q)
+	}
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+// sweepEvery is how many reserved attempts pass between removals of stale
+// counters, so a spray of random usernames or IPs cannot grow the map forever.
+const sweepEvery = 1000
+
+type loginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]entity.LoginAttempts
+	window   time.Duration
+	writes   int
+}
+
+func NewLoginAttemptRepository() *loginAttemptRepository {
+	return &loginAttemptRepository{attempts: make(map[string]entity.LoginAttempts)}
+}
+
+func (r *loginAttemptRepository) Reserve(_ context.Context, key string, now time.Time, policy entity.LoginThrottlePolicy) (*entity.LoginAttempts, time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.attempts[key]
+	if !ok {
+		a = entity.LoginAttempts{Key: key}
+	}
+
+	if wait := policy.RetryAfter(&a, now); wait > 0 {
+		return &a, wait, nil
+	}
+
+	a = a.AddFailure(now, policy.Window)
+	r.attempts[key] = a
+
+	r.window = policy.Window
+	r.writes++
+	if r.writes%sweepEvery == 0 {
+		r.sweep(now)
+	}
+
+	return &a, 0, nil
+}
+
+func (r *loginAttemptRepository) Release(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.attempts[key]
+	if !ok {
+		return nil
+	}
+
+	if a.Failures <= 1 {
+		delete(r.attempts, key)
+		return nil
+	}
+	a.Failures--
+	r.attempts[key] = a
+
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *loginAttemptRepository) sweep(now time.Time) {
+	for key, a := range r.attempts {
+		if now.Sub(a.LastFailure) >= r.window {
+			delete(r.attempts, key)
+		}
+	}
+}
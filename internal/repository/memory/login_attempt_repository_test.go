@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+var testPolicy = entity.LoginThrottlePolicy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	LockoutAfter:    10,
+	LockoutDuration: time.Hour,
+	Window:          time.Hour,
+}
+
+func TestReserveConcurrent(t *testing.T) {
+	r := NewLoginAttemptRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	// Only the free attempts and the one that starts the delay may run, no
+	// matter how many arrive at once.
+	var (
+		mu      sync.Mutex
+		allowed int
+		wg      sync.WaitGroup
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, wait, err := r.Reserve(ctx, "user:jane", now, testPolicy)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if wait == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if want := testPolicy.FreeAttempts + 1; allowed != want {
+		t.Errorf("%d attempts allowed, want %d", allowed, want)
+	}
+}
+
+func TestReserveRelease(t *testing.T) {
+	r := NewLoginAttemptRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	for i := 1; i <= testPolicy.FreeAttempts; i++ {
+		a, wait, err := r.Reserve(ctx, "user:jane", now, testPolicy)
+		if err != nil || wait != 0 || a.Failures != i {
+			t.Fatalf("reserve %d = %+v, %s, %v", i, a, wait, err)
+		}
+	}
+
+	// A successful attempt is taken back and leaves the earlier failures.
+	if err := r.Release(ctx, "user:jane"); err != nil {
+		t.Fatal(err)
+	}
+	a, wait, err := r.Reserve(ctx, "user:jane", now, testPolicy)
+	if err != nil || wait != 0 || a.Failures != testPolicy.FreeAttempts {
+		t.Fatalf("reserve after release = %+v, %s, %v", a, wait, err)
+	}
+
+	// One more may run, then the next has to wait and is not counted.
+	a, wait, err = r.Reserve(ctx, "user:jane", now, testPolicy)
+	if err != nil || wait != 0 || a.Failures != testPolicy.FreeAttempts+1 {
+		t.Fatalf("reserve past free attempts = %+v, %s, %v", a, wait, err)
+	}
+	a, wait, err = r.Reserve(ctx, "user:jane", now, testPolicy)
+	if err != nil || wait != testPolicy.BaseDelay || a.Failures != testPolicy.FreeAttempts+1 {
+		t.Fatalf("reserve while delayed = %+v, %s, %v", a, wait, err)
+	}
+	a, wait, err = r.Reserve(ctx, "user:jane", now.Add(testPolicy.BaseDelay), testPolicy)
+	if err != nil || wait != 0 || a.Failures != testPolicy.FreeAttempts+2 {
+		t.Fatalf("reserve after delay = %+v, %s, %v", a, wait, err)
+	}
+
+	// Other keys are counted separately, and a reset clears the key.
+	if a, _, _ := r.Reserve(ctx, "ip:10.0.0.1", now, testPolicy); a.Failures != 1 {
+		t.Errorf("other key has %d failures, want 1", a.Failures)
+	}
+	if err := r.Reset(ctx, "user:jane"); err != nil {
+		t.Fatal(err)
+	}
+	if a, _, _ := r.Reserve(ctx, "user:jane", now, testPolicy); a.Failures != 1 {
+		t.Errorf("after reset %d failures, want 1", a.Failures)
+	}
+
+	// Releasing a key without failures is a no-op.
+	if err := r.Release(ctx, "user:nobody"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type apiKeyRepository struct {
+	db *dbpg.DB
+}
+
+func NewAPIKeyRepository(db *dbpg.DB) *apiKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// lastUsedPrecision limits how often last_used_at is written, so a busy
+// script does not turn every request into an UPDATE.
+const lastUsedPrecision = time.Minute
+
+const apiKeyColumns = `k.id, k.user_id, k.name, k.prefix, k.permissions, k.expires_at, k.last_used_at, k.created_at, k.revoked_at`
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *entity.APIKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, permissions, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	var expiresAt *time.Time
+	if key.ExpiresAt != nil {
+		utc := key.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		key.UserID, key.Name, key.Prefix, keyHash, pq.Array(permissionStrings(key.Permissions)), expiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+
+	if isUniqueViolation(err) {
+		return entity.ErrAPIKeyExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `, u.username
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1
+	`
+
+	key := &entity.APIKey{}
+	var permissions []string
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, keyHash).Scan(
+		&key.ID, &key.UserID, &key.Name, &key.Prefix, pq.Array(&permissions),
+		&key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt, &key.Username,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	key.Permissions = toPermissions(permissions)
+	return key, nil
+}
+
+func (r *apiKeyRepository) GetByUserID(ctx context.Context, userID int) ([]*entity.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys k WHERE k.user_id = $1 ORDER BY k.id`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*entity.APIKey
+	for rows.Next() {
+		key := &entity.APIKey{}
+		var permissions []string
+		err := rows.Scan(
+			&key.ID, &key.UserID, &key.Name, &key.Prefix, pq.Array(&permissions),
+			&key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		key.Permissions = toPermissions(permissions)
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id, userID int) error {
+	query := `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	if affected == 0 {
+		return entity.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int, at time.Time) error {
+	query := `
+		UPDATE api_keys SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)
+	`
+
+	at = at.UTC()
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, at, at.Add(-lastUsedPrecision)); err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+
+	return nil
+}
+
+func permissionStrings(perms []entity.Permission) []string {
+	out := make([]string, len(perms))
+	for i, p := range perms {
+		out[i] = string(p)
+	}
+	return out
+}
+
+func toPermissions(values []string) []entity.Permission {
+	out := make([]entity.Permission, len(values))
+	for i, v := range values {
+		out[i] = entity.Permission(v)
+	}
+	return out
+}
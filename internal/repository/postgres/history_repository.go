@@ -2,6 +2,10 @@ package postgres
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -10,33 +14,292 @@ import (
 	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
 )
 
+// historyRepository hashes new rows with HMAC-SHA256 under hashKey, which
+// only the application holds, so the chain cannot be rebuilt from the
+// database alone.
 type historyRepository struct {
-	db *dbpg.DB
+	db      *dbpg.DB
+	hashKey []byte
 }
 
-func NewHistoryRepository(db *dbpg.DB) *historyRepository {
-	return &historyRepository{db: db}
+func NewHistoryRepository(db *dbpg.DB, hashKey []byte) *historyRepository {
+	return &historyRepository{db: db, hashKey: hashKey}
 }
 
-func (r *historyRepository) GetByItemID(ctx context.Context, itemID int) ([]*entity.ItemHistory, error) {
+const historyColumns = `id, item_id, location_id, action, username, COALESCE(api_key_name, ''), old_data, new_data,
+	COALESCE(request_id, ''), COALESCE(client_ip, ''), COALESCE(reason, ''),
+	COALESCE(prev_hash, ''), COALESCE(hash, ''), changed_at`
+
+// historyHead is the single row of items_history_head. Locking it serialises
+// writers of the hash chain so every row links to the row committed right
+// before it.
+const historyHead = `SELECT row_count, COALESCE(last_id, 0), COALESCE(last_hash, ''), COALESCE(mac, '') FROM items_history_head`
+
+func (r *historyRepository) Record(ctx context.Context, h *entity.ItemHistory) error {
+	oldData, err := marshalSnapshot(h.OldData)
+	if err != nil {
+		return err
+	}
+
+	newData, err := marshalSnapshot(h.NewData)
+	if err != nil {
+		return err
+	}
+
+	return NewTransactor(r.db).WithinTx(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+
+		head, err := r.getHead(ctx, historyHead+` FOR UPDATE`)
+		if err != nil {
+			return err
+		}
+		if head == nil {
+			return fmt.Errorf("history chain head is missing")
+		}
+		// Signing a head that was changed behind our back would hide the change.
+		if head.MAC != head.Expected {
+			return fmt.Errorf("history chain head signature does not match")
+		}
+
+		query := `
+			INSERT INTO items_history (item_id, location_id, action, username, api_key_name, old_data, new_data, request_id, client_ip, reason, prev_hash)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''))
+			RETURNING id, changed_at
+		`
+
+		err = db.QueryRowContext(
+			ctx, query,
+			h.ItemID, h.LocationID, h.Action, h.Username, h.APIKey, oldData, newData, h.RequestID, h.ClientIP, h.Reason, head.LastHash,
+		).Scan(&h.ID, &h.ChangedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record history: %w", err)
+		}
+
+		// The hashed string is built by Postgres from the stored row, so it
+		// covers the JSONB snapshots in their canonical form.
+		var input string
+		err = db.QueryRowContext(
+			ctx, `SELECT items_history_hash_input(h) FROM items_history h WHERE h.id = $1`, h.ID,
+		).Scan(&input)
+		if err != nil {
+			return fmt.Errorf("failed to hash history: %w", err)
+		}
+
+		h.PrevHash = head.LastHash
+		h.Hash = r.mac(input)
+
+		if _, err := db.ExecContext(ctx, `UPDATE items_history SET hash = $2 WHERE id = $1`, h.ID, h.Hash); err != nil {
+			return fmt.Errorf("failed to hash history: %w", err)
+		}
+
+		return r.saveHead(ctx, head.Count+1, h.ID, h.Hash)
+	})
+}
+
+// SignChainHead signs the head left unsigned by the migration that
+// introduced it. A head is only signed while no keyed row exists: after that
+// a missing signature means tampering, not an upgrade.
+func (r *historyRepository) SignChainHead(ctx context.Context) error {
+	return NewTransactor(r.db).WithinTx(ctx, func(ctx context.Context) error {
+		head, err := r.getHead(ctx, historyHead+` FOR UPDATE`)
+		if err != nil {
+			return err
+		}
+		if head == nil {
+			return fmt.Errorf("history chain head is missing")
+		}
+		if head.MAC != "" {
+			return nil
+		}
+
+		var keyed bool
+		err = conn(ctx, r.db).QueryRowContext(
+			ctx, `SELECT EXISTS (SELECT 1 FROM items_history WHERE hash_version >= $1)`, entity.HistoryHashKeyed,
+		).Scan(&keyed)
+		if err != nil {
+			return fmt.Errorf("failed to check history chain: %w", err)
+		}
+		if keyed {
+			return fmt.Errorf("history chain head is not signed")
+		}
+
+		return r.saveHead(ctx, head.Count, head.LastID, head.LastHash)
+	})
+}
+
+// StreamChain walks the whole history in chain order. The rows and the head
+// are read in one snapshot, so rows written meanwhile do not look like a
+// mismatch with the head.
+func (r *historyRepository) StreamChain(ctx context.Context, fn func(*entity.ChainLink) error) (*entity.ChainHead, error) {
+	tx, err := r.db.Master.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ctx = context.WithValue(ctx, txKey{}, tx)
+
+	head, err := r.getHead(ctx, historyHead)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		SELECT id, item_id, action, username, old_data, new_data, changed_at
-		FROM items_history
-		WHERE item_id = $1
-		ORDER BY changed_at DESC
+		SELECT h.id, h.hash_version, COALESCE(h.prev_hash, ''), COALESCE(h.hash, ''), items_history_hash_input(h)
+		FROM items_history h
+		ORDER BY h.id
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, itemID)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history chain: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var input string
+		link := &entity.ChainLink{}
+		if err := rows.Scan(&link.ID, &link.Version, &link.PrevHash, &link.Hash, &input); err != nil {
+			return nil, fmt.Errorf("failed to scan history chain: %w", err)
+		}
+
+		if link.Version >= entity.HistoryHashKeyed {
+			link.Expected = r.mac(input)
+		} else {
+			sum := sha256.Sum256([]byte(input))
+			link.Expected = hex.EncodeToString(sum[:])
+		}
+
+		if err := fn(link); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return head, nil
+}
+
+// getHead reads the chain head with query, or returns nil if there is none.
+func (r *historyRepository) getHead(ctx context.Context, query string) (*entity.ChainHead, error) {
+	head := &entity.ChainHead{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query).Scan(&head.Count, &head.LastID, &head.LastHash, &head.MAC)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history chain head: %w", err)
+	}
+
+	head.Expected = r.headMAC(head.Count, head.LastID, head.LastHash)
+
+	return head, nil
+}
+
+func (r *historyRepository) saveHead(ctx context.Context, count, lastID int, lastHash string) error {
+	query := `UPDATE items_history_head SET row_count = $1, last_id = NULLIF($2, 0), last_hash = NULLIF($3, ''), mac = $4`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, count, lastID, lastHash, r.headMAC(count, lastID, lastHash))
+	if err != nil {
+		return fmt.Errorf("failed to update history chain head: %w", err)
+	}
+
+	return nil
+}
+
+func (r *historyRepository) headMAC(count, lastID int, lastHash string) string {
+	return r.mac(fmt.Sprintf("items_history_head|row_count=%d|last_id=%d|last_hash=%s", count, lastID, lastHash))
+}
+
+// mac is the hex HMAC-SHA256 of input under the history hash key.
+func (r *historyRepository) mac(input string) string {
+	m := hmac.New(sha256.New, r.hashKey)
+	m.Write([]byte(input))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// marshalSnapshot encodes an item for a JSONB column. It returns a string
+// because lib/pq would send a []byte as bytea.
+func marshalSnapshot(item *entity.Item) (interface{}, error) {
+	if item == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal item snapshot: %w", err)
+	}
+
+	return string(data), nil
+}
+
+func (r *historyRepository) GetByID(ctx context.Context, id int) (*entity.ItemHistory, error) {
+	query := `SELECT ` + historyColumns + ` FROM items_history WHERE id = $1`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get history: %w", err)
 	}
 	defer rows.Close()
 
-	return r.scanHistory(rows)
+	history, err := r.scanHistory(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history) == 0 {
+		return nil, entity.ErrHistoryNotFound
+	}
+
+	return history[0], nil
 }
 
 func (r *historyRepository) GetAll(ctx context.Context, filter *entity.HistoryFilter) ([]*entity.ItemHistory, error) {
-	query := `SELECT id, item_id, action, username, old_data, new_data, changed_at FROM items_history WHERE 1=1`
+	query, args := buildHistoryQuery(filter)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanHistory(rows)
+}
+
+// Stream walks every row matching filter and hands it to fn one at a time,
+// so callers can export the whole table without holding it in memory.
+func (r *historyRepository) Stream(ctx context.Context, filter *entity.HistoryFilter, fn func(*entity.ItemHistory) error) error {
+	query, args := buildHistoryQuery(filter)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		h, err := scanHistoryRow(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(h); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return nil
+}
+
+func buildHistoryQuery(filter *entity.HistoryFilter) (string, []interface{}) {
+	query := `SELECT ` + historyColumns + ` FROM items_history WHERE 1=1`
 	args := []interface{}{}
 	argPos := 1
 
@@ -46,6 +309,12 @@ func (r *historyRepository) GetAll(ctx context.Context, filter *entity.HistoryFi
 		argPos++
 	}
 
+	if filter.LocationID != nil {
+		query += fmt.Sprintf(" AND location_id = $%d", argPos)
+		args = append(args, *filter.LocationID)
+		argPos++
+	}
+
 	if filter.Username != nil {
 		query += fmt.Sprintf(" AND username = $%d", argPos)
 		args = append(args, *filter.Username)
@@ -70,7 +339,22 @@ func (r *historyRepository) GetAll(ctx context.Context, filter *entity.HistoryFi
 		argPos++
 	}
 
-	query += " ORDER BY changed_at DESC"
+	if filter.Field != nil {
+		query += fmt.Sprintf(
+			" AND old_data IS NOT NULL AND new_data IS NOT NULL AND old_data -> $%d IS DISTINCT FROM new_data -> $%d",
+			argPos, argPos,
+		)
+		args = append(args, *filter.Field)
+		argPos++
+	}
+
+	if filter.Cursor != nil {
+		query += fmt.Sprintf(" AND (changed_at, id) < ($%d, $%d)", argPos, argPos+1)
+		args = append(args, filter.Cursor.ChangedAt, filter.Cursor.ID)
+		argPos += 2
+	}
+
+	query += " ORDER BY changed_at DESC, id DESC"
 
 	if filter.Limit > 0 {
 		query += fmt.Sprintf(" LIMIT $%d", argPos)
@@ -78,51 +362,29 @@ func (r *historyRepository) GetAll(ctx context.Context, filter *entity.HistoryFi
 		argPos++
 	}
 
-	if filter.Offset > 0 {
+	if filter.Cursor == nil && filter.Offset > 0 {
 		query += fmt.Sprintf(" OFFSET $%d", argPos)
 		args = append(args, filter.Offset)
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get history: %w", err)
-	}
-	defer rows.Close()
+	return query, args
+}
 
-	return r.scanHistory(rows)
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
 func (r *historyRepository) scanHistory(rows interface {
+	rowScanner
 	Next() bool
-	Scan(dest ...interface{}) error
 	Err() error
 }) ([]*entity.ItemHistory, error) {
 	var history []*entity.ItemHistory
 
 	for rows.Next() {
-		h := &entity.ItemHistory{}
-		var oldDataJSON, newDataJSON []byte
-
-		err := rows.Scan(
-			&h.ID, &h.ItemID, &h.Action, &h.Username,
-			&oldDataJSON, &newDataJSON, &h.ChangedAt,
-		)
+		h, err := scanHistoryRow(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan history: %w", err)
-		}
-
-		if len(oldDataJSON) > 0 && !isNull(oldDataJSON) {
-			h.OldData = &entity.Item{}
-			if err := json.Unmarshal(oldDataJSON, h.OldData); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal old_data: %w", err)
-			}
-		}
-
-		if len(newDataJSON) > 0 && !isNull(newDataJSON) {
-			h.NewData = &entity.Item{}
-			if err := json.Unmarshal(newDataJSON, h.NewData); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal new_data: %w", err)
-			}
+			return nil, err
 		}
 
 		history = append(history, h)
@@ -135,6 +397,35 @@ func (r *historyRepository) scanHistory(rows interface {
 	return history, nil
 }
 
+func scanHistoryRow(row rowScanner) (*entity.ItemHistory, error) {
+	h := &entity.ItemHistory{}
+	var oldDataJSON, newDataJSON []byte
+
+	err := row.Scan(
+		&h.ID, &h.ItemID, &h.LocationID, &h.Action, &h.Username, &h.APIKey, &oldDataJSON, &newDataJSON,
+		&h.RequestID, &h.ClientIP, &h.Reason, &h.PrevHash, &h.Hash, &h.ChangedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan history: %w", err)
+	}
+
+	if len(oldDataJSON) > 0 && !isNull(oldDataJSON) {
+		h.OldData = &entity.Item{}
+		if err := json.Unmarshal(oldDataJSON, h.OldData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal old_data: %w", err)
+		}
+	}
+
+	if len(newDataJSON) > 0 && !isNull(newDataJSON) {
+		h.NewData = &entity.Item{}
+		if err := json.Unmarshal(newDataJSON, h.NewData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal new_data: %w", err)
+		}
+	}
+
+	return h, nil
+}
+
 func isNull(data []byte) bool {
 	return len(data) == 0 || strings.TrimSpace(string(data)) == "null"
 }
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type invitationRepository struct {
+	db *dbpg.DB
+}
+
+func NewInvitationRepository(db *dbpg.DB) *invitationRepository {
+	return &invitationRepository{db: db}
+}
+
+func (r *invitationRepository) Create(ctx context.Context, invitation *entity.Invitation, codeHash string) error {
+	query := `
+		INSERT INTO invitations (code_hash, role, created_by, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		codeHash, invitation.Role, invitation.CreatedBy, invitation.ExpiresAt.UTC(),
+	).Scan(&invitation.ID, &invitation.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *invitationRepository) GetByCodeHashForUpdate(ctx context.Context, codeHash string) (*entity.Invitation, error) {
+	query := `
+		SELECT id, role, created_by, expires_at, used_at, used_by, created_at
+		FROM invitations
+		WHERE code_hash = $1
+		FOR UPDATE
+	`
+
+	invitation := &entity.Invitation{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, codeHash).Scan(
+		&invitation.ID, &invitation.Role, &invitation.CreatedBy, &invitation.ExpiresAt,
+		&invitation.UsedAt, &invitation.UsedBy, &invitation.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrInvalidInvitation
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	return invitation, nil
+}
+
+func (r *invitationRepository) MarkUsed(ctx context.Context, id int, username string) error {
+	query := `UPDATE invitations SET used_at = NOW(), used_by = $1 WHERE id = $2 AND used_at IS NULL`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, username, id)
+	if err != nil {
+		return fmt.Errorf("failed to use invitation: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return entity.ErrInvalidInvitation
+	}
+
+	return nil
+}
@@ -3,8 +3,11 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/lib/pq"
 	"github.com/wb-go/wbf/dbpg"
 	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
 )
@@ -17,38 +20,115 @@ func NewItemRepository(db *dbpg.DB) *itemRepository {
 	return &itemRepository{db: db}
 }
 
+// itemQuantity is the derived item total. It is an expression rather than a
+// column so filters and sorting see the same number the API returns.
+const itemQuantity = `(SELECT COALESCE(SUM(s.quantity), 0) FROM stock_levels s WHERE s.item_id = items.id)`
+
+const itemStock = `COALESCE((
+	SELECT json_agg(json_build_object(
+		'location_id', l.id, 'location_code', l.code, 'zone', l.zone,
+		'warehouse_id', w.id, 'warehouse_code', w.code, 'quantity', s.quantity
+	) ORDER BY w.code, l.code)
+	FROM stock_levels s
+	JOIN locations l ON l.id = s.location_id
+	JOIN warehouses w ON w.id = l.warehouse_id
+	WHERE s.item_id = items.id
+), '[]')`
+
+// itemInTransit sums the lines of dispatched transfer orders: stock that has
+// left one warehouse and not yet arrived at another.
+const itemInTransit = `(
+	SELECT COALESCE(SUM(tl.quantity), 0)
+	FROM transfer_lines tl
+	JOIN transfer_orders t ON t.id = tl.transfer_id
+	WHERE tl.item_id = items.id AND t.status = 'dispatched'
+)`
+
+// itemReserved sums the reservations still holding stock. Expired ones stop
+// counting at once, before the expiry job marks them.
+const itemReserved = `(
+	SELECT COALESCE(SUM(r.quantity), 0)
+	FROM reservations r
+	WHERE r.item_id = items.id AND r.status = 'active' AND r.expires_at > NOW()
+)`
+
+const itemColumns = `id, sku,
+	COALESCE((SELECT array_agg(b.barcode ORDER BY b.barcode) FROM item_barcodes b WHERE b.item_id = items.id), '{}'),
+	name, description, ` + itemQuantity + `, ` + itemStock + `, ` + itemInTransit + `, ` + itemReserved + `,
+	price, version, created_at, updated_at, deleted_at, deleted_by`
+
+const (
+	itemSKUIndex   = "idx_items_sku"
+	itemBarcodeKey = "item_barcodes_pkey"
+)
+
 func (r *itemRepository) Create(ctx context.Context, item *entity.Item, username string) error {
 	query := `
-		INSERT INTO items (name, description, quantity, price, created_by)
+		INSERT INTO items (sku, name, description, price, created_by)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at
+		RETURNING id, version, created_at, updated_at
 	`
 
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx, query,
-		item.Name, item.Description, item.Quantity, item.Price, username,
-	).Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+		item.SKU, item.Name, item.Description, item.Price, username,
+	).Scan(&item.ID, &item.Version, &item.CreatedAt, &item.UpdatedAt)
 
+	if isUniqueViolationOf(err, itemSKUIndex) {
+		return entity.ErrSKUExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create item: %w", err)
 	}
 
+	return r.setBarcodes(ctx, item.ID, item.Barcodes)
+}
+
+// setBarcodes replaces the item's barcodes. Barcodes are unique across all
+// items, deleted ones included.
+func (r *itemRepository) setBarcodes(ctx context.Context, itemID int, barcodes []string) error {
+	db := conn(ctx, r.db)
+
+	if _, err := db.ExecContext(ctx, `DELETE FROM item_barcodes WHERE item_id = $1`, itemID); err != nil {
+		return fmt.Errorf("failed to clear item barcodes: %w", err)
+	}
+
+	if len(barcodes) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO item_barcodes (item_id, barcode) SELECT $1, unnest($2::varchar[])`
+	_, err := db.ExecContext(ctx, query, itemID, pq.Array(barcodes))
+	if isUniqueViolationOf(err, itemBarcodeKey) {
+		return entity.ErrBarcodeExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save item barcodes: %w", err)
+	}
+
 	return nil
 }
 
 func (r *itemRepository) GetByID(ctx context.Context, id int) (*entity.Item, error) {
-	query := `
-		SELECT id, name, description, quantity, price, created_at, updated_at
-		FROM items
-		WHERE id = $1 AND deleted_at IS NULL
-	`
+	query := `SELECT ` + itemColumns + ` FROM items WHERE id = $1 AND deleted_at IS NULL`
 
-	item := &entity.Item{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&item.ID, &item.Name, &item.Description,
-		&item.Quantity, &item.Price, &item.CreatedAt, &item.UpdatedAt,
-	)
+	item, err := scanItem(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	return item, nil
+}
 
+// GetByIDForUpdate reads an active item and locks its row until the
+// surrounding transaction ends.
+func (r *itemRepository) GetByIDForUpdate(ctx context.Context, id int) (*entity.Item, error) {
+	query := `SELECT ` + itemColumns + ` FROM items WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+
+	item, err := scanItem(conn(ctx, r.db).QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, entity.ErrItemNotFound
 	}
@@ -59,74 +139,249 @@ func (r *itemRepository) GetByID(ctx context.Context, id int) (*entity.Item, err
 	return item, nil
 }
 
-func (r *itemRepository) GetAll(ctx context.Context) ([]*entity.Item, error) {
+// GetByCode finds an active item by SKU or by one of its barcodes. An SKU
+// match wins if the code happens to be both.
+func (r *itemRepository) GetByCode(ctx context.Context, code string) (*entity.Item, error) {
 	query := `
-		SELECT id, name, description, quantity, price, created_at, updated_at
+		SELECT ` + itemColumns + `
 		FROM items
 		WHERE deleted_at IS NULL
-		ORDER BY created_at DESC
+		  AND (sku = $1 OR id IN (SELECT item_id FROM item_barcodes WHERE barcode = $2))
+		ORDER BY sku = $1 DESC
+		LIMIT 1
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	item, err := scanItem(conn(ctx, r.db).QueryRowContext(ctx, query, entity.NormalizeSKU(code), code))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	return item, nil
+}
+
+func scanItem(row rowScanner) (*entity.Item, error) {
+	item := &entity.Item{}
+	var stockJSON []byte
+
+	err := row.Scan(
+		&item.ID, &item.SKU, pq.Array(&item.Barcodes), &item.Name, &item.Description,
+		&item.Quantity, &stockJSON, &item.InTransit, &item.Reserved, &item.Price, &item.Version, &item.CreatedAt, &item.UpdatedAt,
+		&item.DeletedAt, &item.DeletedBy,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get items: %w", err)
+		return nil, err
+	}
+
+	if err := json.Unmarshal(stockJSON, &item.Stock); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal item stock: %w", err)
+	}
+
+	item.OnHand = item.Quantity
+	item.Available = item.OnHand - item.Reserved
+
+	return item, nil
+}
+
+func (r *itemRepository) GetAll(ctx context.Context, filter *entity.ItemFilter) ([]*entity.Item, int, error) {
+	where, args := buildItemFilter(filter)
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM items` + where
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count items: %w", err)
+	}
+
+	query := `SELECT ` + itemColumns + ` FROM items` + where
+	query += buildItemOrder(filter)
+
+	argPos := len(args) + 1
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argPos)
+		args = append(args, filter.Limit)
+		argPos++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argPos)
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get items: %w", err)
 	}
 	defer rows.Close()
 
 	var items []*entity.Item
 	for rows.Next() {
-		item := &entity.Item{}
-		err := rows.Scan(
-			&item.ID, &item.Name, &item.Description,
-			&item.Quantity, &item.Price, &item.CreatedAt, &item.UpdatedAt,
-		)
+		item, err := scanItem(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan item: %w", err)
+			return nil, 0, fmt.Errorf("failed to scan item: %w", err)
 		}
 		items = append(items, item)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
 	}
 
-	return items, nil
+	return items, total, nil
 }
 
+func buildItemFilter(filter *entity.ItemFilter) (string, []interface{}) {
+	where := " WHERE deleted_at IS NULL"
+	if filter.Deleted {
+		where = " WHERE deleted_at IS NOT NULL"
+	}
+	args := []interface{}{}
+	argPos := 1
+
+	if filter.Name != nil {
+		where += fmt.Sprintf(" AND name ILIKE $%d", argPos)
+		args = append(args, "%"+escapeLike(*filter.Name)+"%")
+		argPos++
+	}
+
+	if filter.QuantityMin != nil {
+		where += fmt.Sprintf(" AND "+itemQuantity+" >= $%d", argPos)
+		args = append(args, *filter.QuantityMin)
+		argPos++
+	}
+
+	if filter.QuantityMax != nil {
+		where += fmt.Sprintf(" AND "+itemQuantity+" <= $%d", argPos)
+		args = append(args, *filter.QuantityMax)
+		argPos++
+	}
+
+	if filter.WarehouseID != nil {
+		where += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM stock_levels s JOIN locations l ON l.id = s.location_id
+			WHERE s.item_id = items.id AND l.warehouse_id = $%d
+		)`, argPos)
+		args = append(args, *filter.WarehouseID)
+		argPos++
+	}
+
+	if filter.PriceMin != nil {
+		where += fmt.Sprintf(" AND price >= $%d", argPos)
+		args = append(args, *filter.PriceMin)
+		argPos++
+	}
+
+	if filter.PriceMax != nil {
+		where += fmt.Sprintf(" AND price <= $%d", argPos)
+		args = append(args, *filter.PriceMax)
+		argPos++
+	}
+
+	if filter.CreatedFrom != nil {
+		where += fmt.Sprintf(" AND created_at >= $%d", argPos)
+		args = append(args, *filter.CreatedFrom)
+		argPos++
+	}
+
+	if filter.CreatedTo != nil {
+		where += fmt.Sprintf(" AND created_at <= $%d", argPos)
+		args = append(args, *filter.CreatedTo)
+		argPos++
+	}
+
+	if filter.UpdatedFrom != nil {
+		where += fmt.Sprintf(" AND updated_at >= $%d", argPos)
+		args = append(args, *filter.UpdatedFrom)
+		argPos++
+	}
+
+	if filter.UpdatedTo != nil {
+		where += fmt.Sprintf(" AND updated_at <= $%d", argPos)
+		args = append(args, *filter.UpdatedTo)
+	}
+
+	return where, args
+}
+
+// Sort columns come from a whitelist, never from user input directly.
+var itemSortColumns = map[entity.ItemSortField]string{
+	entity.ItemSortByID:        "id",
+	entity.ItemSortByName:      "name",
+	entity.ItemSortByQuantity:  itemQuantity,
+	entity.ItemSortByPrice:     "price",
+	entity.ItemSortByCreatedAt: "created_at",
+	entity.ItemSortByUpdatedAt: "updated_at",
+	entity.ItemSortByDeletedAt: "deleted_at",
+}
+
+func buildItemOrder(filter *entity.ItemFilter) string {
+	column, ok := itemSortColumns[filter.SortBy]
+	if !ok {
+		column = "created_at"
+		if filter.Deleted {
+			column = "deleted_at"
+		}
+	}
+
+	direction := "DESC"
+	if filter.SortOrder == entity.SortAsc {
+		direction = "ASC"
+	}
+
+	if column == "id" {
+		return fmt.Sprintf(" ORDER BY id %s", direction)
+	}
+
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Update writes the item if its stored version still equals item.Version.
+// A zero item.Version skips the check.
 func (r *itemRepository) Update(ctx context.Context, item *entity.Item, username string) error {
 	query := `
 		UPDATE items
-		SET name = $1, description = $2, quantity = $3, price = $4, 
-		    updated_at = NOW(), updated_by = $5
-		WHERE id = $6 AND deleted_at IS NULL
-		RETURNING updated_at
+		SET sku = $1, name = $2, description = $3, price = $4,
+		    updated_at = NOW(), updated_by = $5, version = version + 1
+		WHERE id = $6 AND deleted_at IS NULL AND ($7 = 0 OR version = $7)
+		RETURNING version, updated_at
 	`
 
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx, query,
-		item.Name, item.Description, item.Quantity, item.Price, username, item.ID,
-	).Scan(&item.UpdatedAt)
+		item.SKU, item.Name, item.Description, item.Price, username, item.ID, item.Version,
+	).Scan(&item.Version, &item.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return entity.ErrItemNotFound
+		return r.missOrConflict(ctx, item.ID)
+	}
+	if isUniqueViolationOf(err, itemSKUIndex) {
+		return entity.ErrSKUExists
 	}
 	if err != nil {
 		return fmt.Errorf("failed to update item: %w", err)
 	}
 
-	return nil
+	return r.setBarcodes(ctx, item.ID, item.Barcodes)
 }
 
-func (r *itemRepository) Delete(ctx context.Context, id int, username string) error {
+// Touch bumps the version of an active item whose stock changed, so its ETag
+// changes along with the quantity it reports.
+func (r *itemRepository) Touch(ctx context.Context, id int, username string) error {
 	query := `
 		UPDATE items
-		SET deleted_at = NOW(), deleted_by = $1
+		SET updated_at = NOW(), updated_by = $1, version = version + 1
 		WHERE id = $2 AND deleted_at IS NULL
 	`
 
-	result, err := r.db.ExecContext(ctx, query, username, id)
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, username, id)
 	if err != nil {
-		return fmt.Errorf("failed to delete item: %w", err)
+		return fmt.Errorf("failed to update item: %w", err)
 	}
 
 	rows, err := result.RowsAffected()
@@ -140,3 +395,71 @@ func (r *itemRepository) Delete(ctx context.Context, id int, username string) er
 
 	return nil
 }
+
+func (r *itemRepository) Delete(ctx context.Context, id int, version int, username string) error {
+	query := `
+		UPDATE items
+		SET deleted_at = NOW(), deleted_by = $1, version = version + 1
+		WHERE id = $2 AND deleted_at IS NULL AND ($3 = 0 OR version = $3)
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, username, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rows == 0 {
+		return r.missOrConflict(ctx, id)
+	}
+
+	return nil
+}
+
+func (r *itemRepository) Restore(ctx context.Context, id int, username string) (*entity.Item, error) {
+	query := `
+		UPDATE items
+		SET deleted_at = NULL, deleted_by = NULL,
+		    updated_at = NOW(), updated_by = $1, version = version + 1
+		WHERE id = $2 AND deleted_at IS NOT NULL
+		RETURNING ` + itemColumns + `
+	`
+
+	item, err := scanItem(conn(ctx, r.db).QueryRowContext(ctx, query, username, id))
+
+	if err == sql.ErrNoRows {
+		switch _, err := r.GetByID(ctx, id); err {
+		case nil:
+			return nil, entity.ErrItemNotDeleted
+		case entity.ErrItemNotFound:
+			return nil, entity.ErrItemNotFound
+		default:
+			return nil, fmt.Errorf("failed to check item: %w", err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore item: %w", err)
+	}
+
+	return item, nil
+}
+
+// missOrConflict tells apart a conditional write that matched nothing because
+// the item is gone from one that lost a race with a concurrent write.
+func (r *itemRepository) missOrConflict(ctx context.Context, id int) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM items WHERE id = $1 AND deleted_at IS NULL)`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check item: %w", err)
+	}
+
+	if !exists {
+		return entity.ErrItemNotFound
+	}
+
+	return entity.ErrVersionConflict
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type loginAttemptRepository struct {
+	db *dbpg.DB
+}
+
+func NewLoginAttemptRepository(db *dbpg.DB) *loginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+// Reserve compares and swaps the counter: it is only written if nobody
+// changed it since it was read, otherwise the decision is made again.
+func (r *loginAttemptRepository) Reserve(ctx context.Context, key string, now time.Time, policy entity.LoginThrottlePolicy) (*entity.LoginAttempts, time.Duration, error) {
+	// last_failure has no time zone; an offset would be dropped, not applied.
+	now = now.UTC()
+
+	for {
+		current, exists, err := r.get(ctx, key)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if wait := policy.RetryAfter(current, now); wait > 0 {
+			return current, wait, nil
+		}
+
+		next := current.AddFailure(now, policy.Window)
+
+		var res sql.Result
+		if exists {
+			res, err = conn(ctx, r.db).ExecContext(ctx, `
+				UPDATE login_attempts SET failures = $2, last_failure = $3
+				WHERE key = $1 AND failures = $4 AND last_failure = $5
+			`, key, next.Failures, next.LastFailure, current.Failures, current.LastFailure)
+		} else {
+			res, err = conn(ctx, r.db).ExecContext(ctx, `
+				INSERT INTO login_attempts (key, failures, last_failure) VALUES ($1, $2, $3)
+				ON CONFLICT (key) DO NOTHING
+			`, key, next.Failures, next.LastFailure)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to reserve login attempt: %w", err)
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to reserve login attempt: %w", err)
+		}
+		if rows == 1 {
+			return &next, 0, nil
+		}
+	}
+}
+
+func (r *loginAttemptRepository) get(ctx context.Context, key string) (*entity.LoginAttempts, bool, error) {
+	query := `SELECT failures, last_failure FROM login_attempts WHERE key = $1`
+
+	a := &entity.LoginAttempts{Key: key}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, key).Scan(&a.Failures, &a.LastFailure)
+	if err == sql.ErrNoRows {
+		return a, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	return a, true, nil
+}
+
+func (r *loginAttemptRepository) Release(ctx context.Context, key string) error {
+	query := `UPDATE login_attempts SET failures = GREATEST(failures - 1, 0) WHERE key = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to release login attempt: %w", err)
+	}
+
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM login_attempts WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+
+	return nil
+}
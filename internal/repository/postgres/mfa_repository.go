@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/secretbox"
+)
+
+// mfaRepository keeps TOTP secrets encrypted with box. Each secret is bound to
+// its user, so it cannot be copied to another account's row.
+type mfaRepository struct {
+	db  *dbpg.DB
+	box *secretbox.Box
+}
+
+func NewMFARepository(db *dbpg.DB, box *secretbox.Box) *mfaRepository {
+	return &mfaRepository{db: db, box: box}
+}
+
+func secretBinding(userID int) []byte {
+	return []byte("user_mfa:" + strconv.Itoa(userID))
+}
+
+func (r *mfaRepository) GetByUserID(ctx context.Context, userID int) (*entity.MFA, error) {
+	return r.get(ctx, `SELECT user_id, secret, enabled_at, last_step, created_at FROM user_mfa WHERE user_id = $1`, userID)
+}
+
+// GetByUserIDForUpdate locks the enrollment so a code cannot be accepted twice
+// by concurrent requests.
+func (r *mfaRepository) GetByUserIDForUpdate(ctx context.Context, userID int) (*entity.MFA, error) {
+	return r.get(ctx, `SELECT user_id, secret, enabled_at, last_step, created_at FROM user_mfa WHERE user_id = $1 FOR UPDATE`, userID)
+}
+
+func (r *mfaRepository) get(ctx context.Context, query string, userID int) (*entity.MFA, error) {
+	mfa := &entity.MFA{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(
+		&mfa.UserID, &mfa.Secret, &mfa.EnabledAt, &mfa.LastStep, &mfa.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mfa: %w", err)
+	}
+
+	mfa.Secret, err = r.box.Open(mfa.Secret, secretBinding(mfa.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt mfa secret: %w", err)
+	}
+
+	return mfa, nil
+}
+
+func (r *mfaRepository) SavePending(ctx context.Context, userID int, secret string) error {
+	sealed, err := r.box.Seal(secret, secretBinding(userID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt mfa secret: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_mfa (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_step = 0, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, sealed)
+	if err != nil {
+		return fmt.Errorf("failed to save mfa secret: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save mfa secret: %w", err)
+	}
+
+	if affected == 0 {
+		return entity.ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// SealPlaintextSecrets encrypts the secrets stored before encryption was
+// introduced. It runs at startup: until then such a secret cannot be read, and
+// a plain secret written straight into the table is never accepted.
+func (r *mfaRepository) SealPlaintextSecrets(ctx context.Context) (int, error) {
+	plain, err := r.plaintextSecrets(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for userID, secret := range plain {
+		sealed, err := r.box.Seal(secret, secretBinding(userID))
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt mfa secret: %w", err)
+		}
+
+		// A secret replaced since it was read is already sealed and stays as is.
+		query := `UPDATE user_mfa SET secret = $2 WHERE user_id = $1 AND secret = $3`
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, sealed, secret); err != nil {
+			return 0, fmt.Errorf("failed to encrypt mfa secret: %w", err)
+		}
+	}
+
+	return len(plain), nil
+}
+
+func (r *mfaRepository) plaintextSecrets(ctx context.Context) (map[int]string, error) {
+	query := `SELECT user_id, secret FROM user_mfa WHERE secret NOT LIKE $1`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, secretbox.Prefix+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mfa secrets: %w", err)
+	}
+	defer rows.Close()
+
+	plain := make(map[int]string)
+	for rows.Next() {
+		var (
+			userID int
+			secret string
+		)
+		if err := rows.Scan(&userID, &secret); err != nil {
+			return nil, fmt.Errorf("failed to scan mfa secret: %w", err)
+		}
+		plain[userID] = secret
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return plain, nil
+}
+
+func (r *mfaRepository) Enable(ctx context.Context, userID int, step int64) error {
+	query := `UPDATE user_mfa SET enabled_at = NOW(), last_step = $2 WHERE user_id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, step); err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) SetLastStep(ctx context.Context, userID int, step int64) error {
+	query := `UPDATE user_mfa SET last_step = $2 WHERE user_id = $1`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, step); err != nil {
+		return fmt.Errorf("failed to update mfa step: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) Delete(ctx context.Context, userID int) error {
+	return NewTransactor(r.db).WithinTx(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+
+		if _, err := db.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		if _, err := db.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete mfa: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	return NewTransactor(r.db).WithinTx(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+
+		if _, err := db.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		for _, hash := range codeHashes {
+			query := `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+			if _, err := db.ExecContext(ctx, query, userID, hash); err != nil {
+				return fmt.Errorf("failed to save recovery code: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// UseRecoveryCode burns an unused recovery code. It fails with
+// ErrInvalidMFACode if there is no such code.
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	query := `
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	if affected == 0 {
+		return entity.ErrInvalidMFACode
+	}
+
+	return nil
+}
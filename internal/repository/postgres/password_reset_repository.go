@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type passwordResetRepository struct {
+	db *dbpg.DB
+}
+
+func NewPasswordResetRepository(db *dbpg.DB) *passwordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, reset *entity.PasswordReset, tokenHash string) error {
+	query := `
+		INSERT INTO password_resets (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		reset.UserID, tokenHash, reset.ExpiresAt.UTC(),
+	).Scan(&reset.ID, &reset.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	return nil
+}
+
+func (r *passwordResetRepository) GetByTokenHashForUpdate(ctx context.Context, tokenHash string) (*entity.PasswordReset, error) {
+	query := `
+		SELECT id, user_id, expires_at, created_at
+		FROM password_resets
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+
+	reset := &entity.PasswordReset{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash).Scan(
+		&reset.ID, &reset.UserID, &reset.ExpiresAt, &reset.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrInvalidResetToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password reset: %w", err)
+	}
+
+	return reset, nil
+}
+
+// DeleteByUserID drops every outstanding reset token of a user. It is how a
+// token is used up, and no token survives a password change.
+func (r *passwordResetRepository) DeleteByUserID(ctx context.Context, userID int) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM password_resets WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete password resets: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type reservationRepository struct {
+	db *dbpg.DB
+}
+
+func NewReservationRepository(db *dbpg.DB) *reservationRepository {
+	return &reservationRepository{db: db}
+}
+
+// reservationStatus reports a reservation past its expiry as expired even if
+// the expiry job has not got to it yet.
+const reservationStatus = `CASE WHEN status = 'active' AND expires_at <= NOW() THEN 'expired' ELSE status END`
+
+const reservationColumns = `id, item_id, owner, reference, quantity, ` + reservationStatus + `, expires_at,
+	created_by, COALESCE(api_key_name, ''), COALESCE(request_id, ''), created_at,
+	released_at, released_by, COALESCE(release_reason, '')`
+
+func (r *reservationRepository) Create(ctx context.Context, res *entity.Reservation) error {
+	query := `
+		INSERT INTO reservations (item_id, owner, reference, quantity, status, expires_at, created_by, api_key_name, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''))
+		RETURNING id, created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		res.ItemID, res.Owner, res.Reference, res.Quantity, res.Status, res.ExpiresAt,
+		res.CreatedBy, res.APIKey, res.RequestID,
+	).Scan(&res.ID, &res.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create reservation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *reservationRepository) GetByID(ctx context.Context, id int) (*entity.Reservation, error) {
+	return r.get(ctx, `SELECT `+reservationColumns+` FROM reservations WHERE id = $1`, id)
+}
+
+func (r *reservationRepository) GetByIDForUpdate(ctx context.Context, id int) (*entity.Reservation, error) {
+	return r.get(ctx, `SELECT `+reservationColumns+` FROM reservations WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *reservationRepository) get(ctx context.Context, query string, id int) (*entity.Reservation, error) {
+	res, err := scanReservation(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrReservationMissing
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	return res, nil
+}
+
+func (r *reservationRepository) GetAll(ctx context.Context, filter *entity.ReservationFilter) ([]*entity.Reservation, int, error) {
+	db := conn(ctx, r.db)
+
+	var status *string
+	if filter.Status != nil {
+		s := string(*filter.Status)
+		status = &s
+	}
+
+	where := `
+		WHERE ($1::int IS NULL OR item_id = $1)
+		  AND ($2::varchar IS NULL OR owner = $2)
+		  AND ($3::varchar IS NULL OR ` + reservationStatus + ` = $3)
+	`
+	args := []interface{}{filter.ItemID, filter.Owner, status}
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM reservations`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count reservations: %w", err)
+	}
+
+	query := `SELECT ` + reservationColumns + ` FROM reservations` + where + ` ORDER BY id DESC LIMIT $4 OFFSET $5`
+
+	rows, err := db.QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get reservations: %w", err)
+	}
+	defer rows.Close()
+
+	reservations := []*entity.Reservation{}
+	for rows.Next() {
+		res, err := scanReservation(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan reservation: %w", err)
+		}
+		reservations = append(reservations, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return reservations, total, nil
+}
+
+func (r *reservationRepository) Release(ctx context.Context, res *entity.Reservation, username, reason string) error {
+	query := `
+		UPDATE reservations
+		SET status = 'released', released_at = NOW(), released_by = $2, release_reason = NULLIF($3, '')
+		WHERE id = $1 AND status = 'active' AND expires_at > NOW()
+		RETURNING released_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, res.ID, username, reason).Scan(&res.ReleasedAt)
+	if err == sql.ErrNoRows {
+		return entity.ErrReservationClosed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release reservation: %w", err)
+	}
+
+	res.Status = entity.ReservationReleased
+	res.ReleasedBy = &username
+	res.ReleaseReason = reason
+
+	return nil
+}
+
+func (r *reservationRepository) ReleaseExpired(ctx context.Context) (int, error) {
+	query := `
+		UPDATE reservations
+		SET status = 'expired', released_at = expires_at, released_by = 'system'
+		WHERE status = 'active' AND expires_at <= NOW()
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release expired reservations: %w", err)
+	}
+
+	released, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to release expired reservations: %w", err)
+	}
+
+	return int(released), nil
+}
+
+func scanReservation(row rowScanner) (*entity.Reservation, error) {
+	res := &entity.Reservation{}
+
+	err := row.Scan(
+		&res.ID, &res.ItemID, &res.Owner, &res.Reference, &res.Quantity, &res.Status, &res.ExpiresAt,
+		&res.CreatedBy, &res.APIKey, &res.RequestID, &res.CreatedAt,
+		&res.ReleasedAt, &res.ReleasedBy, &res.ReleaseReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type stockRepository struct {
+	db *dbpg.DB
+}
+
+func NewStockRepository(db *dbpg.DB) *stockRepository {
+	return &stockRepository{db: db}
+}
+
+func (r *stockRepository) GetLevel(ctx context.Context, itemID, locationID int) (int, error) {
+	query := `SELECT quantity FROM stock_levels WHERE item_id = $1 AND location_id = $2`
+
+	var quantity int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, itemID, locationID).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stock level: %w", err)
+	}
+
+	return quantity, nil
+}
+
+func (r *stockRepository) SetLevel(ctx context.Context, itemID, locationID, quantity int) error {
+	db := conn(ctx, r.db)
+
+	if quantity == 0 {
+		query := `DELETE FROM stock_levels WHERE item_id = $1 AND location_id = $2`
+		if _, err := db.ExecContext(ctx, query, itemID, locationID); err != nil {
+			return fmt.Errorf("failed to clear stock level: %w", err)
+		}
+		return nil
+	}
+
+	query := `
+		INSERT INTO stock_levels (item_id, location_id, quantity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (item_id, location_id)
+		DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()
+	`
+
+	if _, err := db.ExecContext(ctx, query, itemID, locationID, quantity); err != nil {
+		return fmt.Errorf("failed to set stock level: %w", err)
+	}
+
+	return nil
+}
+
+type stockMovementRepository struct {
+	db *dbpg.DB
+}
+
+func NewStockMovementRepository(db *dbpg.DB) *stockMovementRepository {
+	return &stockMovementRepository{db: db}
+}
+
+func (r *stockMovementRepository) Create(ctx context.Context, m *entity.StockMovement) error {
+	query := `
+		INSERT INTO stock_movements (
+			item_id, location_id, type, delta, balance_after, reason_code, reference,
+			username, api_key_name, request_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NULLIF($9, ''), NULLIF($10, ''))
+		RETURNING id, created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		m.ItemID, m.LocationID, m.Type, m.Delta, m.BalanceAfter, m.ReasonCode, m.Reference,
+		m.Username, m.APIKey, m.RequestID,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record stock movement: %w", err)
+	}
+
+	return nil
+}
+
+func (r *stockMovementRepository) GetByItemID(ctx context.Context, itemID, limit, offset int) ([]*entity.StockMovement, int, error) {
+	db := conn(ctx, r.db)
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM stock_movements WHERE item_id = $1`, itemID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count stock movements: %w", err)
+	}
+
+	query := `
+		SELECT id, item_id, location_id, type, delta, balance_after, reason_code, COALESCE(reference, ''),
+		       username, COALESCE(api_key_name, ''), COALESCE(request_id, ''), created_at
+		FROM stock_movements
+		WHERE item_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := db.QueryContext(ctx, query, itemID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := []*entity.StockMovement{}
+	for rows.Next() {
+		m := &entity.StockMovement{}
+		err := rows.Scan(
+			&m.ID, &m.ItemID, &m.LocationID, &m.Type, &m.Delta, &m.BalanceAfter, &m.ReasonCode, &m.Reference,
+			&m.Username, &m.APIKey, &m.RequestID, &m.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan stock movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return movements, total, nil
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type tokenRepository struct {
+	db *dbpg.DB
+}
+
+func NewTokenRepository(db *dbpg.DB) *tokenRepository {
+	return &tokenRepository{db: db}
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, access_jti, access_expires_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		token.UserID, token.TokenHash, token.FamilyID, token.AccessJTI, token.AccessExpiresAt.UTC(), token.ExpiresAt.UTC(),
+	).Scan(&token.ID, &token.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *tokenRepository) GetRefreshTokenForUpdate(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, family_id, access_jti, access_expires_at, expires_at, created_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+
+	token := &entity.RefreshToken{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.FamilyID,
+		&token.AccessJTI, &token.AccessExpiresAt, &token.ExpiresAt, &token.CreatedAt, &token.RevokedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (r *tokenRepository) RevokeRefreshToken(ctx context.Context, id int) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeFamily ends a whole login session: every refresh token of the family
+// and every access token still alive that was issued alongside them.
+func (r *tokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.revokeSessions(ctx, "family_id = $1", familyID)
+}
+
+func (r *tokenRepository) RevokeUserSessions(ctx context.Context, userID int) error {
+	return r.revokeSessions(ctx, "user_id = $1", userID)
+}
+
+func (r *tokenRepository) revokeSessions(ctx context.Context, where string, arg interface{}) error {
+	return NewTransactor(r.db).WithinTx(ctx, func(ctx context.Context) error {
+		db := conn(ctx, r.db)
+
+		denyQuery := `
+			INSERT INTO revoked_tokens (jti, expires_at)
+			SELECT access_jti, access_expires_at
+			FROM refresh_tokens
+			WHERE ` + where + ` AND access_expires_at > NOW()
+			ON CONFLICT (jti) DO NOTHING
+		`
+		if _, err := db.ExecContext(ctx, denyQuery, arg); err != nil {
+			return fmt.Errorf("failed to revoke access tokens: %w", err)
+		}
+
+		revokeQuery := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE ` + where + ` AND revoked_at IS NULL`
+		if _, err := db.ExecContext(ctx, revokeQuery, arg); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *tokenRepository) DenyAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, jti, expiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	// Entries outlive their token only until it would have expired anyway.
+	if _, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("failed to clean up revoked tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *tokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	if err := r.db.Master.QueryRowContext(ctx, query, jti).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,309 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type transferRepository struct {
+	db *dbpg.DB
+}
+
+func NewTransferRepository(db *dbpg.DB) *transferRepository {
+	return &transferRepository{db: db}
+}
+
+const transferLines = `COALESCE((
+	SELECT json_agg(json_build_object(
+		'id', l.id, 'item_id', l.item_id, 'from_location_id', l.from_location_id, 'quantity', l.quantity,
+		'to_location_id', l.to_location_id, 'received_quantity', l.received_quantity,
+		'discrepancy', l.discrepancy, 'discrepancy_note', l.discrepancy_note
+	) ORDER BY l.id)
+	FROM transfer_lines l
+	WHERE l.transfer_id = transfer_orders.id
+), '[]')`
+
+const transferColumns = `id, source_warehouse_id, destination_warehouse_id, status, note, ` + transferLines + `,
+	created_by, created_at, updated_at, dispatched_at, received_at, cancelled_at`
+
+func (r *transferRepository) Create(ctx context.Context, order *entity.TransferOrder) error {
+	db := conn(ctx, r.db)
+
+	query := `
+		INSERT INTO transfer_orders (source_warehouse_id, destination_warehouse_id, status, note, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := db.QueryRowContext(
+		ctx, query,
+		order.SourceWarehouseID, order.DestinationWarehouseID, order.Status, order.Note, order.CreatedBy,
+	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer order: %w", err)
+	}
+	order.Number = entity.TransferNumber(order.ID)
+
+	lineQuery := `
+		INSERT INTO transfer_lines (transfer_id, item_id, from_location_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		err := db.QueryRowContext(ctx, lineQuery, order.ID, line.ItemID, line.FromLocationID, line.Quantity).Scan(&line.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create transfer line: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *transferRepository) GetByID(ctx context.Context, id int) (*entity.TransferOrder, error) {
+	return r.get(ctx, `SELECT `+transferColumns+` FROM transfer_orders WHERE id = $1`, id)
+}
+
+// GetByIDForUpdate locks the order row, which serialises state changes of the
+// order. The caller must be in a transaction.
+func (r *transferRepository) GetByIDForUpdate(ctx context.Context, id int) (*entity.TransferOrder, error) {
+	return r.get(ctx, `SELECT `+transferColumns+` FROM transfer_orders WHERE id = $1 FOR UPDATE`, id)
+}
+
+func (r *transferRepository) get(ctx context.Context, query string, id int) (*entity.TransferOrder, error) {
+	order, err := scanTransfer(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer order: %w", err)
+	}
+
+	return order, nil
+}
+
+func (r *transferRepository) GetAll(ctx context.Context, filter *entity.TransferFilter) ([]*entity.TransferOrder, int, error) {
+	db := conn(ctx, r.db)
+
+	var status *string
+	if filter.Status != nil {
+		s := string(*filter.Status)
+		status = &s
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM transfer_orders WHERE ($1::varchar IS NULL OR status = $1)`
+	if err := db.QueryRowContext(ctx, countQuery, status).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transfer orders: %w", err)
+	}
+
+	query := `
+		SELECT ` + transferColumns + `
+		FROM transfer_orders
+		WHERE ($1::varchar IS NULL OR status = $1)
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := db.QueryContext(ctx, query, status, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get transfer orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []*entity.TransferOrder{}
+	for rows.Next() {
+		order, err := scanTransfer(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan transfer order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return orders, total, nil
+}
+
+func (r *transferRepository) Update(ctx context.Context, order *entity.TransferOrder) error {
+	db := conn(ctx, r.db)
+
+	query := `
+		UPDATE transfer_orders
+		SET status = $2::varchar,
+		    updated_at = NOW(),
+		    dispatched_at = CASE WHEN $2::varchar = 'dispatched' THEN NOW() ELSE dispatched_at END,
+		    received_at = CASE WHEN $2::varchar = 'received' THEN NOW() ELSE received_at END,
+		    cancelled_at = CASE WHEN $2::varchar = 'cancelled' THEN NOW() ELSE cancelled_at END
+		WHERE id = $1
+		RETURNING updated_at, dispatched_at, received_at, cancelled_at
+	`
+
+	err := db.QueryRowContext(ctx, query, order.ID, order.Status).Scan(
+		&order.UpdatedAt, &order.DispatchedAt, &order.ReceivedAt, &order.CancelledAt,
+	)
+	if err == sql.ErrNoRows {
+		return entity.ErrTransferNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update transfer order: %w", err)
+	}
+
+	lineQuery := `
+		UPDATE transfer_lines
+		SET to_location_id = $3, received_quantity = $4, discrepancy = $5, discrepancy_note = $6
+		WHERE id = $1 AND transfer_id = $2
+	`
+
+	for _, line := range order.Lines {
+		if line.ReceivedQuantity == nil {
+			continue
+		}
+
+		_, err := db.ExecContext(
+			ctx, lineQuery,
+			line.ID, order.ID, line.ToLocationID, line.ReceivedQuantity, line.Discrepancy, line.DiscrepancyNote,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update transfer line: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func scanTransfer(row rowScanner) (*entity.TransferOrder, error) {
+	order := &entity.TransferOrder{}
+	var linesJSON []byte
+
+	err := row.Scan(
+		&order.ID, &order.SourceWarehouseID, &order.DestinationWarehouseID, &order.Status, &order.Note, &linesJSON,
+		&order.CreatedBy, &order.CreatedAt, &order.UpdatedAt, &order.DispatchedAt, &order.ReceivedAt, &order.CancelledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(linesJSON, &order.Lines); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal transfer lines: %w", err)
+	}
+	order.Number = entity.TransferNumber(order.ID)
+
+	return order, nil
+}
+
+type transferHistoryRepository struct {
+	db *dbpg.DB
+}
+
+func NewTransferHistoryRepository(db *dbpg.DB) *transferHistoryRepository {
+	return &transferHistoryRepository{db: db}
+}
+
+func (r *transferHistoryRepository) Record(ctx context.Context, h *entity.TransferHistory) error {
+	oldData, err := marshalTransferSnapshot(h.OldData)
+	if err != nil {
+		return err
+	}
+
+	newData, err := marshalTransferSnapshot(h.NewData)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO transfer_history (transfer_id, action, username, api_key_name, old_data, new_data, request_id, client_ip, reason)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''))
+		RETURNING id, changed_at
+	`
+
+	err = conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		h.TransferID, h.Action, h.Username, h.APIKey, oldData, newData, h.RequestID, h.ClientIP, h.Reason,
+	).Scan(&h.ID, &h.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record transfer history: %w", err)
+	}
+
+	return nil
+}
+
+func (r *transferHistoryRepository) GetByTransferID(ctx context.Context, transferID, limit, offset int) ([]*entity.TransferHistory, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM transfer_history WHERE transfer_id = $1`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, transferID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transfer history: %w", err)
+	}
+
+	query := `
+		SELECT id, transfer_id, action, username, COALESCE(api_key_name, ''), old_data, new_data,
+			COALESCE(request_id, ''), COALESCE(client_ip, ''), COALESCE(reason, ''), changed_at
+		FROM transfer_history
+		WHERE transfer_id = $1
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, transferID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get transfer history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []*entity.TransferHistory{}
+	for rows.Next() {
+		h := &entity.TransferHistory{}
+		var oldDataJSON, newDataJSON []byte
+
+		err := rows.Scan(
+			&h.ID, &h.TransferID, &h.Action, &h.Username, &h.APIKey, &oldDataJSON, &newDataJSON,
+			&h.RequestID, &h.ClientIP, &h.Reason, &h.ChangedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan transfer history: %w", err)
+		}
+
+		if !isNull(oldDataJSON) {
+			h.OldData = &entity.TransferOrder{}
+			if err := json.Unmarshal(oldDataJSON, h.OldData); err != nil {
+				return nil, 0, fmt.Errorf("failed to unmarshal old_data: %w", err)
+			}
+		}
+
+		if !isNull(newDataJSON) {
+			h.NewData = &entity.TransferOrder{}
+			if err := json.Unmarshal(newDataJSON, h.NewData); err != nil {
+				return nil, 0, fmt.Errorf("failed to unmarshal new_data: %w", err)
+			}
+		}
+
+		history = append(history, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return history, total, nil
+}
+
+func marshalTransferSnapshot(order *entity.TransferOrder) (interface{}, error) {
+	if order == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transfer snapshot: %w", err)
+	}
+
+	return string(data), nil
+}
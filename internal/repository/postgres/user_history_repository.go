@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type userHistoryRepository struct {
+	db *dbpg.DB
+}
+
+func NewUserHistoryRepository(db *dbpg.DB) *userHistoryRepository {
+	return &userHistoryRepository{db: db}
+}
+
+func (r *userHistoryRepository) Record(ctx context.Context, h *entity.UserHistory) error {
+	oldData, err := marshalUserSnapshot(h.OldData)
+	if err != nil {
+		return err
+	}
+
+	newData, err := marshalUserSnapshot(h.NewData)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO users_history (user_id, action, username, api_key_name, old_data, new_data, request_id, client_ip, reason)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''))
+		RETURNING id, changed_at
+	`
+
+	err = conn(ctx, r.db).QueryRowContext(
+		ctx, query,
+		h.UserID, h.Action, h.Username, h.APIKey, oldData, newData, h.RequestID, h.ClientIP, h.Reason,
+	).Scan(&h.ID, &h.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record user history: %w", err)
+	}
+
+	return nil
+}
+
+func (r *userHistoryRepository) GetByUserID(ctx context.Context, userID, limit, offset int) ([]*entity.UserHistory, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM users_history WHERE user_id = $1`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count user history: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, action, username, COALESCE(api_key_name, ''), old_data, new_data,
+			COALESCE(request_id, ''), COALESCE(client_ip, ''), COALESCE(reason, ''), changed_at
+		FROM users_history
+		WHERE user_id = $1
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get user history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*entity.UserHistory
+	for rows.Next() {
+		h := &entity.UserHistory{}
+		var oldDataJSON, newDataJSON []byte
+
+		err := rows.Scan(
+			&h.ID, &h.UserID, &h.Action, &h.Username, &h.APIKey, &oldDataJSON, &newDataJSON,
+			&h.RequestID, &h.ClientIP, &h.Reason, &h.ChangedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user history: %w", err)
+		}
+
+		if !isNull(oldDataJSON) {
+			h.OldData = &entity.User{}
+			if err := json.Unmarshal(oldDataJSON, h.OldData); err != nil {
+				return nil, 0, fmt.Errorf("failed to unmarshal old_data: %w", err)
+			}
+		}
+
+		if !isNull(newDataJSON) {
+			h.NewData = &entity.User{}
+			if err := json.Unmarshal(newDataJSON, h.NewData); err != nil {
+				return nil, 0, fmt.Errorf("failed to unmarshal new_data: %w", err)
+			}
+		}
+
+		history = append(history, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return history, total, nil
+}
+
+// marshalUserSnapshot encodes a user for a JSONB column. The password hash is
+// left out by the json tags on entity.User.
+func marshalUserSnapshot(user *entity.User) (interface{}, error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal user snapshot: %w", err)
+	}
+
+	return string(data), nil
+}
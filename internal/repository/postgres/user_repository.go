@@ -17,18 +17,43 @@ func NewUserRepository(db *dbpg.DB) *userRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) GetByUsername(ctx context.Context, username string) (*entity.User, error) {
-	query := `
-		SELECT id, username, password, role, created_at
-		FROM users
-		WHERE username = $1
-	`
+const userColumns = `id, username, COALESCE(password, ''), role, created_at, disabled_at, password_change_required,
+	COALESCE(sso_issuer, ''), COALESCE(sso_subject, '')`
 
-	user := &entity.User{}
-	err := r.db.QueryRowContext(ctx, query, username).Scan(
-		&user.ID, &user.Username, &user.Password, &user.Role, &user.CreatedAt,
-	)
+func (r *userRepository) GetByID(ctx context.Context, id int) (*entity.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetByIDForUpdate reads a user and locks its row until the surrounding
+// transaction ends.
+func (r *userRepository) GetByIDForUpdate(ctx context.Context, id int) (*entity.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 FOR UPDATE`
+
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*entity.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
 
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, username))
 	if err == sql.ErrNoRows {
 		return nil, entity.ErrInvalidCredentials
 	}
@@ -39,21 +64,164 @@ func (r *userRepository) GetByUsername(ctx context.Context, username string) (*e
 	return user, nil
 }
 
+// GetBySSOSubject finds the account linked to an identity provider user.
+func (r *userRepository) GetBySSOSubject(ctx context.Context, issuer, subject string) (*entity.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE sso_issuer = $1 AND sso_subject = $2`
+
+	user, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, issuer, subject))
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) GetAll(ctx context.Context, filter *entity.UserFilter) ([]*entity.User, int, error) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	argPos := 1
+
+	if filter.Username != nil {
+		where += fmt.Sprintf(" AND username ILIKE $%d", argPos)
+		args = append(args, "%"+escapeLike(*filter.Username)+"%")
+		argPos++
+	}
+
+	if filter.Role != nil {
+		where += fmt.Sprintf(" AND role = $%d", argPos)
+		args = append(args, *filter.Role)
+		argPos++
+	}
+
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			where += " AND disabled_at IS NOT NULL"
+		} else {
+			where += " AND disabled_at IS NULL"
+		}
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM users` + where
+	if err := conn(ctx, r.db).QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users` + where + ` ORDER BY id`
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argPos)
+		args = append(args, filter.Limit)
+		argPos++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argPos)
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*entity.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return users, total, nil
+}
+
 func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
 	query := `
-		INSERT INTO users (username, password, role)
-		VALUES ($1, $2, $3)
+		INSERT INTO users (username, password, role, password_change_required, sso_issuer, sso_subject)
+		VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''), NULLIF($6, ''))
 		RETURNING id, created_at
 	`
 
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx, query,
-		user.Username, user.Password, user.Role,
+		user.Username, user.Password, user.Role, user.PasswordChangeRequired, user.SSOIssuer, user.SSOSubject,
 	).Scan(&user.ID, &user.CreatedAt)
 
+	if isUniqueViolation(err) {
+		return entity.ErrUserExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return nil
 }
+
+func (r *userRepository) UpdateRole(ctx context.Context, id int, role entity.Role) error {
+	return r.exec(ctx, "update user role", `UPDATE users SET role = $2 WHERE id = $1`, id, role)
+}
+
+// SetDisabled blocks or unblocks an account. Disabling an already disabled
+// account keeps the original timestamp.
+func (r *userRepository) SetDisabled(ctx context.Context, id int, disabled bool) error {
+	query := `
+		UPDATE users
+		SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) ELSE NULL END
+		WHERE id = $1
+	`
+
+	return r.exec(ctx, "update user status", query, id, disabled)
+}
+
+// UpdatePassword stores a new password hash. changeRequired makes the user
+// replace it before doing anything else.
+func (r *userRepository) UpdatePassword(ctx context.Context, id int, password string, changeRequired bool) error {
+	query := `UPDATE users SET password = $2, password_change_required = $3 WHERE id = $1`
+
+	return r.exec(ctx, "update user password", query, id, password, changeRequired)
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int) error {
+	return r.exec(ctx, "delete user", `DELETE FROM users WHERE id = $1`, id)
+}
+
+func (r *userRepository) exec(ctx context.Context, op, query string, args ...interface{}) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func scanUser(row rowScanner) (*entity.User, error) {
+	user := &entity.User{}
+	err := row.Scan(
+		&user.ID, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.DisabledAt, &user.PasswordChangeRequired,
+		&user.SSOIssuer, &user.SSOSubject,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/wb-go/wbf/dbpg"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+)
+
+type warehouseRepository struct {
+	db *dbpg.DB
+}
+
+func NewWarehouseRepository(db *dbpg.DB) *warehouseRepository {
+	return &warehouseRepository{db: db}
+}
+
+func (r *warehouseRepository) Create(ctx context.Context, warehouse *entity.Warehouse) error {
+	query := `
+		INSERT INTO warehouses (code, name, address)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query, warehouse.Code, warehouse.Name, warehouse.Address,
+	).Scan(&warehouse.ID, &warehouse.CreatedAt)
+
+	if isUniqueViolation(err) {
+		return entity.ErrWarehouseExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create warehouse: %w", err)
+	}
+
+	return nil
+}
+
+func (r *warehouseRepository) GetByID(ctx context.Context, id int) (*entity.Warehouse, error) {
+	query := `SELECT id, code, name, address, created_at FROM warehouses WHERE id = $1`
+
+	warehouse := &entity.Warehouse{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+		&warehouse.ID, &warehouse.Code, &warehouse.Name, &warehouse.Address, &warehouse.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrWarehouseNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouse: %w", err)
+	}
+
+	return warehouse, nil
+}
+
+func (r *warehouseRepository) GetAll(ctx context.Context) ([]*entity.Warehouse, error) {
+	query := `SELECT id, code, name, address, created_at FROM warehouses ORDER BY code`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouses: %w", err)
+	}
+	defer rows.Close()
+
+	warehouses := []*entity.Warehouse{}
+	for rows.Next() {
+		warehouse := &entity.Warehouse{}
+		err := rows.Scan(&warehouse.ID, &warehouse.Code, &warehouse.Name, &warehouse.Address, &warehouse.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan warehouse: %w", err)
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return warehouses, nil
+}
+
+type locationRepository struct {
+	db *dbpg.DB
+}
+
+func NewLocationRepository(db *dbpg.DB) *locationRepository {
+	return &locationRepository{db: db}
+}
+
+const locationColumns = `l.id, l.warehouse_id, w.code, l.code, l.zone, l.created_at`
+
+func (r *locationRepository) Create(ctx context.Context, location *entity.Location) error {
+	query := `
+		INSERT INTO locations (warehouse_id, code, zone)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, (SELECT code FROM warehouses WHERE id = $1)
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx, query, location.WarehouseID, location.Code, location.Zone,
+	).Scan(&location.ID, &location.CreatedAt, &location.WarehouseCode)
+
+	if isUniqueViolation(err) {
+		return entity.ErrLocationExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create location: %w", err)
+	}
+
+	return nil
+}
+
+func (r *locationRepository) GetByID(ctx context.Context, id int) (*entity.Location, error) {
+	query := `
+		SELECT ` + locationColumns + `
+		FROM locations l
+		JOIN warehouses w ON w.id = l.warehouse_id
+		WHERE l.id = $1
+	`
+
+	location := &entity.Location{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+		&location.ID, &location.WarehouseID, &location.WarehouseCode, &location.Code, &location.Zone, &location.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, entity.ErrLocationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get location: %w", err)
+	}
+
+	return location, nil
+}
+
+func (r *locationRepository) GetAll(ctx context.Context, warehouseID *int) ([]*entity.Location, error) {
+	query := `
+		SELECT ` + locationColumns + `
+		FROM locations l
+		JOIN warehouses w ON w.id = l.warehouse_id
+		WHERE $1::int IS NULL OR l.warehouse_id = $1
+		ORDER BY w.code, l.zone, l.code
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []*entity.Location{}
+	for rows.Next() {
+		location := &entity.Location{}
+		err := rows.Scan(
+			&location.ID, &location.WarehouseID, &location.WarehouseCode, &location.Code, &location.Zone, &location.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan location: %w", err)
+		}
+		locations = append(locations, location)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return locations, nil
+}
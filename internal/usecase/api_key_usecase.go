@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/repository"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/securetoken"
+)
+
+// apiKeyPrefixLength is how much of a key is kept in clear text so its owner
+// can tell keys apart.
+const apiKeyPrefixLength = len(entity.APIKeyPrefix) + 8
+
+type APIKeyUseCase struct {
+	apiKeyRepo  repository.APIKeyRepository
+	authUseCase *AuthUseCase
+}
+
+func NewAPIKeyUseCase(apiKeyRepo repository.APIKeyRepository, authUseCase *AuthUseCase) *APIKeyUseCase {
+	return &APIKeyUseCase{
+		apiKeyRepo:  apiKeyRepo,
+		authUseCase: authUseCase,
+	}
+}
+
+// Create mints a key for owner. The key may only carry permissions the owner
+// has. The plain key is returned only here; just its hash is stored.
+func (uc *APIKeyUseCase) Create(ctx context.Context, owner *entity.User, name string, permissions []entity.Permission, expiresAt *time.Time) (*entity.APIKey, string, error) {
+	if owner.APIKeyName != "" {
+		return nil, "", entity.ErrForbidden
+	}
+
+	for _, perm := range permissions {
+		if !perm.IsValid() || !owner.Can(perm) {
+			return nil, "", fmt.Errorf("%w: %q", entity.ErrInvalidPermission, perm)
+		}
+	}
+
+	token, err := securetoken.New()
+	if err != nil {
+		return nil, "", err
+	}
+	plain := entity.APIKeyPrefix + token
+
+	key := &entity.APIKey{
+		UserID:      owner.ID,
+		Name:        name,
+		Prefix:      plain[:apiKeyPrefixLength],
+		Permissions: permissions,
+		ExpiresAt:   expiresAt,
+	}
+
+	if err := uc.apiKeyRepo.Create(ctx, key, securetoken.Hash(plain)); err != nil {
+		if err == entity.ErrAPIKeyExists {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return key, plain, nil
+}
+
+// List returns the keys of owner. Like Create and Revoke it is refused to a
+// request made with an API key, so a leaked key cannot find or cancel the
+// owner's other keys.
+func (uc *APIKeyUseCase) List(ctx context.Context, owner *entity.User) ([]*entity.APIKey, error) {
+	if owner.APIKeyName != "" {
+		return nil, entity.ErrForbidden
+	}
+
+	return uc.apiKeyRepo.GetByUserID(ctx, owner.ID)
+}
+
+func (uc *APIKeyUseCase) Revoke(ctx context.Context, owner *entity.User, id int) error {
+	if owner.APIKeyName != "" {
+		return entity.ErrForbidden
+	}
+
+	return uc.apiKeyRepo.Revoke(ctx, id, owner.ID)
+}
+
+// Authenticate resolves a plain key to its owner. The returned user only has
+// the permissions granted both by the key and by the owner's current role.
+func (uc *APIKeyUseCase) Authenticate(ctx context.Context, plain string) (*entity.User, error) {
+	key, err := uc.apiKeyRepo.GetByHash(ctx, securetoken.Hash(plain))
+	if err != nil {
+		if err == entity.ErrAPIKeyNotFound {
+			return nil, entity.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, entity.ErrInvalidToken
+	}
+
+	owner, err := uc.authUseCase.GetActiveUser(ctx, key.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	var granted []entity.Permission
+	for _, perm := range entity.AllPermissions {
+		if owner.Can(perm) && key.Grants(perm) {
+			granted = append(granted, perm)
+		}
+	}
+	owner.Permissions = granted
+	owner.APIKeyName = key.Name
+
+	if err := uc.apiKeyRepo.TouchLastUsed(ctx, key.ID, now); err != nil {
+		return nil, err
+	}
+
+	return owner, nil
+}
@@ -0,0 +1,320 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/jwt"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/securetoken"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSkew accepts codes from one period before and after now to allow
+	// for clock drift on the user's device.
+	totpSkew = 1
+)
+
+func (uc *AuthUseCase) mfaRequired(role entity.Role) bool {
+	return slices.Contains(uc.settings.MFARequiredRoles, role)
+}
+
+// mfaChallenge returns nil if user may log in with the password alone.
+func (uc *AuthUseCase) mfaChallenge(ctx context.Context, user *entity.User) (*entity.MFAChallenge, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, user.ID)
+	if err != nil && err != entity.ErrMFANotEnrolled {
+		return nil, err
+	}
+
+	if !mfa.IsEnabled() && !uc.mfaRequired(user.Role) {
+		return nil, nil
+	}
+
+	token, err := uc.jwtManager.GenerateMFA(user.Username, uc.settings.MFAPendingTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &entity.MFAChallenge{
+		Token:              token.Value,
+		ExpiresAt:          token.ExpiresAt,
+		EnrollmentRequired: !mfa.IsEnabled(),
+	}, nil
+}
+
+// pendingUser resolves an "mfa pending" token from the first login step.
+func (uc *AuthUseCase) pendingUser(ctx context.Context, mfaToken string) (*entity.User, *jwt.Claims, error) {
+	claims, err := uc.jwtManager.VerifyMFA(ctx, mfaToken)
+	if err != nil {
+		return nil, nil, entity.ErrInvalidToken
+	}
+
+	user, err := uc.GetActiveUser(ctx, claims.Username)
+	if err != nil {
+		if err == entity.ErrUserNotFound {
+			return nil, nil, entity.ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	return user, claims, nil
+}
+
+// StartMFAEnrollment lets a user whose role requires MFA set it up in the
+// middle of a login, using the pending token instead of a session.
+func (uc *AuthUseCase) StartMFAEnrollment(ctx context.Context, mfaToken string) (*entity.MFAEnrollment, error) {
+	user, _, err := uc.pendingUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.BeginMFAEnrollment(ctx, user)
+}
+
+// CompleteMFALogin finishes a login with a TOTP code or a recovery code. If
+// the user was enrolling, the code confirms the enrollment and the recovery
+// codes are returned with the tokens. Wrong codes count as failed logins.
+func (uc *AuthUseCase) CompleteMFALogin(ctx context.Context, mfaToken, code, recoveryCode, clientIP string) (*entity.LoginResult, error) {
+	user, claims, err := uc.pendingUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	err = uc.guardMFACode(ctx, user, clientIP, func() error {
+		return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			mfa, err := uc.mfaRepo.GetByUserIDForUpdate(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+
+			if !mfa.IsEnabled() {
+				recoveryCodes, err = uc.enableMFA(ctx, mfa, code)
+				return err
+			}
+
+			if recoveryCode != "" {
+				return uc.mfaRepo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(recoveryCode))
+			}
+
+			return uc.acceptTOTP(ctx, mfa, code)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The pending token is single use.
+	if err := uc.tokenRepo.DenyAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, fmt.Errorf("failed to complete login: %w", err)
+	}
+
+	result, err := uc.finishLogin(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	result.RecoveryCodes = recoveryCodes
+
+	return result, nil
+}
+
+// BeginMFAEnrollment creates a new, not yet enabled TOTP secret for user.
+// Calling it again before confirming replaces the secret.
+func (uc *AuthUseCase) BeginMFAEnrollment(ctx context.Context, user *entity.User) (*entity.MFAEnrollment, error) {
+	if user.APIKeyName != "" {
+		return nil, entity.ErrForbidden
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.mfaRepo.SavePending(ctx, user.ID, secret); err != nil {
+		if err == entity.ErrMFAAlreadyEnabled {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to start mfa enrollment: %w", err)
+	}
+
+	return &entity.MFAEnrollment{
+		Secret: secret,
+		URI:    totp.URI(uc.settings.MFAIssuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment enables MFA once the user proves the authenticator
+// produces valid codes, and returns fresh recovery codes.
+func (uc *AuthUseCase) ConfirmMFAEnrollment(ctx context.Context, user *entity.User, code, clientIP string) ([]string, error) {
+	if user.APIKeyName != "" {
+		return nil, entity.ErrForbidden
+	}
+
+	var recoveryCodes []string
+	err := uc.guardMFACode(ctx, user, clientIP, func() error {
+		return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			mfa, err := uc.mfaRepo.GetByUserIDForUpdate(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+
+			if mfa.IsEnabled() {
+				return entity.ErrMFAAlreadyEnabled
+			}
+
+			recoveryCodes, err = uc.enableMFA(ctx, mfa, code)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableMFA turns MFA off for user, unless the role requires it.
+func (uc *AuthUseCase) DisableMFA(ctx context.Context, user *entity.User, code, clientIP string) error {
+	if user.APIKeyName != "" {
+		return entity.ErrForbidden
+	}
+
+	if uc.mfaRequired(user.Role) {
+		return entity.ErrMFARequired
+	}
+
+	return uc.guardMFACode(ctx, user, clientIP, func() error {
+		return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			mfa, err := uc.mfaRepo.GetByUserIDForUpdate(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+
+			if !mfa.IsEnabled() {
+				return entity.ErrMFANotEnrolled
+			}
+
+			if err := uc.acceptTOTP(ctx, mfa, code); err != nil {
+				return err
+			}
+
+			return uc.mfaRepo.Delete(ctx, user.ID)
+		})
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of user.
+func (uc *AuthUseCase) RegenerateRecoveryCodes(ctx context.Context, user *entity.User, code, clientIP string) ([]string, error) {
+	if user.APIKeyName != "" {
+		return nil, entity.ErrForbidden
+	}
+
+	var recoveryCodes []string
+	err := uc.guardMFACode(ctx, user, clientIP, func() error {
+		return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			mfa, err := uc.mfaRepo.GetByUserIDForUpdate(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+
+			if !mfa.IsEnabled() {
+				return entity.ErrMFANotEnrolled
+			}
+
+			if err := uc.acceptTOTP(ctx, mfa, code); err != nil {
+				return err
+			}
+
+			recoveryCodes, err = uc.newRecoveryCodes(ctx, user.ID)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// guardMFACode runs check under the login throttle: nothing is checked while
+// the user or IP has to wait, and an ErrInvalidMFACode counts as a failure.
+func (uc *AuthUseCase) guardMFACode(ctx context.Context, user *entity.User, clientIP string, check func() error) error {
+	attempt, err := uc.reserveAttempt(ctx, entity.UsernameAttemptKey(strings.ToLower(user.Username)), entity.IPAttemptKey(clientIP))
+	if err != nil {
+		return err
+	}
+
+	err = check()
+	if err != entity.ErrInvalidMFACode {
+		if err := uc.releaseAttempt(ctx, attempt); err != nil {
+			return err
+		}
+		return err
+	}
+
+	if err := uc.attemptFailed(attempt); err != entity.ErrInvalidCredentials {
+		return err
+	}
+
+	return entity.ErrInvalidMFACode
+}
+
+func (uc *AuthUseCase) enableMFA(ctx context.Context, mfa *entity.MFA, code string) ([]string, error) {
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, entity.ErrInvalidMFACode
+	}
+
+	if err := uc.mfaRepo.Enable(ctx, mfa.UserID, step); err != nil {
+		return nil, err
+	}
+
+	return uc.newRecoveryCodes(ctx, mfa.UserID)
+}
+
+// acceptTOTP checks a code and remembers its time step, so the same code
+// cannot be replayed within its validity window.
+func (uc *AuthUseCase) acceptTOTP(ctx context.Context, mfa *entity.MFA, code string) error {
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), totpSkew)
+	if !ok || step <= mfa.LastStep {
+		return entity.ErrInvalidMFACode
+	}
+
+	return uc.mfaRepo.SetLastStep(ctx, mfa.UserID, step)
+}
+
+func (uc *AuthUseCase) newRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		raw := hex.EncodeToString(b)
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		hashes[i] = hashRecoveryCode(raw)
+	}
+
+	if err := uc.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, so codes can be typed the
+// way they were shown or without separators.
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return securetoken.Hash(normalized)
+}
@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wb-go/wbf/zlog"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/notifier"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/securetoken"
+)
+
+// ChangePassword replaces the caller's own password. The current password is
+// checked like a login, so wrong guesses are throttled. Every session of the
+// user ends, including the caller's; a new one is returned instead.
+func (uc *AuthUseCase) ChangePassword(ctx context.Context, user *entity.User, current, password string, meta *entity.AuditMeta) (*entity.LoginResult, error) {
+	if user.APIKeyName != "" {
+		return nil, entity.ErrForbidden
+	}
+
+	attempt, err := uc.reserveAttempt(ctx, entity.UsernameAttemptKey(strings.ToLower(user.Username)), entity.IPAttemptKey(meta.ClientIP))
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := uc.authenticate(ctx, user.Username, current)
+	if err == entity.ErrInvalidCredentials {
+		return nil, uc.attemptFailed(attempt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.releaseAttempt(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	if err := uc.checkNewPassword(stored, password); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := uc.changeUser(ctx, stored.ID, entity.UserActionChangePassword, meta, func(ctx context.Context, _ *entity.User) (bool, error) {
+		return true, uc.setPassword(ctx, stored.ID, hashedPassword, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	uc.resolvePermissions(updated)
+
+	return uc.finishLogin(ctx, updated)
+}
+
+// RequestPasswordReset sends a reset link through the notifier. Requests are
+// throttled per username and per client IP like logins, with counters of their
+// own. Past the throttle, the request does the same work whatever the username:
+// the account is looked up and the link sent only after the answer, so neither
+// the answer nor its timing reveals which usernames exist. Unknown, disabled
+// and SSO-only accounts are silently ignored. Requesting again invalidates the
+// previous link.
+func (uc *AuthUseCase) RequestPasswordReset(ctx context.Context, username, clientIP string) error {
+	userKey := entity.PasswordResetAttemptKey(entity.UsernameAttemptKey(strings.ToLower(username)))
+	ipKey := entity.PasswordResetAttemptKey(entity.IPAttemptKey(clientIP))
+
+	// Every request counts: it is never taken back, whatever the username.
+	if _, err := uc.reserveAttempt(ctx, userKey, ipKey); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := uc.sendPasswordReset(context.WithoutCancel(ctx), username); err != nil {
+			zlog.Logger.Error().Err(err).Msg("Failed to send password reset")
+		}
+	}()
+
+	return nil
+}
+
+func (uc *AuthUseCase) sendPasswordReset(ctx context.Context, username string) error {
+	user, err := uc.userRepo.GetByUsername(ctx, username)
+	if err == entity.ErrInvalidCredentials {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.IsDisabled() || user.Password == "" {
+		return nil
+	}
+
+	token, err := securetoken.New()
+	if err != nil {
+		return err
+	}
+
+	reset := &entity.PasswordReset{
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(uc.settings.PasswordResetTTL),
+	}
+
+	err = uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.resetRepo.DeleteByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return uc.resetRepo.Create(ctx, reset, securetoken.Hash(token))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request password reset: %w", err)
+	}
+
+	err = uc.notifier.SendPasswordReset(ctx, notifier.PasswordResetMessage{
+		Username:  user.Username,
+		Link:      uc.settings.PasswordResetURL + "?token=" + url.QueryEscape(token),
+		ExpiresAt: reset.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send password reset: %w", err)
+	}
+
+	return nil
+}
+
+// ResetPasswordWithToken sets a new password using a token from
+// RequestPasswordReset. The token works once; all sessions of the user end and
+// a lockout from failed logins is lifted.
+func (uc *AuthUseCase) ResetPasswordWithToken(ctx context.Context, token, password string, meta *entity.AuditMeta) error {
+	var username string
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		reset, err := uc.resetRepo.GetByTokenHashForUpdate(ctx, securetoken.Hash(token))
+		if err != nil {
+			return err
+		}
+
+		if !reset.IsUsable(time.Now()) {
+			return entity.ErrInvalidResetToken
+		}
+
+		user, err := uc.userRepo.GetByIDForUpdate(ctx, reset.UserID)
+		if err != nil {
+			return err
+		}
+
+		if user.IsDisabled() {
+			return entity.ErrInvalidResetToken
+		}
+
+		if err := uc.checkNewPassword(user, password); err != nil {
+			return err
+		}
+
+		hashedPassword, err := hashPassword(password)
+		if err != nil {
+			return err
+		}
+
+		if err := uc.setPassword(ctx, user.ID, hashedPassword, false); err != nil {
+			return err
+		}
+
+		updated, err := uc.userRepo.GetByID(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		username = user.Username
+		meta.Username = user.Username
+
+		return uc.userHistory.Record(ctx, entity.NewUserHistory(user.ID, entity.UserActionResetPassword, user, updated, meta))
+	})
+	if err != nil {
+		switch {
+		case err == entity.ErrInvalidResetToken, err == entity.ErrPasswordReused, errors.Is(err, entity.ErrWeakPassword):
+			return err
+		case err == entity.ErrUserNotFound:
+			return entity.ErrInvalidResetToken
+		}
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	return uc.attemptRepo.Reset(ctx, entity.UsernameAttemptKey(strings.ToLower(username)))
+}
+
+// checkNewPassword applies the password policy and rejects keeping the
+// current password.
+func (uc *AuthUseCase) checkNewPassword(user *entity.User, password string) error {
+	if err := uc.settings.PasswordPolicy.Validate(password, user.Username); err != nil {
+		return err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil {
+		return entity.ErrPasswordReused
+	}
+
+	return nil
+}
+
+// setPassword stores a new password hash, drops pending reset tokens and ends
+// all sessions of the user.
+func (uc *AuthUseCase) setPassword(ctx context.Context, userID int, hashedPassword string, changeRequired bool) error {
+	if err := uc.userRepo.UpdatePassword(ctx, userID, hashedPassword, changeRequired); err != nil {
+		return err
+	}
+
+	if err := uc.resetRepo.DeleteByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	return uc.tokenRepo.RevokeUserSessions(ctx, userID)
+}
+
+func hashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hashed), nil
+}
@@ -2,64 +2,491 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
 	"github.com/yokitheyo/WarehouseControl/internal/domain/repository"
 	"github.com/yokitheyo/WarehouseControl/internal/pkg/jwt"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/notifier"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/securetoken"
 )
 
+type AuthSettings struct {
+	RefreshTTL         time.Duration
+	PublicRegistration entity.RegistrationMode
+	InvitationTTL      time.Duration
+	Permissions        entity.PermissionMatrix
+	UserThrottle       entity.LoginThrottlePolicy
+	IPThrottle         entity.LoginThrottlePolicy
+	MFARequiredRoles   []entity.Role
+	MFAPendingTTL      time.Duration
+	MFAIssuer          string
+	PasswordPolicy     entity.PasswordPolicy
+	PasswordResetTTL   time.Duration
+	// PasswordResetURL is the page users open to set a new password; the
+	// reset token is appended as the token query parameter.
+	PasswordResetURL string
+}
+
 type AuthUseCase struct {
-	userRepo   repository.UserRepository
-	jwtManager *jwt.Manager
+	userRepo       repository.UserRepository
+	userHistory    repository.UserHistoryRepository
+	tokenRepo      repository.TokenRepository
+	invitationRepo repository.InvitationRepository
+	attemptRepo    repository.LoginAttemptRepository
+	mfaRepo        repository.MFARepository
+	resetRepo      repository.PasswordResetRepository
+	transactor     repository.Transactor
+	jwtManager     *jwt.Manager
+	notifier       notifier.Notifier
+	settings       AuthSettings
 }
 
-func NewAuthUseCase(userRepo repository.UserRepository, jwtManager *jwt.Manager) *AuthUseCase {
+func NewAuthUseCase(
+	userRepo repository.UserRepository,
+	userHistory repository.UserHistoryRepository,
+	tokenRepo repository.TokenRepository,
+	invitationRepo repository.InvitationRepository,
+	attemptRepo repository.LoginAttemptRepository,
+	mfaRepo repository.MFARepository,
+	resetRepo repository.PasswordResetRepository,
+	transactor repository.Transactor,
+	jwtManager *jwt.Manager,
+	notifier notifier.Notifier,
+	settings AuthSettings,
+) *AuthUseCase {
 	return &AuthUseCase{
-		userRepo:   userRepo,
-		jwtManager: jwtManager,
+		userRepo:       userRepo,
+		userHistory:    userHistory,
+		tokenRepo:      tokenRepo,
+		invitationRepo: invitationRepo,
+		attemptRepo:    attemptRepo,
+		mfaRepo:        mfaRepo,
+		resetRepo:      resetRepo,
+		transactor:     transactor,
+		jwtManager:     jwtManager,
+		notifier:       notifier,
+		settings:       settings,
 	}
 }
 
-func (uc *AuthUseCase) Login(ctx context.Context, username, password string) (string, error) {
+// Login checks credentials. Attempts are counted per username and per client
+// IP before the password is checked, and taken back if it was right; while
+// either has to wait, credentials are not checked at all and a
+// LoginThrottledError is returned. Unknown usernames are counted and timed
+// like wrong passwords, so responses do not reveal which accounts exist.
+//
+// When the account uses MFA, or its role requires it, no tokens are issued
+// yet: the result carries a challenge to finish with CompleteMFALogin.
+func (uc *AuthUseCase) Login(ctx context.Context, username, password, clientIP string) (*entity.LoginResult, error) {
+	attempt, err := uc.reserveAttempt(ctx, entity.UsernameAttemptKey(strings.ToLower(username)), entity.IPAttemptKey(clientIP))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := uc.authenticate(ctx, username, password)
+	if err == entity.ErrInvalidCredentials {
+		return nil, uc.attemptFailed(attempt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.releaseAttempt(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, entity.ErrUserDisabled
+	}
+	uc.resolvePermissions(user)
+
+	return uc.startLogin(ctx, user)
+}
+
+// startLogin is called once the first factor is accepted, by password or by
+// SSO. If the user has to pass MFA too, the result carries the challenge
+// instead of tokens.
+func (uc *AuthUseCase) startLogin(ctx context.Context, user *entity.User) (*entity.LoginResult, error) {
+	challenge, err := uc.mfaChallenge(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		// The counter is only reset once the second factor is passed too,
+		// otherwise the password would allow unlimited code guesses.
+		return &entity.LoginResult{User: user, MFA: challenge}, nil
+	}
+
+	return uc.finishLogin(ctx, user)
+}
+
+// finishLogin starts a new session for a fully authenticated user.
+func (uc *AuthUseCase) finishLogin(ctx context.Context, user *entity.User) (*entity.LoginResult, error) {
+	if err := uc.attemptRepo.Reset(ctx, entity.UsernameAttemptKey(strings.ToLower(user.Username))); err != nil {
+		return nil, err
+	}
+
+	familyID, err := securetoken.New()
+	if err != nil {
+		return nil, err
+	}
+
+	pair, err := uc.issueTokens(ctx, user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.LoginResult{Tokens: pair, User: user}, nil
+}
+
+// dummyPasswordHash is compared against when the username does not exist, so
+// that such attempts take as long as a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+func (uc *AuthUseCase) authenticate(ctx context.Context, username, password string) (*entity.User, error) {
 	user, err := uc.userRepo.GetByUsername(ctx, username)
+	if err == entity.ErrInvalidCredentials {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, err
+	}
 	if err != nil {
-		return "", entity.ErrInvalidCredentials
+		return nil, err
+	}
+
+	// Accounts created through SSO have no password to log in with.
+	if user.Password == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return nil, entity.ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", entity.ErrInvalidCredentials
+		return nil, entity.ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// loginAttempt is an attempt that was counted as failed for its username and
+// client IP before the credentials were checked.
+type loginAttempt struct {
+	now            time.Time
+	userKey, ipKey string
+	user, ip       *entity.LoginAttempts
+}
+
+// reserveAttempt counts an attempt against the username and the client IP
+// before anything is checked. While either has to wait, nothing is counted
+// and a LoginThrottledError is returned.
+func (uc *AuthUseCase) reserveAttempt(ctx context.Context, userKey, ipKey string) (*loginAttempt, error) {
+	attempt := &loginAttempt{now: time.Now(), userKey: userKey, ipKey: ipKey}
+
+	user, wait, err := uc.attemptRepo.Reserve(ctx, userKey, attempt.now, uc.settings.UserThrottle)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &entity.LoginThrottledError{RetryAfter: wait}
 	}
 
-	token, err := uc.jwtManager.Generate(user.Username, user.Role)
+	ip, wait, err := uc.attemptRepo.Reserve(ctx, ipKey, attempt.now, uc.settings.IPThrottle)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
+	}
+	if wait > 0 {
+		if err := uc.attemptRepo.Release(ctx, userKey); err != nil {
+			return nil, err
+		}
+		return nil, &entity.LoginThrottledError{RetryAfter: wait}
 	}
 
-	return token, nil
+	attempt.user, attempt.ip = user, ip
+	return attempt, nil
 }
 
-func (uc *AuthUseCase) Register(ctx context.Context, username, password string, role entity.Role) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// attemptFailed returns the error for a reserved attempt that failed:
+// ErrInvalidCredentials, or a LoginThrottledError if this failure made the
+// username or IP wait.
+func (uc *AuthUseCase) attemptFailed(attempt *loginAttempt) error {
+	wait := max(uc.settings.UserThrottle.RetryAfter(attempt.user, attempt.now), uc.settings.IPThrottle.RetryAfter(attempt.ip, attempt.now))
+	if wait > 0 {
+		return &entity.LoginThrottledError{RetryAfter: wait}
+	}
+
+	return entity.ErrInvalidCredentials
+}
+
+// releaseAttempt takes back a reserved attempt that did not fail. The
+// username counter is cleared by finishLogin once the whole login succeeds.
+func (uc *AuthUseCase) releaseAttempt(ctx context.Context, attempt *loginAttempt) error {
+	if err := uc.attemptRepo.Release(ctx, attempt.userKey); err != nil {
+		return err
+	}
+
+	return uc.attemptRepo.Release(ctx, attempt.ipKey)
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already rotated
+// means it leaked, so the whole session it belongs to is revoked.
+func (uc *AuthUseCase) Refresh(ctx context.Context, refreshToken string) (*entity.TokenPair, *entity.User, error) {
+	var (
+		pair   *entity.TokenPair
+		user   *entity.User
+		reused bool
+	)
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.tokenRepo.GetRefreshTokenForUpdate(ctx, securetoken.Hash(refreshToken))
+		if err != nil {
+			return err
+		}
+
+		if current.RevokedAt != nil {
+			reused = true
+			return uc.tokenRepo.RevokeFamily(ctx, current.FamilyID)
+		}
+
+		if !current.IsActive(time.Now()) {
+			return entity.ErrInvalidToken
+		}
+
+		user, err = uc.userRepo.GetByID(ctx, current.UserID)
+		if err != nil {
+			return err
+		}
+
+		if user.IsDisabled() {
+			return entity.ErrInvalidToken
+		}
+		uc.resolvePermissions(user)
+
+		if err := uc.tokenRepo.RevokeRefreshToken(ctx, current.ID); err != nil {
+			return err
+		}
+
+		pair, err = uc.issueTokens(ctx, user, current.FamilyID)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		if err == entity.ErrInvalidToken || err == entity.ErrUserNotFound {
+			return nil, nil, entity.ErrInvalidToken
+		}
+		return nil, nil, fmt.Errorf("failed to refresh token: %w", err)
 	}
 
-	user := &entity.User{
-		Username: username,
-		Password: string(hashedPassword),
-		Role:     role,
+	if reused {
+		return nil, nil, entity.ErrInvalidToken
+	}
+
+	return pair, user, nil
+}
+
+// Logout ends the session of the given refresh token and revokes the access
+// token presented with the request. Either may be empty or already invalid.
+func (uc *AuthUseCase) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	if accessToken != "" {
+		if claims, err := uc.jwtManager.Verify(ctx, accessToken); err == nil {
+			if err := uc.tokenRepo.DenyAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+				return fmt.Errorf("failed to logout: %w", err)
+			}
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.tokenRepo.GetRefreshTokenForUpdate(ctx, securetoken.Hash(refreshToken))
+		if err != nil {
+			return err
+		}
+
+		return uc.tokenRepo.RevokeFamily(ctx, current.FamilyID)
+	})
+	if err != nil && err != entity.ErrInvalidToken {
+		return fmt.Errorf("failed to logout: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeSessions logs a user out everywhere: all refresh tokens and every
+// access token issued with them stop working immediately.
+func (uc *AuthUseCase) RevokeSessions(ctx context.Context, userID int) error {
+	if _, err := uc.userRepo.GetByID(ctx, userID); err != nil {
+		return err
 	}
 
-	if err := uc.userRepo.Create(ctx, user); err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+	if err := uc.tokenRepo.RevokeUserSessions(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
 	}
 
 	return nil
 }
 
+func (uc *AuthUseCase) issueTokens(ctx context.Context, user *entity.User, familyID string) (*entity.TokenPair, error) {
+	access, err := uc.jwtManager.Generate(user.Username, user.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	refresh, err := securetoken.New()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &entity.RefreshToken{
+		UserID:          user.ID,
+		TokenHash:       securetoken.Hash(refresh),
+		FamilyID:        familyID,
+		AccessJTI:       access.ID,
+		AccessExpiresAt: access.ExpiresAt,
+		ExpiresAt:       time.Now().Add(uc.settings.RefreshTTL),
+	}
+
+	if err := uc.tokenRepo.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	return &entity.TokenPair{
+		AccessToken:      access.Value,
+		AccessExpiresAt:  access.ExpiresAt,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// Register is the public sign-up. With an invitation code the account gets the
+// role bound to the invitation; without one it is only allowed when public
+// registration is enabled, and then only as a viewer.
+func (uc *AuthUseCase) Register(ctx context.Context, username, password string, role entity.Role, inviteCode string, meta *entity.AuditMeta) (*entity.User, error) {
+	if inviteCode != "" {
+		return uc.registerWithInvitation(ctx, username, password, inviteCode, meta)
+	}
+
+	if uc.settings.PublicRegistration != entity.RegistrationViewer {
+		return nil, entity.ErrRegistrationClosed
+	}
+
+	if role != "" && role != entity.RoleViewer {
+		return nil, entity.ErrForbidden
+	}
+
+	return uc.createUser(ctx, username, password, entity.RoleViewer, false, meta)
+}
+
+func (uc *AuthUseCase) registerWithInvitation(ctx context.Context, username, password, code string, meta *entity.AuditMeta) (*entity.User, error) {
+	var user *entity.User
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		invitation, err := uc.invitationRepo.GetByCodeHashForUpdate(ctx, securetoken.Hash(code))
+		if err != nil {
+			return err
+		}
+
+		if !invitation.IsUsable(time.Now()) {
+			return entity.ErrInvalidInvitation
+		}
+
+		user, err = uc.createUser(ctx, username, password, invitation.Role, false, meta)
+		if err != nil {
+			return err
+		}
+
+		return uc.invitationRepo.MarkUsed(ctx, invitation.ID, user.Username)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CreateUser lets an admin create an account with any role. The user has to
+// replace the admin-chosen password on first login.
+func (uc *AuthUseCase) CreateUser(ctx context.Context, username, password string, role entity.Role, meta *entity.AuditMeta) (*entity.User, error) {
+	if !uc.settings.Permissions.HasRole(role) {
+		return nil, entity.ErrInvalidRole
+	}
+
+	return uc.createUser(ctx, username, password, role, true, meta)
+}
+
+// CreateInvitation issues a one-time registration code bound to role. The
+// plain code is returned only here; just its hash is stored.
+func (uc *AuthUseCase) CreateInvitation(ctx context.Context, role entity.Role, createdBy string) (*entity.Invitation, string, error) {
+	if !uc.settings.Permissions.HasRole(role) {
+		return nil, "", entity.ErrInvalidRole
+	}
+
+	code, err := securetoken.New()
+	if err != nil {
+		return nil, "", err
+	}
+
+	invitation := &entity.Invitation{
+		Role:      role,
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(uc.settings.InvitationTTL),
+	}
+
+	if err := uc.invitationRepo.Create(ctx, invitation, securetoken.Hash(code)); err != nil {
+		return nil, "", fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return invitation, code, nil
+}
+
+func (uc *AuthUseCase) createUser(
+	ctx context.Context,
+	username, password string,
+	role entity.Role,
+	changeRequired bool,
+	meta *entity.AuditMeta,
+) (*entity.User, error) {
+	if err := uc.settings.PasswordPolicy.Validate(password, username); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &entity.User{
+		Username:               username,
+		Password:               hashedPassword,
+		Role:                   role,
+		PasswordChangeRequired: changeRequired,
+	}
+
+	err = uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+
+		return uc.userHistory.Record(ctx, entity.NewUserHistory(user.ID, entity.UserActionCreate, nil, user, meta))
+	})
+	if err != nil {
+		if err == entity.ErrUserExists {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
 func (uc *AuthUseCase) GetUserInfo(ctx context.Context, username string) (*entity.User, error) {
 	user, err := uc.userRepo.GetByUsername(ctx, username)
 	if err != nil {
@@ -68,3 +495,222 @@ func (uc *AuthUseCase) GetUserInfo(ctx context.Context, username string) (*entit
 
 	return user, nil
 }
+
+// GetActiveUser loads the account behind an access token. Tokens of disabled
+// or deleted accounts must stop working before they expire.
+func (uc *AuthUseCase) GetActiveUser(ctx context.Context, username string) (*entity.User, error) {
+	user, err := uc.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if err == entity.ErrInvalidCredentials {
+			return nil, entity.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, entity.ErrUserDisabled
+	}
+	uc.resolvePermissions(user)
+
+	return user, nil
+}
+
+// resolvePermissions fills in what the user's role grants. A role missing from
+// the matrix grants nothing.
+func (uc *AuthUseCase) resolvePermissions(user *entity.User) {
+	user.Permissions = uc.settings.Permissions.Permissions(user.Role)
+}
+
+func (uc *AuthUseCase) ListUsers(ctx context.Context, filter *entity.UserFilter) ([]*entity.User, int, error) {
+	return uc.userRepo.GetAll(ctx, filter)
+}
+
+func (uc *AuthUseCase) GetUser(ctx context.Context, id int) (*entity.User, error) {
+	return uc.userRepo.GetByID(ctx, id)
+}
+
+func (uc *AuthUseCase) GetUserHistory(ctx context.Context, id, limit, offset int) ([]*entity.UserHistory, int, error) {
+	return uc.userHistory.GetByUserID(ctx, id, limit, offset)
+}
+
+func (uc *AuthUseCase) UpdateRole(ctx context.Context, id int, role entity.Role, meta *entity.AuditMeta) (*entity.User, error) {
+	if !uc.settings.Permissions.HasRole(role) {
+		return nil, entity.ErrInvalidRole
+	}
+
+	return uc.changeUser(ctx, id, entity.UserActionUpdateRole, meta, func(ctx context.Context, user *entity.User) (bool, error) {
+		if user.Role == role {
+			return false, nil
+		}
+
+		return true, uc.userRepo.UpdateRole(ctx, id, role)
+	})
+}
+
+// SetDisabled blocks or unblocks an account. Blocking also ends all of its
+// sessions.
+func (uc *AuthUseCase) SetDisabled(ctx context.Context, id int, disabled bool, meta *entity.AuditMeta) (*entity.User, error) {
+	action := entity.UserActionEnable
+	if disabled {
+		action = entity.UserActionDisable
+	}
+
+	return uc.changeUser(ctx, id, action, meta, func(ctx context.Context, user *entity.User) (bool, error) {
+		if user.IsDisabled() == disabled {
+			return false, nil
+		}
+
+		if err := uc.userRepo.SetDisabled(ctx, id, disabled); err != nil {
+			return false, err
+		}
+
+		if disabled {
+			return true, uc.tokenRepo.RevokeUserSessions(ctx, id)
+		}
+
+		return true, nil
+	})
+}
+
+// UnlockUser clears the failed login counter of an account, lifting a lockout
+// before it expires. Lockouts of client IPs are left alone.
+func (uc *AuthUseCase) UnlockUser(ctx context.Context, id int, meta *entity.AuditMeta) (*entity.User, error) {
+	return uc.changeUser(ctx, id, entity.UserActionUnlock, meta, func(ctx context.Context, user *entity.User) (bool, error) {
+		return true, uc.attemptRepo.Reset(ctx, entity.UsernameAttemptKey(strings.ToLower(user.Username)))
+	})
+}
+
+// ResetMFA removes a user's second factor, e.g. after a lost phone with no
+// recovery codes left. Roles that require MFA enroll again on the next login.
+func (uc *AuthUseCase) ResetMFA(ctx context.Context, id int, meta *entity.AuditMeta) (*entity.User, error) {
+	return uc.changeUser(ctx, id, entity.UserActionResetMFA, meta, func(ctx context.Context, user *entity.User) (bool, error) {
+		_, err := uc.mfaRepo.GetByUserID(ctx, id)
+		if err == entity.ErrMFANotEnrolled {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if err := uc.mfaRepo.Delete(ctx, id); err != nil {
+			return false, err
+		}
+
+		return true, uc.tokenRepo.RevokeUserSessions(ctx, id)
+	})
+}
+
+// ResetPassword sets a new password chosen by an admin and logs the user out
+// everywhere. The user has to replace it on the next login.
+func (uc *AuthUseCase) ResetPassword(ctx context.Context, id int, password string, meta *entity.AuditMeta) (*entity.User, error) {
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// A rejected password must not cost a bcrypt run.
+	if err := uc.settings.PasswordPolicy.Validate(password, user.Username); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.changeUser(ctx, id, entity.UserActionResetPassword, meta, func(ctx context.Context, _ *entity.User) (bool, error) {
+		return true, uc.setPassword(ctx, id, hashedPassword, true)
+	})
+}
+
+func (uc *AuthUseCase) DeleteUser(ctx context.Context, id int, meta *entity.AuditMeta) error {
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := uc.userRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if user.Username == meta.Username {
+			return entity.ErrSelfModification
+		}
+
+		// Access tokens are denylisted through the refresh token rows, so
+		// they have to be revoked before the rows are cascaded away.
+		if err := uc.tokenRepo.RevokeUserSessions(ctx, id); err != nil {
+			return err
+		}
+
+		if err := uc.userRepo.Delete(ctx, id); err != nil {
+			return err
+		}
+
+		return uc.userHistory.Record(ctx, entity.NewUserHistory(id, entity.UserActionDelete, user, nil, meta))
+	})
+	if err != nil {
+		if err == entity.ErrUserNotFound || err == entity.ErrSelfModification {
+			return err
+		}
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
+}
+
+// changeUser runs an admin change of one account in a transaction and records
+// it in the user history. apply reports whether anything was changed; no-ops
+// are not recorded. Admins cannot change their own account this way, so there
+// is always at least one active admin left.
+func (uc *AuthUseCase) changeUser(
+	ctx context.Context,
+	id int,
+	action entity.UserAction,
+	meta *entity.AuditMeta,
+	apply func(ctx context.Context, user *entity.User) (bool, error),
+) (*entity.User, error) {
+	var updated *entity.User
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.userRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if current.Username == meta.Username && !allowedOnSelf(action) {
+			return entity.ErrSelfModification
+		}
+
+		changed, err := apply(ctx, current)
+		if err != nil {
+			return err
+		}
+
+		if !changed {
+			updated = current
+			return nil
+		}
+
+		updated, err = uc.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return uc.userHistory.Record(ctx, entity.NewUserHistory(id, action, current, updated, meta))
+	})
+	if err != nil {
+		if err == entity.ErrUserNotFound || err == entity.ErrSelfModification || errors.Is(err, entity.ErrWeakPassword) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return updated, nil
+}
+
+func allowedOnSelf(action entity.UserAction) bool {
+	switch action {
+	case entity.UserActionResetPassword, entity.UserActionUnlock, entity.UserActionChangePassword:
+		return true
+	default:
+		return false
+	}
+}
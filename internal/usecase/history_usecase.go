@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
@@ -10,28 +11,203 @@ import (
 
 type HistoryUseCase struct {
 	historyRepo repository.HistoryRepository
+	itemUseCase *ItemUseCase
 }
 
-func NewHistoryUseCase(historyRepo repository.HistoryRepository) *HistoryUseCase {
+func NewHistoryUseCase(historyRepo repository.HistoryRepository, itemUseCase *ItemUseCase) *HistoryUseCase {
 	return &HistoryUseCase{
 		historyRepo: historyRepo,
+		itemUseCase: itemUseCase,
 	}
 }
 
-func (uc *HistoryUseCase) GetByItemID(ctx context.Context, itemID int) ([]*entity.ItemHistory, error) {
-	history, err := uc.historyRepo.GetByItemID(ctx, itemID)
+func (uc *HistoryUseCase) GetByItemID(ctx context.Context, itemID int, filter *entity.HistoryFilter) (*entity.HistoryPage, error) {
+	filter.ItemID = &itemID
+
+	return uc.GetAll(ctx, filter)
+}
+
+func (uc *HistoryUseCase) GetAll(ctx context.Context, filter *entity.HistoryFilter) (*entity.HistoryPage, error) {
+	limit := filter.Limit
+	if limit > 0 {
+		// Fetch one extra row to find out whether another page exists.
+		filter.Limit = limit + 1
+		defer func() { filter.Limit = limit }()
+	}
+
+	history, err := uc.historyRepo.GetAll(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get history: %w", err)
 	}
 
-	return history, nil
+	if filter.WithChanges {
+		for _, h := range history {
+			h.Changes = entity.DiffItems(h.OldData, h.NewData)
+		}
+	}
+
+	page := &entity.HistoryPage{Items: history}
+	if limit > 0 && len(history) > limit {
+		page.Items = history[:limit]
+		page.NextCursor = entity.NewHistoryCursor(page.Items[limit-1])
+	}
+
+	return page, nil
 }
 
-func (uc *HistoryUseCase) GetAll(ctx context.Context, filter *entity.HistoryFilter) ([]*entity.ItemHistory, error) {
-	history, err := uc.historyRepo.GetAll(ctx, filter)
+// Export streams every history row matching filter, ignoring pagination.
+func (uc *HistoryUseCase) Export(ctx context.Context, filter *entity.HistoryFilter, fn func(*entity.ItemHistory) error) error {
+	filter.Limit = 0
+	filter.Offset = 0
+	filter.Cursor = nil
+
+	err := uc.historyRepo.Stream(ctx, filter, func(h *entity.ItemHistory) error {
+		if filter.WithChanges {
+			h.Changes = entity.DiffItems(h.OldData, h.NewData)
+		}
+		return fn(h)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get history: %w", err)
+		return fmt.Errorf("failed to export history: %w", err)
+	}
+
+	return nil
+}
+
+// Revert rewrites the item to the snapshot stored in a history row. The write
+// goes through ItemUseCase.Update so it is validated and audited like any
+// other edit. With dryRun set only the pending changes are computed.
+func (uc *HistoryUseCase) Revert(ctx context.Context, historyID int, version int, dryRun bool, meta *entity.AuditMeta) (*entity.RevertResult, error) {
+	record, err := uc.historyRepo.GetByID(ctx, historyID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := record.Snapshot()
+	if snapshot == nil {
+		return nil, entity.ErrNothingToRevert
+	}
+
+	current, err := uc.itemUseCase.GetByID(ctx, record.ItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != 0 && current.Version != version {
+		return nil, entity.ErrVersionConflict
+	}
+
+	item := &entity.Item{
+		ID:          current.ID,
+		SKU:         snapshot.SKU,
+		Barcodes:    snapshot.Barcodes,
+		Name:        snapshot.Name,
+		Description: snapshot.Description,
+		Price:       snapshot.Price,
+		Version:     current.Version,
+		CreatedAt:   current.CreatedAt,
+	}
+	item.CopyStock(current)
+
+	// Stock follows physical movements and is never reverted. Snapshots
+	// recorded before items had codes carry none; keep the current ones
+	// rather than clearing them.
+	if item.SKU == "" {
+		item.SKU = current.SKU
+	}
+	if item.Barcodes == nil {
+		item.Barcodes = current.Barcodes
+	}
+
+	result := &entity.RevertResult{
+		Item:    current,
+		Changes: entity.DiffItems(current, item),
+	}
+
+	if dryRun || len(result.Changes) == 0 {
+		return result, nil
+	}
+
+	if meta.Reason == "" {
+		revertMeta := *meta
+		revertMeta.Reason = fmt.Sprintf("revert to history record #%d", historyID)
+		meta = &revertMeta
+	}
+
+	if err := uc.itemUseCase.Update(ctx, item, meta); err != nil {
+		return nil, err
+	}
+
+	result.Item = item
+	result.Applied = true
+
+	return result, nil
+}
+
+var errChainBroken = errors.New("history chain broken")
+
+// VerifyChain recomputes every history hash and checks that each row links to
+// the one before it, then that the chain ends at its signed head. It stops at
+// the first broken link.
+//
+// Keyed hashes and the head catch rows that were changed, inserted or deleted
+// from the end. A head rolled back together with the rows it covered, e.g. by
+// restoring an older copy of the tables, is only caught against an anchor: the
+// Checked and LastHash of an earlier verification. Without one the result
+// says nothing about rows removed that way.
+func (uc *HistoryUseCase) VerifyChain(ctx context.Context, anchor *entity.ChainAnchor) (*entity.ChainVerification, error) {
+	result := &entity.ChainVerification{Valid: true, Anchored: anchor != nil}
+
+	var lastID, lastVersion int
+	head, err := uc.historyRepo.StreamChain(ctx, func(link *entity.ChainLink) error {
+		var reason string
+		switch {
+		case link.Hash == "":
+			reason = "hash is missing"
+		case link.PrevHash != result.LastHash:
+			reason = "previous hash does not match the preceding row"
+		case link.Version < lastVersion:
+			reason = "hash version is older than the preceding row"
+		case link.Hash != link.Expected:
+			reason = "row contents do not match its hash"
+		case anchor != nil && result.Checked+1 == anchor.Count && link.Hash != anchor.Hash:
+			reason = "row does not match the anchor"
+		}
+
+		if reason != "" {
+			result.Valid = false
+			result.BrokenAt = &entity.ChainBreak{ID: link.ID, Reason: reason}
+			return errChainBroken
+		}
+
+		result.Checked++
+		result.LastHash = link.Hash
+		lastID, lastVersion = link.ID, link.Version
+		return nil
+	})
+	if errors.Is(err, errChainBroken) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify history chain: %w", err)
+	}
+
+	var reason string
+	switch {
+	case head == nil:
+		reason = "chain head is missing"
+	case head.MAC != head.Expected:
+		reason = "chain head signature does not match"
+	case result.Checked < head.Count, anchor != nil && result.Checked < anchor.Count:
+		reason = "rows are missing from the end of the chain"
+	case result.Checked != head.Count || result.LastHash != head.LastHash:
+		reason = "chain does not end at its head"
+	}
+
+	if reason != "" {
+		result.Valid = false
+		result.BrokenAt = &entity.ChainBreak{ID: lastID, Reason: reason}
 	}
 
-	return history, nil
+	return result, nil
 }
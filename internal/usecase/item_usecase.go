@@ -3,27 +3,46 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
 	"github.com/yokitheyo/WarehouseControl/internal/domain/repository"
 )
 
 type ItemUseCase struct {
-	itemRepo repository.ItemRepository
+	itemRepo    repository.ItemRepository
+	historyRepo repository.HistoryRepository
+	transactor  repository.Transactor
 }
 
-func NewItemUseCase(itemRepo repository.ItemRepository) *ItemUseCase {
+func NewItemUseCase(
+	itemRepo repository.ItemRepository,
+	historyRepo repository.HistoryRepository,
+	transactor repository.Transactor,
+) *ItemUseCase {
 	return &ItemUseCase{
-		itemRepo: itemRepo,
+		itemRepo:    itemRepo,
+		historyRepo: historyRepo,
+		transactor:  transactor,
 	}
 }
 
-func (uc *ItemUseCase) Create(ctx context.Context, item *entity.Item, username string) error {
+func (uc *ItemUseCase) Create(ctx context.Context, item *entity.Item, meta *entity.AuditMeta) error {
+	item.Normalize()
+	item.Quantity = 0
+	item.Stock = []entity.StockLevel{}
 	if err := item.Validate(); err != nil {
 		return err
 	}
 
-	if err := uc.itemRepo.Create(ctx, item, username); err != nil {
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.itemRepo.Create(ctx, item, meta.Username); err != nil {
+			return err
+		}
+
+		return uc.historyRepo.Record(ctx, entity.NewItemHistory(item.ID, entity.ActionInsert, nil, item, meta))
+	})
+	if err != nil {
 		return fmt.Errorf("failed to create item: %w", err)
 	}
 
@@ -39,31 +58,144 @@ func (uc *ItemUseCase) GetByID(ctx context.Context, id int) (*entity.Item, error
 	return item, nil
 }
 
-func (uc *ItemUseCase) GetAll(ctx context.Context) ([]*entity.Item, error) {
-	items, err := uc.itemRepo.GetAll(ctx)
+// GetByCode resolves a scanned barcode or an SKU to an active item.
+func (uc *ItemUseCase) GetByCode(ctx context.Context, code string) (*entity.Item, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, entity.ErrItemNotFound
+	}
+
+	return uc.itemRepo.GetByCode(ctx, code)
+}
+
+func (uc *ItemUseCase) GetAll(ctx context.Context, filter *entity.ItemFilter) ([]*entity.Item, int, error) {
+	items, total, err := uc.itemRepo.GetAll(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get items: %w", err)
+	}
+
+	return items, total, nil
+}
+
+func (uc *ItemUseCase) GetDeleted(ctx context.Context, filter *entity.ItemFilter) ([]*entity.Item, int, error) {
+	filter.Deleted = true
+
+	items, total, err := uc.itemRepo.GetAll(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get items: %w", err)
+		return nil, 0, fmt.Errorf("failed to get deleted items: %w", err)
 	}
 
-	return items, nil
+	return items, total, nil
 }
 
-func (uc *ItemUseCase) Update(ctx context.Context, item *entity.Item, username string) error {
+// Update overwrites the item. A non-zero item.Version must match the stored
+// version. A write that changes nothing is skipped and leaves no history.
+func (uc *ItemUseCase) Update(ctx context.Context, item *entity.Item, meta *entity.AuditMeta) error {
+	item.Normalize()
 	if err := item.Validate(); err != nil {
 		return err
 	}
 
-	if err := uc.itemRepo.Update(ctx, item, username); err != nil {
-		return err
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.itemRepo.GetByIDForUpdate(ctx, item.ID)
+		if err != nil {
+			return err
+		}
+
+		if item.Version != 0 && current.Version != item.Version {
+			return entity.ErrVersionConflict
+		}
+
+		item.CopyStock(current)
+
+		if item.SameContent(current) {
+			*item = *current
+			return nil
+		}
+
+		item.Version = current.Version
+		item.CreatedAt = current.CreatedAt
+
+		return uc.write(ctx, current, item, meta)
+	})
+}
+
+// Patch applies patch on top of the stored item. A non-zero version must match
+// the stored one.
+func (uc *ItemUseCase) Patch(ctx context.Context, id int, patch *entity.ItemPatch, version int, meta *entity.AuditMeta) (*entity.Item, error) {
+	var item *entity.Item
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.itemRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && current.Version != version {
+			return entity.ErrVersionConflict
+		}
+
+		item = current.Apply(patch)
+		item.Normalize()
+		if err := item.Validate(); err != nil {
+			return err
+		}
+
+		if item.SameContent(current) {
+			return nil
+		}
+
+		return uc.write(ctx, current, item, meta)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return item, nil
 }
 
-func (uc *ItemUseCase) Delete(ctx context.Context, id int, username string) error {
-	if err := uc.itemRepo.Delete(ctx, id, username); err != nil {
+func (uc *ItemUseCase) write(ctx context.Context, current, item *entity.Item, meta *entity.AuditMeta) error {
+	if err := uc.itemRepo.Update(ctx, item, meta.Username); err != nil {
 		return err
 	}
 
-	return nil
+	return uc.historyRepo.Record(ctx, entity.NewItemHistory(item.ID, entity.ActionUpdate, current, item, meta))
+}
+
+func (uc *ItemUseCase) Delete(ctx context.Context, id int, version int, meta *entity.AuditMeta) error {
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.itemRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && current.Version != version {
+			return entity.ErrVersionConflict
+		}
+
+		if err := uc.itemRepo.Delete(ctx, id, current.Version, meta.Username); err != nil {
+			return err
+		}
+
+		return uc.historyRepo.Record(ctx, entity.NewItemHistory(id, entity.ActionDelete, current, nil, meta))
+	})
+}
+
+func (uc *ItemUseCase) Restore(ctx context.Context, id int, meta *entity.AuditMeta) (*entity.Item, error) {
+	var item *entity.Item
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		item, err = uc.itemRepo.Restore(ctx, id, meta.Username)
+		if err != nil {
+			return err
+		}
+
+		return uc.historyRepo.Record(ctx, entity.NewItemHistory(id, entity.ActionRestore, nil, item, meta))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
 }
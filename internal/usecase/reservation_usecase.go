@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/repository"
+)
+
+type ReservationSettings struct {
+	// DefaultTTL applies when a reservation is created without an expiry.
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+type ReservationUseCase struct {
+	reservationRepo repository.ReservationRepository
+	itemRepo        repository.ItemRepository
+	transactor      repository.Transactor
+	settings        ReservationSettings
+}
+
+func NewReservationUseCase(
+	reservationRepo repository.ReservationRepository,
+	itemRepo repository.ItemRepository,
+	transactor repository.Transactor,
+	settings ReservationSettings,
+) *ReservationUseCase {
+	return &ReservationUseCase{
+		reservationRepo: reservationRepo,
+		itemRepo:        itemRepo,
+		transactor:      transactor,
+		settings:        settings,
+	}
+}
+
+// Create holds stock for an order. The item row is locked while the
+// available stock is checked, so concurrent reservations and stock movements
+// cannot together promise more than there is.
+//
+// Reservations do not bump the item version: they are not part of the item
+// an editor sends back, and expiry would otherwise invalidate ETags behind
+// everyone's back.
+func (uc *ReservationUseCase) Create(ctx context.Context, reservation *entity.Reservation, meta *entity.AuditMeta) error {
+	now := time.Now()
+
+	if reservation.Owner == "" {
+		reservation.Owner = meta.Username
+	}
+	if reservation.ExpiresAt.IsZero() {
+		reservation.ExpiresAt = now.Add(uc.settings.DefaultTTL)
+	}
+	// expires_at has no time zone; an offset would be dropped, not applied.
+	reservation.ExpiresAt = reservation.ExpiresAt.UTC()
+	reservation.Status = entity.ReservationActive
+	reservation.CreatedBy = meta.Username
+	reservation.APIKey = meta.APIKey
+	reservation.RequestID = meta.RequestID
+
+	if err := reservation.Validate(now, uc.settings.MaxTTL); err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		item, err := uc.itemRepo.GetByIDForUpdate(ctx, reservation.ItemID)
+		if err != nil {
+			return err
+		}
+
+		if reservation.Quantity > item.Available {
+			return fmt.Errorf("%w: %d available", entity.ErrStockUnavailable, max(item.Available, 0))
+		}
+
+		return uc.reservationRepo.Create(ctx, reservation)
+	})
+}
+
+func (uc *ReservationUseCase) GetByID(ctx context.Context, id int) (*entity.Reservation, error) {
+	return uc.reservationRepo.GetByID(ctx, id)
+}
+
+func (uc *ReservationUseCase) GetAll(ctx context.Context, filter *entity.ReservationFilter) ([]*entity.Reservation, int, error) {
+	if filter.Status != nil && !filter.Status.IsValid() {
+		return nil, 0, fmt.Errorf("%w: unknown status %q", entity.ErrInvalidReservation, *filter.Status)
+	}
+
+	return uc.reservationRepo.GetAll(ctx, filter)
+}
+
+// Release gives the held stock back, e.g. when the order was cancelled or
+// has been shipped.
+func (uc *ReservationUseCase) Release(ctx context.Context, id int, meta *entity.AuditMeta) (*entity.Reservation, error) {
+	var reservation *entity.Reservation
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		reservation, err = uc.reservationRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if reservation.Status != entity.ReservationActive {
+			return entity.ErrReservationClosed
+		}
+
+		return uc.reservationRepo.Release(ctx, reservation, meta.Username, meta.Reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// ReleaseExpired is run periodically. Expired reservations already stop
+// counting against available stock; this only records that they ended.
+func (uc *ReservationUseCase) ReleaseExpired(ctx context.Context) (int, error) {
+	return uc.reservationRepo.ReleaseExpired(ctx)
+}
@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/repository"
+	"github.com/yokitheyo/WarehouseControl/internal/pkg/oidc"
+)
+
+type SSOSettings struct {
+	UsernameClaim string
+	GroupsClaim   string
+	RoleMapping   entity.RoleMapping
+}
+
+// SSOUseCase logs users in through an OpenID Connect provider. Accounts are
+// created on first login and their role follows the provider's claims.
+type SSOUseCase struct {
+	provider    *oidc.Provider
+	userRepo    repository.UserRepository
+	userHistory repository.UserHistoryRepository
+	transactor  repository.Transactor
+	authUseCase *AuthUseCase
+	settings    SSOSettings
+}
+
+func NewSSOUseCase(
+	provider *oidc.Provider,
+	userRepo repository.UserRepository,
+	userHistory repository.UserHistoryRepository,
+	transactor repository.Transactor,
+	authUseCase *AuthUseCase,
+	settings SSOSettings,
+) *SSOUseCase {
+	return &SSOUseCase{
+		provider:    provider,
+		userRepo:    userRepo,
+		userHistory: userHistory,
+		transactor:  transactor,
+		authUseCase: authUseCase,
+		settings:    settings,
+	}
+}
+
+// Start returns the provider URL to send the browser to, and the state that
+// has to be presented again to Finish.
+func (uc *SSOUseCase) Start(ctx context.Context) (string, *entity.SSOLoginState, error) {
+	state, err := oidc.NewState()
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce, err := oidc.NewState()
+	if err != nil {
+		return "", nil, err
+	}
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", nil, err
+	}
+
+	authURL, err := uc.provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", entity.ErrSSOFailed, err)
+	}
+
+	return authURL, &entity.SSOLoginState{State: state, Nonce: nonce, CodeVerifier: verifier}, nil
+}
+
+// Finish redeems the authorization code and starts a session. The provider
+// only stands in for the password: accounts that use MFA, or whose role
+// requires it, get the same challenge as after a password login.
+func (uc *SSOUseCase) Finish(ctx context.Context, code string, state *entity.SSOLoginState, meta *entity.AuditMeta) (*entity.LoginResult, error) {
+	rawIDToken, err := uc.provider.Exchange(ctx, code, state.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrSSOFailed, err)
+	}
+
+	claims, err := uc.provider.VerifyIDToken(ctx, rawIDToken, state.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", entity.ErrSSOFailed, err)
+	}
+
+	identity := &entity.ExternalIdentity{
+		Issuer:   claims.String("iss"),
+		Subject:  claims.String("sub"),
+		Username: claims.String(uc.settings.UsernameClaim),
+		Groups:   claims.Strings(uc.settings.GroupsClaim),
+	}
+	if identity.Subject == "" || identity.Username == "" {
+		return nil, fmt.Errorf("%w: id token has no sub or %s claim", entity.ErrSSOFailed, uc.settings.UsernameClaim)
+	}
+
+	role, ok := uc.settings.RoleMapping.Resolve(identity.Groups)
+	if !ok {
+		return nil, entity.ErrSSONoRole
+	}
+
+	user, err := uc.provision(ctx, identity, role, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, entity.ErrUserDisabled
+	}
+	uc.authUseCase.resolvePermissions(user)
+
+	return uc.authUseCase.startLogin(ctx, user)
+}
+
+// provision returns the account linked to identity, creating it on first
+// login. The role is synced with the provider on every login. A local account
+// that already uses the name is never taken over.
+func (uc *SSOUseCase) provision(ctx context.Context, identity *entity.ExternalIdentity, role entity.Role, meta *entity.AuditMeta) (*entity.User, error) {
+	var user *entity.User
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.userRepo.GetBySSOSubject(ctx, identity.Issuer, identity.Subject)
+		if err == entity.ErrUserNotFound {
+			user = &entity.User{
+				Username:   identity.Username,
+				Role:       role,
+				SSOIssuer:  identity.Issuer,
+				SSOSubject: identity.Subject,
+			}
+			if err := uc.userRepo.Create(ctx, user); err != nil {
+				return err
+			}
+
+			return uc.userHistory.Record(ctx, entity.NewUserHistory(user.ID, entity.UserActionCreate, nil, user, ssoMeta(meta, user)))
+		}
+		if err != nil {
+			return err
+		}
+
+		user = current
+		if current.Role == role {
+			return nil
+		}
+
+		if err := uc.userRepo.UpdateRole(ctx, current.ID, role); err != nil {
+			return err
+		}
+
+		user, err = uc.userRepo.GetByID(ctx, current.ID)
+		if err != nil {
+			return err
+		}
+
+		return uc.userHistory.Record(ctx, entity.NewUserHistory(user.ID, entity.UserActionUpdateRole, current, user, ssoMeta(meta, user)))
+	})
+	if err != nil {
+		if err == entity.ErrUserExists {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to provision sso user: %w", err)
+	}
+
+	return user, nil
+}
+
+// ssoMeta attributes changes made on behalf of the identity provider to the
+// user who logged in.
+func ssoMeta(meta *entity.AuditMeta, user *entity.User) *entity.AuditMeta {
+	m := *meta
+	m.Username = user.Username
+	if m.Reason == "" {
+		m.Reason = "single sign-on"
+	}
+	return &m
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/repository"
+)
+
+type StockUseCase struct {
+	itemRepo     repository.ItemRepository
+	locationRepo repository.LocationRepository
+	stockRepo    repository.StockRepository
+	movementRepo repository.StockMovementRepository
+	historyRepo  repository.HistoryRepository
+	transactor   repository.Transactor
+}
+
+func NewStockUseCase(
+	itemRepo repository.ItemRepository,
+	locationRepo repository.LocationRepository,
+	stockRepo repository.StockRepository,
+	movementRepo repository.StockMovementRepository,
+	historyRepo repository.HistoryRepository,
+	transactor repository.Transactor,
+) *StockUseCase {
+	return &StockUseCase{
+		itemRepo:     itemRepo,
+		locationRepo: locationRepo,
+		stockRepo:    stockRepo,
+		movementRepo: movementRepo,
+		historyRepo:  historyRepo,
+		transactor:   transactor,
+	}
+}
+
+// Move posts a movement to the ledger. A non-zero version must match the
+// stored item version. Transfer movements are rejected: they are posted by
+// transfer orders.
+func (uc *StockUseCase) Move(ctx context.Context, movement *entity.StockMovement, version int, meta *entity.AuditMeta) (*entity.MovementResult, error) {
+	if err := movement.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !movement.Type.IsManual() {
+		return nil, fmt.Errorf("%w: %s movements are posted by transfer orders", entity.ErrInvalidMovement, movement.Type)
+	}
+
+	item, err := uc.post(ctx, movement, version, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.MovementResult{Movement: movement, Item: item}, nil
+}
+
+// post locks the item and applies a validated movement in a transaction,
+// joining the caller's transaction if there is one.
+func (uc *StockUseCase) post(ctx context.Context, movement *entity.StockMovement, version int, meta *entity.AuditMeta) (*entity.Item, error) {
+	var item *entity.Item
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.itemRepo.GetByIDForUpdate(ctx, movement.ItemID)
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && current.Version != version {
+			return entity.ErrVersionConflict
+		}
+
+		item, err = uc.apply(ctx, current, movement, meta)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// SetStock records a stock count: it posts the adjustment that brings the
+// item's stock at the location to quantity. Nothing is posted if the count
+// matches. A count that leaves less on hand than is reserved is refused
+// unless overrideReservations is set.
+func (uc *StockUseCase) SetStock(ctx context.Context, itemID, locationID, quantity, version int, overrideReservations bool, meta *entity.AuditMeta) (*entity.Item, error) {
+	if quantity < 0 {
+		return nil, entity.ErrInvalidQuantity
+	}
+
+	var item *entity.Item
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.itemRepo.GetByIDForUpdate(ctx, itemID)
+		if err != nil {
+			return err
+		}
+
+		if version != 0 && current.Version != version {
+			return entity.ErrVersionConflict
+		}
+
+		if _, err := uc.locationRepo.GetByID(ctx, locationID); err != nil {
+			return err
+		}
+
+		level, err := uc.stockRepo.GetLevel(ctx, itemID, locationID)
+		if err != nil {
+			return err
+		}
+
+		if level == quantity {
+			item = current
+			return nil
+		}
+
+		reason := entity.ReasonStocktake
+		if overrideReservations {
+			reason = entity.ReasonStocktakeOverride
+		}
+
+		item, err = uc.apply(ctx, current, &entity.StockMovement{
+			ItemID:     itemID,
+			LocationID: locationID,
+			Type:       entity.MovementAdjustment,
+			Delta:      quantity - level,
+			ReasonCode: reason,
+		}, meta)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (uc *StockUseCase) GetMovements(ctx context.Context, itemID, limit, offset int) ([]*entity.StockMovement, int, error) {
+	if _, err := uc.itemRepo.GetByID(ctx, itemID); err != nil {
+		return nil, 0, err
+	}
+
+	movements, total, err := uc.movementRepo.GetByItemID(ctx, itemID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get stock movements: %w", err)
+	}
+
+	return movements, total, nil
+}
+
+// apply writes a movement for current, which the caller has locked, and keeps
+// the stock level, the item version and history in step with it. It must run
+// inside a transaction.
+func (uc *StockUseCase) apply(ctx context.Context, current *entity.Item, movement *entity.StockMovement, meta *entity.AuditMeta) (*entity.Item, error) {
+	if _, err := uc.locationRepo.GetByID(ctx, movement.LocationID); err != nil {
+		return nil, err
+	}
+
+	level, err := uc.stockRepo.GetLevel(ctx, movement.ItemID, movement.LocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	movement.BalanceAfter = level + movement.Delta
+	if movement.BalanceAfter < 0 {
+		return nil, entity.ErrInsufficientStock
+	}
+
+	// Reserved stock has been promised to orders, so a decrease may not eat
+	// into it. current is locked, so no reservation can appear meanwhile.
+	if movement.Delta < 0 && !movement.OverridesReservations() {
+		if onHand := current.OnHand + movement.Delta; onHand < current.Reserved {
+			return nil, fmt.Errorf("%w: %d reserved, %d would remain on hand",
+				entity.ErrStockUnavailable, current.Reserved, onHand)
+		}
+	}
+
+	movement.Username = meta.Username
+	movement.APIKey = meta.APIKey
+	movement.RequestID = meta.RequestID
+
+	if err := uc.movementRepo.Create(ctx, movement); err != nil {
+		return nil, err
+	}
+
+	if err := uc.stockRepo.SetLevel(ctx, movement.ItemID, movement.LocationID, movement.BalanceAfter); err != nil {
+		return nil, err
+	}
+
+	if err := uc.itemRepo.Touch(ctx, movement.ItemID, meta.Username); err != nil {
+		return nil, err
+	}
+
+	item, err := uc.itemRepo.GetByID(ctx, movement.ItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if meta.Reason == "" {
+		movementMeta := *meta
+		movementMeta.Reason = fmt.Sprintf("%s (movement #%d)", movement.Describe(), movement.ID)
+		meta = &movementMeta
+	}
+
+	record := entity.NewItemHistory(movement.ItemID, entity.ActionStock, current, item, meta)
+	record.LocationID = &movement.LocationID
+
+	if err := uc.historyRepo.Record(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
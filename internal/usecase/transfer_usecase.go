@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/repository"
+)
+
+// TransferUseCase runs transfer orders. Dispatch and receipt post their stock
+// movements through StockUseCase in the same transaction as the status
+// change, so an order is never half dispatched or half received.
+type TransferUseCase struct {
+	transferRepo  repository.TransferRepository
+	historyRepo   repository.TransferHistoryRepository
+	itemRepo      repository.ItemRepository
+	warehouseRepo repository.WarehouseRepository
+	locationRepo  repository.LocationRepository
+	stock         *StockUseCase
+	transactor    repository.Transactor
+}
+
+func NewTransferUseCase(
+	transferRepo repository.TransferRepository,
+	historyRepo repository.TransferHistoryRepository,
+	itemRepo repository.ItemRepository,
+	warehouseRepo repository.WarehouseRepository,
+	locationRepo repository.LocationRepository,
+	stock *StockUseCase,
+	transactor repository.Transactor,
+) *TransferUseCase {
+	return &TransferUseCase{
+		transferRepo:  transferRepo,
+		historyRepo:   historyRepo,
+		itemRepo:      itemRepo,
+		warehouseRepo: warehouseRepo,
+		locationRepo:  locationRepo,
+		stock:         stock,
+		transactor:    transactor,
+	}
+}
+
+// Create stores a draft order. Nothing moves until it is dispatched.
+func (uc *TransferUseCase) Create(ctx context.Context, order *entity.TransferOrder, meta *entity.AuditMeta) error {
+	order.Status = entity.TransferDraft
+	order.CreatedBy = meta.Username
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := uc.warehouseRepo.GetByID(ctx, order.SourceWarehouseID); err != nil {
+		return err
+	}
+	if _, err := uc.warehouseRepo.GetByID(ctx, order.DestinationWarehouseID); err != nil {
+		return err
+	}
+
+	for _, line := range order.Lines {
+		if _, err := uc.itemRepo.GetByID(ctx, line.ItemID); err != nil {
+			return err
+		}
+		if err := uc.checkLocation(ctx, line.FromLocationID, order.SourceWarehouseID); err != nil {
+			return err
+		}
+	}
+
+	return uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.transferRepo.Create(ctx, order); err != nil {
+			return err
+		}
+
+		return uc.historyRepo.Record(ctx, entity.NewTransferHistory(order.ID, entity.TransferActionCreate, nil, order, meta))
+	})
+}
+
+func (uc *TransferUseCase) GetByID(ctx context.Context, id int) (*entity.TransferOrder, error) {
+	return uc.transferRepo.GetByID(ctx, id)
+}
+
+func (uc *TransferUseCase) GetAll(ctx context.Context, filter *entity.TransferFilter) ([]*entity.TransferOrder, int, error) {
+	if filter.Status != nil && !filter.Status.IsValid() {
+		return nil, 0, fmt.Errorf("%w: unknown status %q", entity.ErrInvalidTransfer, *filter.Status)
+	}
+
+	return uc.transferRepo.GetAll(ctx, filter)
+}
+
+func (uc *TransferUseCase) GetHistory(ctx context.Context, id, limit, offset int) ([]*entity.TransferHistory, int, error) {
+	if _, err := uc.transferRepo.GetByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+
+	return uc.historyRepo.GetByTransferID(ctx, id, limit, offset)
+}
+
+// Dispatch takes every line out of its source location. If any location is
+// short, nothing is dispatched.
+func (uc *TransferUseCase) Dispatch(ctx context.Context, id int, meta *entity.AuditMeta) (*entity.TransferOrder, error) {
+	return uc.transition(ctx, id, entity.TransferDispatched, entity.TransferActionDispatch, meta, func(ctx context.Context, order *entity.TransferOrder) error {
+		for _, line := range linesByItem(order.Lines) {
+			movement := &entity.StockMovement{
+				ItemID:     line.ItemID,
+				LocationID: line.FromLocationID,
+				Type:       entity.MovementTransferOut,
+				Delta:      -line.Quantity,
+				ReasonCode: entity.ReasonTransfer,
+				Reference:  order.Number,
+			}
+
+			if _, err := uc.stock.post(ctx, movement, 0, meta); err != nil {
+				return fmt.Errorf("line %d: %w", line.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Receive puts what arrived into destination locations. The receipt is final:
+// it covers every line, and whatever did not arrive is written off at once.
+// The full dispatched quantity is booked in and the shortfall is booked out
+// again as a transfer loss, so the ledger accounts for every dispatched unit.
+func (uc *TransferUseCase) Receive(ctx context.Context, id int, receipt []entity.TransferReceiptLine, meta *entity.AuditMeta) (*entity.TransferOrder, error) {
+	return uc.transition(ctx, id, entity.TransferReceived, entity.TransferActionReceive, meta, func(ctx context.Context, order *entity.TransferOrder) error {
+		if err := order.ApplyReceipt(receipt); err != nil {
+			return err
+		}
+
+		for _, line := range linesByItem(order.Lines) {
+			if err := uc.checkLocation(ctx, *line.ToLocationID, order.DestinationWarehouseID); err != nil {
+				return err
+			}
+
+			movements := []*entity.StockMovement{{
+				ItemID:     line.ItemID,
+				LocationID: *line.ToLocationID,
+				Type:       entity.MovementTransferIn,
+				Delta:      line.Quantity,
+				ReasonCode: entity.ReasonTransfer,
+				Reference:  order.Number,
+			}}
+			if line.Discrepancy > 0 {
+				movements = append(movements, &entity.StockMovement{
+					ItemID:     line.ItemID,
+					LocationID: *line.ToLocationID,
+					Type:       entity.MovementTransferLoss,
+					Delta:      -line.Discrepancy,
+					ReasonCode: entity.ReasonTransfer,
+					Reference:  order.Number,
+				})
+			}
+
+			for _, movement := range movements {
+				if _, err := uc.stock.post(ctx, movement, 0, meta); err != nil {
+					return fmt.Errorf("line %d: %w", line.ID, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Cancel drops a draft order. Dispatched goods cannot be cancelled back into
+// stock; they must be received.
+func (uc *TransferUseCase) Cancel(ctx context.Context, id int, meta *entity.AuditMeta) (*entity.TransferOrder, error) {
+	return uc.transition(ctx, id, entity.TransferCancelled, entity.TransferActionCancel, meta, nil)
+}
+
+// transition locks the order, checks that it may move to status, runs apply
+// and stores the new status with a history row, all in one transaction.
+func (uc *TransferUseCase) transition(
+	ctx context.Context,
+	id int,
+	status entity.TransferStatus,
+	action entity.TransferAction,
+	meta *entity.AuditMeta,
+	apply func(ctx context.Context, order *entity.TransferOrder) error,
+) (*entity.TransferOrder, error) {
+	var order *entity.TransferOrder
+
+	err := uc.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := uc.transferRepo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if !current.CanMoveTo(status) {
+			return entity.ErrTransferStatus
+		}
+
+		order = current.Clone()
+		if apply != nil {
+			if err := apply(ctx, order); err != nil {
+				return err
+			}
+		}
+
+		order.Status = status
+		if err := uc.transferRepo.Update(ctx, order); err != nil {
+			return err
+		}
+
+		return uc.historyRepo.Record(ctx, entity.NewTransferHistory(order.ID, action, current, order, meta))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (uc *TransferUseCase) checkLocation(ctx context.Context, locationID, warehouseID int) error {
+	location, err := uc.locationRepo.GetByID(ctx, locationID)
+	if err != nil {
+		return err
+	}
+
+	if location.WarehouseID != warehouseID {
+		return fmt.Errorf("%w: location %d is not in warehouse %d", entity.ErrInvalidTransfer, locationID, warehouseID)
+	}
+
+	return nil
+}
+
+// linesByItem returns the lines ordered by item, so concurrent orders lock
+// item rows in the same order and cannot deadlock.
+func linesByItem(lines []entity.TransferLine) []entity.TransferLine {
+	sorted := append([]entity.TransferLine(nil), lines...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ItemID < sorted[j].ItemID
+	})
+	return sorted
+}
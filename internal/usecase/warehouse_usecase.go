@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/yokitheyo/WarehouseControl/internal/domain/entity"
+	"github.com/yokitheyo/WarehouseControl/internal/domain/repository"
+)
+
+type WarehouseUseCase struct {
+	warehouseRepo repository.WarehouseRepository
+	locationRepo  repository.LocationRepository
+}
+
+func NewWarehouseUseCase(
+	warehouseRepo repository.WarehouseRepository,
+	locationRepo repository.LocationRepository,
+) *WarehouseUseCase {
+	return &WarehouseUseCase{
+		warehouseRepo: warehouseRepo,
+		locationRepo:  locationRepo,
+	}
+}
+
+func (uc *WarehouseUseCase) CreateWarehouse(ctx context.Context, warehouse *entity.Warehouse) error {
+	warehouse.Normalize()
+	if err := warehouse.Validate(); err != nil {
+		return err
+	}
+
+	return uc.warehouseRepo.Create(ctx, warehouse)
+}
+
+func (uc *WarehouseUseCase) GetWarehouses(ctx context.Context) ([]*entity.Warehouse, error) {
+	return uc.warehouseRepo.GetAll(ctx)
+}
+
+func (uc *WarehouseUseCase) CreateLocation(ctx context.Context, location *entity.Location) error {
+	location.Normalize()
+	if err := location.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := uc.warehouseRepo.GetByID(ctx, location.WarehouseID); err != nil {
+		return err
+	}
+
+	return uc.locationRepo.Create(ctx, location)
+}
+
+func (uc *WarehouseUseCase) GetLocations(ctx context.Context, warehouseID *int) ([]*entity.Location, error) {
+	return uc.locationRepo.GetAll(ctx, warehouseID)
+}
@@ -0,0 +1,15 @@
+CREATE INDEX IF NOT EXISTS idx_items_created_at ON items (created_at DESC, id DESC)
+WHERE
+    deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_items_updated_at ON items (updated_at DESC, id DESC)
+WHERE
+    deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_items_price ON items (price, id)
+WHERE
+    deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_items_quantity ON items (quantity, id)
+WHERE
+    deleted_at IS NULL;
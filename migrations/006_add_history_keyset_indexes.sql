@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_items_history_changed_at;
+
+CREATE INDEX IF NOT EXISTS idx_items_history_changed_at_id ON items_history (changed_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_items_history_item_changed_at_id ON items_history (
+    item_id,
+    changed_at DESC,
+    id DESC
+);
@@ -0,0 +1,4 @@
+ALTER TABLE items
+ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN items.version IS 'Версия записи для оптимистичной блокировки. Увеличивается при каждом изменении';
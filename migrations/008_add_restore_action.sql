@@ -0,0 +1,58 @@
+ALTER TABLE items_history
+DROP CONSTRAINT IF EXISTS items_history_action_check;
+
+ALTER TABLE items_history
+ADD CONSTRAINT items_history_action_check CHECK (
+    action IN (
+        'INSERT',
+        'UPDATE',
+        'DELETE',
+        'RESTORE'
+    )
+);
+
+CREATE OR REPLACE FUNCTION log_item_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF (OLD.name, OLD.description, OLD.quantity, OLD.price, OLD.deleted_at) IS DISTINCT FROM 
+       (NEW.name, NEW.description, NEW.quantity, NEW.price, NEW.deleted_at) THEN
+        
+        INSERT INTO items_history (item_id, action, username, old_data, new_data)
+        VALUES (
+            NEW.id,
+            CASE 
+                WHEN NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN 'DELETE'
+                WHEN NEW.deleted_at IS NULL AND OLD.deleted_at IS NOT NULL THEN 'RESTORE'
+                ELSE 'UPDATE'
+            END,
+            COALESCE(NEW.updated_by, NEW.deleted_by, 'system'),
+            jsonb_build_object(
+                'id', OLD.id,
+                'name', OLD.name,
+                'description', OLD.description,
+                'quantity', OLD.quantity,
+                'price', OLD.price,
+                'created_at', OLD.created_at,
+                'updated_at', OLD.updated_at
+            ),
+            CASE 
+                WHEN NEW.deleted_at IS NULL THEN
+                    jsonb_build_object(
+                        'id', NEW.id,
+                        'name', NEW.name,
+                        'description', NEW.description,
+                        'quantity', NEW.quantity,
+                        'price', NEW.price,
+                        'created_at', NEW.created_at,
+                        'updated_at', NEW.updated_at
+                    )
+                ELSE NULL
+            END
+        );
+    END IF;
+    
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON COLUMN items_history.action IS 'Тип операции: INSERT - создание, UPDATE - обновление, DELETE - удаление, RESTORE - восстановление';
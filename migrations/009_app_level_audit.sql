@@ -0,0 +1,22 @@
+DROP TRIGGER IF EXISTS items_insert_trigger ON items;
+
+DROP TRIGGER IF EXISTS items_update_trigger ON items;
+
+DROP FUNCTION IF EXISTS log_item_insert ();
+
+DROP FUNCTION IF EXISTS log_item_update ();
+
+ALTER TABLE items_history
+ADD COLUMN IF NOT EXISTS request_id VARCHAR(64),
+ADD COLUMN IF NOT EXISTS client_ip VARCHAR(45),
+ADD COLUMN IF NOT EXISTS reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_items_history_request_id ON items_history (request_id);
+
+COMMENT ON TABLE items_history IS 'Таблица истории изменений товаров. Заполняется приложением в той же транзакции, что и изменение товара';
+
+COMMENT ON COLUMN items_history.request_id IS 'Идентификатор HTTP-запроса, в рамках которого выполнено изменение';
+
+COMMENT ON COLUMN items_history.client_ip IS 'IP-адрес клиента, выполнившего изменение';
+
+COMMENT ON COLUMN items_history.reason IS 'Необязательная причина изменения (заголовок X-Change-Reason)';
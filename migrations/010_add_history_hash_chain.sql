@@ -0,0 +1,50 @@
+ALTER TABLE items_history
+ADD COLUMN IF NOT EXISTS prev_hash CHAR(64),
+ADD COLUMN IF NOT EXISTS hash CHAR(64);
+
+-- Хеш строки истории: SHA-256 от содержимого строки и хеша предыдущей строки.
+-- Используется и приложением при записи, и при проверке цепочки.
+CREATE OR REPLACE FUNCTION items_history_hash(h items_history)
+RETURNS TEXT AS $$
+    SELECT encode(
+        sha256(
+            convert_to(
+                concat_ws(
+                    '|',
+                    COALESCE(h.prev_hash, ''),
+                    h.id,
+                    h.item_id,
+                    h.action,
+                    h.username,
+                    COALESCE(h.old_data::text, ''),
+                    COALESCE(h.new_data::text, ''),
+                    COALESCE(h.request_id, ''),
+                    COALESCE(h.client_ip, ''),
+                    COALESCE(h.reason, ''),
+                    to_char(h.changed_at, 'YYYY-MM-DD"T"HH24:MI:SS.US')
+                ),
+                'UTF8'
+            )
+        ),
+        'hex'
+    );
+$$ LANGUAGE sql STABLE;
+
+-- Достраиваем цепочку для уже существующих записей
+DO $$
+DECLARE
+    r RECORD;
+    prev TEXT := NULL;
+BEGIN
+    FOR r IN SELECT id FROM items_history ORDER BY id LOOP
+        UPDATE items_history SET prev_hash = prev WHERE id = r.id;
+        UPDATE items_history h SET hash = items_history_hash(h) WHERE h.id = r.id
+        RETURNING h.hash INTO prev;
+    END LOOP;
+END $$;
+
+COMMENT ON COLUMN items_history.prev_hash IS 'Хеш предыдущей записи истории (NULL для первой записи)';
+
+COMMENT ON COLUMN items_history.hash IS 'SHA-256 содержимого записи и prev_hash. Позволяет обнаружить правку истории в обход приложения';
+
+COMMENT ON FUNCTION items_history_hash (items_history) IS 'Вычисляет хеш записи истории для цепочки целостности';
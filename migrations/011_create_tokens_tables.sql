@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    access_jti VARCHAR(64) NOT NULL,
+    access_expires_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW (),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+COMMENT ON TABLE refresh_tokens IS 'Refresh-токены. Хранится только SHA-256 хеш токена. Токены одной сессии объединены family_id и ротируются при каждом обновлении';
+
+COMMENT ON COLUMN refresh_tokens.access_jti IS 'jti access-токена, выданного вместе с этим refresh-токеном. Нужен для отзыва всех сессий пользователя';
+
+COMMENT ON TABLE revoked_tokens IS 'Список отозванных access-токенов (по jti) до истечения их срока действия';
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    id SERIAL PRIMARY KEY,
+    code_hash CHAR(64) UNIQUE NOT NULL,
+    role VARCHAR(50) NOT NULL CHECK (
+        role IN ('admin', 'manager', 'viewer')
+    ),
+    created_by VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    used_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW ()
+);
+
+COMMENT ON TABLE invitations IS 'Одноразовые коды приглашения. Администратор выбирает роль заранее, хранится только SHA-256 хеш кода';
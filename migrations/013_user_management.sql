@@ -0,0 +1,37 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS users_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (
+        action IN (
+            'CREATE',
+            'UPDATE_ROLE',
+            'DISABLE',
+            'ENABLE',
+            'RESET_PASSWORD',
+            'DELETE'
+        )
+    ),
+    username VARCHAR(255) NOT NULL,
+    old_data JSONB,
+    new_data JSONB,
+    request_id VARCHAR(64),
+    client_ip VARCHAR(45),
+    reason TEXT,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_users_history_user_id ON users_history (user_id, changed_at DESC, id DESC);
+
+COMMENT ON COLUMN users.disabled_at IS 'Время блокировки учётной записи. Заблокированный пользователь не может войти, его токены отклоняются';
+
+COMMENT ON TABLE users_history IS 'Журнал изменений учётных записей пользователей администраторами';
+
+COMMENT ON COLUMN users_history.user_id IS 'ID пользователя, над которым выполнено действие. Без внешнего ключа, чтобы журнал переживал удаление пользователя';
+
+COMMENT ON COLUMN users_history.username IS 'Пользователь, выполнивший действие';
+
+COMMENT ON COLUMN users_history.old_data IS 'Состояние учётной записи до изменения (без пароля)';
+
+COMMENT ON COLUMN users_history.new_data IS 'Состояние учётной записи после изменения (без пароля)';
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+
+ALTER TABLE invitations DROP CONSTRAINT IF EXISTS invitations_role_check;
+
+COMMENT ON COLUMN users.role IS 'Роль пользователя. Набор ролей и их права задаются в секции permissions конфигурации (по умолчанию admin, manager, viewer)';
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_login_attempts_last_failure ON login_attempts (last_failure);
+
+ALTER TABLE users_history DROP CONSTRAINT IF EXISTS users_history_action_check;
+
+ALTER TABLE users_history ADD CONSTRAINT users_history_action_check CHECK (
+    action IN (
+        'CREATE',
+        'UPDATE_ROLE',
+        'DISABLE',
+        'ENABLE',
+        'RESET_PASSWORD',
+        'DELETE',
+        'UNLOCK'
+    )
+);
+
+COMMENT ON TABLE login_attempts IS 'Счётчики неудачных попыток входа для защиты от перебора. Используется при auth.login_throttle.store = postgres, чтобы счётчики были общими для всех экземпляров приложения';
+
+COMMENT ON COLUMN login_attempts.key IS 'Ключ счётчика: user:<имя пользователя> или ip:<адрес клиента>';
@@ -0,0 +1,59 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) UNIQUE NOT NULL,
+    permissions TEXT[] NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW (),
+    revoked_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_api_keys_user_name ON api_keys (user_id, name)
+WHERE
+    revoked_at IS NULL;
+
+ALTER TABLE items_history ADD COLUMN IF NOT EXISTS api_key_name VARCHAR(100);
+
+ALTER TABLE users_history ADD COLUMN IF NOT EXISTS api_key_name VARCHAR(100);
+
+-- api_key_name добавлен в конец: concat_ws пропускает NULL, поэтому хеши
+-- старых записей (и записей без ключа) не меняются
+CREATE OR REPLACE FUNCTION items_history_hash(h items_history)
+RETURNS TEXT AS $$
+    SELECT encode(
+        sha256(
+            convert_to(
+                concat_ws(
+                    '|',
+                    COALESCE(h.prev_hash, ''),
+                    h.id,
+                    h.item_id,
+                    h.action,
+                    h.username,
+                    COALESCE(h.old_data::text, ''),
+                    COALESCE(h.new_data::text, ''),
+                    COALESCE(h.request_id, ''),
+                    COALESCE(h.client_ip, ''),
+                    COALESCE(h.reason, ''),
+                    to_char(h.changed_at, 'YYYY-MM-DD"T"HH24:MI:SS.US'),
+                    h.api_key_name
+                ),
+                'UTF8'
+            )
+        ),
+        'hex'
+    );
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON TABLE api_keys IS 'Персональные API-ключи пользователей для скриптов и интеграций. Хранится только SHA-256 хеш ключа';
+
+COMMENT ON COLUMN api_keys.prefix IS 'Начало ключа, по которому пользователь узнаёт ключ в списке';
+
+COMMENT ON COLUMN api_keys.permissions IS 'Права ключа. Действуют только те, что есть и у роли владельца';
+
+COMMENT ON COLUMN items_history.api_key_name IS 'Имя API-ключа, которым выполнено изменение (username - владелец ключа)';
+
+COMMENT ON COLUMN users_history.api_key_name IS 'Имя API-ключа, которым выполнено изменение (username - владелец ключа)';
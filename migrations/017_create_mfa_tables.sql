@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMP,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW ()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+ALTER TABLE users_history DROP CONSTRAINT IF EXISTS users_history_action_check;
+
+ALTER TABLE users_history ADD CONSTRAINT users_history_action_check CHECK (
+    action IN (
+        'CREATE',
+        'UPDATE_ROLE',
+        'DISABLE',
+        'ENABLE',
+        'RESET_PASSWORD',
+        'DELETE',
+        'UNLOCK',
+        'RESET_MFA'
+    )
+);
+
+COMMENT ON TABLE user_mfa IS 'TOTP (RFC 6238) для двухфакторной аутентификации. Пока enabled_at пустой, подключение не подтверждено и при входе не используется';
+
+COMMENT ON COLUMN user_mfa.last_step IS 'Номер последнего принятого 30-секундного интервала. Не даёт использовать один код дважды';
+
+COMMENT ON TABLE mfa_recovery_codes IS 'Одноразовые коды восстановления на случай потери устройства. Хранится только SHA-256 хеш кода';
@@ -0,0 +1,37 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS password_change_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Учётные записи из 001 с общим известным паролем должны сменить его при первом входе
+UPDATE users
+SET password_change_required = TRUE
+WHERE password = '$2a$12$eRAdXDJHw5QBFcTVhfJesOsqpFvYADCy8kRQ21zbcagT9UiJ.8skq';
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_password_resets_user_id ON password_resets (user_id);
+
+ALTER TABLE users_history DROP CONSTRAINT IF EXISTS users_history_action_check;
+
+ALTER TABLE users_history ADD CONSTRAINT users_history_action_check CHECK (
+    action IN (
+        'CREATE',
+        'UPDATE_ROLE',
+        'DISABLE',
+        'ENABLE',
+        'RESET_PASSWORD',
+        'DELETE',
+        'UNLOCK',
+        'RESET_MFA',
+        'CHANGE_PASSWORD'
+    )
+);
+
+COMMENT ON COLUMN users.password_change_required IS 'Пользователь должен сменить пароль, прежде чем сможет работать: начальные учётные записи и пароли, заданные администратором';
+
+COMMENT ON TABLE password_resets IS 'Одноразовые токены восстановления пароля. Хранится только SHA-256 хеш токена';
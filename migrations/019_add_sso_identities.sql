@@ -0,0 +1,14 @@
+ALTER TABLE users ALTER COLUMN password DROP NOT NULL;
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS sso_issuer VARCHAR(255),
+ADD COLUMN IF NOT EXISTS sso_subject VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_sso_identity ON users (sso_issuer, sso_subject)
+WHERE sso_subject IS NOT NULL;
+
+COMMENT ON COLUMN users.password IS 'bcrypt-хеш пароля. Пустой у пользователей, входящих только через SSO';
+
+COMMENT ON COLUMN users.sso_issuer IS 'Issuer провайдера OpenID Connect, через который создан пользователь';
+
+COMMENT ON COLUMN users.sso_subject IS 'Claim sub пользователя у провайдера. Вместе с issuer однозначно связывает учётную запись с внешней';
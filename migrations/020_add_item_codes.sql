@@ -0,0 +1,27 @@
+ALTER TABLE items
+ADD COLUMN IF NOT EXISTS sku VARCHAR(64);
+
+-- Существующим товарам выдаём временный артикул, его можно заменить на артикул из ERP
+UPDATE items
+SET
+    sku = 'ITEM-' || LPAD(id::TEXT, 6, '0')
+WHERE
+    sku IS NULL;
+
+ALTER TABLE items ALTER COLUMN sku SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_items_sku ON items (sku);
+
+CREATE TABLE IF NOT EXISTS item_barcodes (
+    barcode VARCHAR(48) PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_barcodes_item_id ON item_barcodes (item_id);
+
+COMMENT ON COLUMN items.sku IS 'Артикул товара (SKU) в верхнем регистре. Уникален среди всех товаров, включая удалённые, чтобы восстановление не давало конфликтов';
+
+COMMENT ON TABLE item_barcodes IS 'Штрихкоды товаров (EAN-8, UPC-A, EAN-13, GTIN-14, Code 128). У товара может быть несколько штрихкодов';
+
+COMMENT ON COLUMN item_barcodes.barcode IS 'Значение штрихкода. Уникально среди всех товаров, включая удалённые';
@@ -0,0 +1,118 @@
+CREATE TABLE IF NOT EXISTS warehouses (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS locations (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses (id),
+    code VARCHAR(64) NOT NULL,
+    zone VARCHAR(32) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT locations_warehouse_code_key UNIQUE (warehouse_id, code)
+);
+
+CREATE TABLE IF NOT EXISTS stock_levels (
+    item_id INTEGER NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+    location_id INTEGER NOT NULL REFERENCES locations (id),
+    quantity INTEGER NOT NULL CHECK (quantity >= 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (item_id, location_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_levels_location_id ON stock_levels (location_id);
+
+-- Переносим текущие остатки на основной склад, после чего количество
+-- товара считается как сумма остатков по ячейкам
+INSERT INTO
+    warehouses (code, name)
+VALUES ('MAIN', 'Основной склад')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO
+    locations (warehouse_id, code)
+SELECT id, 'DEFAULT'
+FROM warehouses
+WHERE
+    code = 'MAIN'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO
+    stock_levels (item_id, location_id, quantity)
+SELECT i.id, l.id, i.quantity
+FROM
+    items i
+    JOIN locations l ON l.code = 'DEFAULT'
+    JOIN warehouses w ON w.id = l.warehouse_id
+    AND w.code = 'MAIN'
+WHERE
+    i.quantity > 0
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS idx_items_quantity;
+
+ALTER TABLE items DROP COLUMN IF EXISTS quantity;
+
+ALTER TABLE items_history
+ADD COLUMN IF NOT EXISTS location_id INTEGER REFERENCES locations (id);
+
+CREATE INDEX IF NOT EXISTS idx_items_history_location_id ON items_history (location_id)
+WHERE
+    location_id IS NOT NULL;
+
+ALTER TABLE items_history
+DROP CONSTRAINT IF EXISTS items_history_action_check;
+
+ALTER TABLE items_history
+ADD CONSTRAINT items_history_action_check CHECK (
+    action IN (
+        'INSERT',
+        'UPDATE',
+        'DELETE',
+        'RESTORE',
+        'STOCK'
+    )
+);
+
+-- location_id добавлен в конец: concat_ws пропускает NULL, поэтому хеши
+-- старых записей не меняются
+CREATE OR REPLACE FUNCTION items_history_hash(h items_history)
+RETURNS TEXT AS $$
+    SELECT encode(
+        sha256(
+            convert_to(
+                concat_ws(
+                    '|',
+                    COALESCE(h.prev_hash, ''),
+                    h.id,
+                    h.item_id,
+                    h.action,
+                    h.username,
+                    COALESCE(h.old_data::text, ''),
+                    COALESCE(h.new_data::text, ''),
+                    COALESCE(h.request_id, ''),
+                    COALESCE(h.client_ip, ''),
+                    COALESCE(h.reason, ''),
+                    to_char(h.changed_at, 'YYYY-MM-DD"T"HH24:MI:SS.US'),
+                    h.api_key_name,
+                    h.location_id
+                ),
+                'UTF8'
+            )
+        ),
+        'hex'
+    );
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON TABLE warehouses IS 'Склады';
+
+COMMENT ON TABLE locations IS 'Места хранения (ячейки) на складах. Зона объединяет ячейки: приёмка, холодильник, ряд стеллажей';
+
+COMMENT ON TABLE stock_levels IS 'Остатки товаров по ячейкам. Количество товара - сумма его остатков';
+
+COMMENT ON COLUMN items_history.location_id IS 'Ячейка, в которой изменился остаток (для действия STOCK)';
+
+COMMENT ON COLUMN items_history.action IS 'Тип операции: INSERT - создание, UPDATE - обновление, DELETE - удаление, RESTORE - восстановление, STOCK - изменение остатка в ячейке';
@@ -0,0 +1,95 @@
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES items (id),
+    location_id INTEGER NOT NULL REFERENCES locations (id),
+    type VARCHAR(20) NOT NULL CHECK (
+        type IN (
+            'receipt',
+            'issue',
+            'adjustment',
+            'write_off',
+            'return'
+        )
+    ),
+    delta INTEGER NOT NULL,
+    balance_after INTEGER NOT NULL CHECK (balance_after >= 0),
+    reason_code VARCHAR(32) NOT NULL,
+    reference VARCHAR(100),
+    username VARCHAR(255) NOT NULL,
+    api_key_name VARCHAR(100),
+    request_id VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT stock_movements_delta_sign_check CHECK (
+        (
+            type IN ('receipt', 'return')
+            AND delta > 0
+        )
+        OR (
+            type IN ('issue', 'write_off')
+            AND delta < 0
+        )
+        OR (
+            type = 'adjustment'
+            AND delta <> 0
+        )
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_item_id ON stock_movements (item_id, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_location_id ON stock_movements (location_id);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_reference ON stock_movements (reference)
+WHERE
+    reference IS NOT NULL;
+
+-- Журнал только дополняется: исправление делается новой корректирующей записью
+CREATE OR REPLACE FUNCTION forbid_stock_movement_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS stock_movements_append_only ON stock_movements;
+
+CREATE TRIGGER stock_movements_append_only
+BEFORE UPDATE OR DELETE ON stock_movements
+FOR EACH ROW EXECUTE FUNCTION forbid_stock_movement_change();
+
+-- Входящие остатки, чтобы остаток в ячейке совпадал с суммой движений
+INSERT INTO
+    stock_movements (
+        item_id,
+        location_id,
+        type,
+        delta,
+        balance_after,
+        reason_code,
+        username
+    )
+SELECT item_id, location_id, 'adjustment', quantity, quantity, 'OPENING_BALANCE', 'system'
+FROM stock_levels
+WHERE
+    quantity > 0
+    AND NOT EXISTS (
+        SELECT 1
+        FROM stock_movements m
+        WHERE
+            m.item_id = stock_levels.item_id
+            AND m.location_id = stock_levels.location_id
+    );
+
+COMMENT ON TABLE stock_movements IS 'Журнал движений остатков (только добавление). Остаток товара в ячейке равен сумме delta его движений';
+
+COMMENT ON COLUMN stock_movements.type IS 'Тип операции: receipt - приход, issue - расход, adjustment - корректировка, write_off - списание, return - возврат';
+
+COMMENT ON COLUMN stock_movements.delta IS 'Изменение остатка со знаком: положительное для прихода и возврата, отрицательное для расхода и списания';
+
+COMMENT ON COLUMN stock_movements.balance_after IS 'Остаток товара в ячейке после движения';
+
+COMMENT ON COLUMN stock_movements.reason_code IS 'Код причины (PURCHASE, SALE, STOCKTAKE, DAMAGE и т.д.), допустимый для типа операции';
+
+COMMENT ON COLUMN stock_movements.reference IS 'Номер документа-основания: накладной, заказа, акта';
+
+COMMENT ON TABLE stock_levels IS 'Остатки товаров по ячейкам: сумма движений из stock_movements, обновляется в той же транзакции';
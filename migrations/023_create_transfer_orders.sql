@@ -0,0 +1,132 @@
+CREATE TABLE IF NOT EXISTS transfer_orders (
+    id SERIAL PRIMARY KEY,
+    source_warehouse_id INTEGER NOT NULL REFERENCES warehouses (id),
+    destination_warehouse_id INTEGER NOT NULL REFERENCES warehouses (id),
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (
+        status IN (
+            'draft',
+            'dispatched',
+            'received',
+            'cancelled'
+        )
+    ),
+    note TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    dispatched_at TIMESTAMP,
+    received_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    CONSTRAINT transfer_orders_warehouses_check CHECK (
+        source_warehouse_id <> destination_warehouse_id
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_orders_status ON transfer_orders (status, id DESC);
+
+CREATE TABLE IF NOT EXISTS transfer_lines (
+    id SERIAL PRIMARY KEY,
+    transfer_id INTEGER NOT NULL REFERENCES transfer_orders (id) ON DELETE CASCADE,
+    item_id INTEGER NOT NULL REFERENCES items (id),
+    from_location_id INTEGER NOT NULL REFERENCES locations (id),
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    to_location_id INTEGER REFERENCES locations (id),
+    received_quantity INTEGER CHECK (
+        received_quantity >= 0
+        AND received_quantity <= quantity
+    ),
+    discrepancy INTEGER NOT NULL DEFAULT 0,
+    discrepancy_note TEXT NOT NULL DEFAULT '',
+    CONSTRAINT transfer_lines_item_location_key UNIQUE (
+        transfer_id,
+        item_id,
+        from_location_id
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_lines_item_id ON transfer_lines (item_id);
+
+CREATE TABLE IF NOT EXISTS transfer_history (
+    id SERIAL PRIMARY KEY,
+    transfer_id INTEGER NOT NULL REFERENCES transfer_orders (id),
+    action VARCHAR(20) NOT NULL CHECK (
+        action IN (
+            'CREATE',
+            'DISPATCH',
+            'RECEIVE',
+            'CANCEL'
+        )
+    ),
+    username VARCHAR(255) NOT NULL,
+    api_key_name VARCHAR(100),
+    old_data JSONB,
+    new_data JSONB,
+    request_id VARCHAR(64),
+    client_ip VARCHAR(45),
+    reason TEXT,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_history_transfer_id ON transfer_history (transfer_id, changed_at DESC, id DESC);
+
+-- Перемещения списывают и приходуют товар своими типами движений
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_type_check;
+
+ALTER TABLE stock_movements
+ADD CONSTRAINT stock_movements_type_check CHECK (
+    type IN (
+        'receipt',
+        'issue',
+        'adjustment',
+        'write_off',
+        'return',
+        'transfer_out',
+        'transfer_in'
+    )
+);
+
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_delta_sign_check;
+
+ALTER TABLE stock_movements
+ADD CONSTRAINT stock_movements_delta_sign_check CHECK (
+    (
+        type IN ('receipt', 'return', 'transfer_in')
+        AND delta > 0
+    )
+    OR (
+        type IN ('issue', 'write_off', 'transfer_out')
+        AND delta < 0
+    )
+    OR (
+        type = 'adjustment'
+        AND delta <> 0
+    )
+);
+
+COMMENT ON TABLE transfer_orders IS 'Заказы на перемещение товара между складами: черновик -> отгружен -> получен, черновик можно отменить';
+
+COMMENT ON COLUMN transfer_orders.status IS 'draft - черновик, dispatched - отгружен и находится в пути, received - получен, cancelled - отменён';
+
+COMMENT ON COLUMN transfer_orders.dispatched_at IS 'Время отгрузки: товар списан с ячеек склада-отправителя движениями transfer_out';
+
+COMMENT ON COLUMN transfer_orders.received_at IS 'Время приёмки: товар оприходован в ячейки склада-получателя движениями transfer_in';
+
+COMMENT ON TABLE transfer_lines IS 'Строки заказа на перемещение. Товар строк отгруженных заказов считается находящимся в пути';
+
+COMMENT ON COLUMN transfer_lines.quantity IS 'Количество к отгрузке из ячейки from_location_id';
+
+COMMENT ON COLUMN transfer_lines.received_quantity IS 'Фактически принятое количество, NULL до приёмки';
+
+COMMENT ON COLUMN transfer_lines.discrepancy IS 'Расхождение при приёмке: отгружено минус принято';
+
+COMMENT ON COLUMN transfer_lines.discrepancy_note IS 'Пояснение к расхождению (недостача, бой и т.п.)';
+
+COMMENT ON TABLE transfer_history IS 'Журнал изменений статуса заказов на перемещение';
+
+COMMENT ON COLUMN transfer_history.username IS 'Пользователь, выполнивший действие';
+
+COMMENT ON COLUMN transfer_history.old_data IS 'Состояние заказа со строками до изменения';
+
+COMMENT ON COLUMN transfer_history.new_data IS 'Состояние заказа со строками после изменения';
+
+COMMENT ON COLUMN stock_movements.type IS 'Тип операции: receipt - приход, issue - расход, adjustment - корректировка, write_off - списание, return - возврат, transfer_out - отгрузка по перемещению, transfer_in - приёмка по перемещению';
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS reservations (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES items (id),
+    owner VARCHAR(255) NOT NULL,
+    reference VARCHAR(100) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (
+        status IN (
+            'active',
+            'released',
+            'expired'
+        )
+    ),
+    expires_at TIMESTAMP NOT NULL,
+    created_by VARCHAR(255) NOT NULL,
+    api_key_name VARCHAR(100),
+    request_id VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    released_at TIMESTAMP,
+    released_by VARCHAR(255),
+    release_reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_reservations_item_active ON reservations (item_id)
+WHERE
+    status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_reservations_expires_at ON reservations (expires_at)
+WHERE
+    status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_reservations_owner ON reservations (owner, id DESC);
+
+COMMENT ON TABLE reservations IS 'Резервы товара под ожидающие заказы. Активные резервы уменьшают доступный остаток товара, но не привязаны к ячейкам';
+
+COMMENT ON COLUMN reservations.owner IS 'Владелец резерва (например, менеджер по продажам), по умолчанию - создавший его пользователь';
+
+COMMENT ON COLUMN reservations.reference IS 'Номер заказа или другого документа, под который зарезервирован товар';
+
+COMMENT ON COLUMN reservations.status IS 'active - действует, released - снят вручную, expired - истёк и снят фоновой задачей';
+
+COMMENT ON COLUMN reservations.expires_at IS 'Время истечения. После него резерв не учитывается, даже если фоновая задача ещё не сменила статус';
+
+COMMENT ON COLUMN reservations.released_by IS 'Пользователь, снявший резерв; system для истёкших';
@@ -0,0 +1,61 @@
+-- Формат хеша версии 1 склеивал поля через concat_ws, который пропускает NULL:
+-- api_key_name NULL и location_id 5 давали тот же хеш, что api_key_name '5' и
+-- location_id NULL. Версия 2 хеширует каждое поле на своём месте, с именем и
+-- в кавычках (NULL записывается явно). Старые записи остаются версии 1 и
+-- проверяются по прежнему формату.
+ALTER TABLE items_history
+ADD COLUMN IF NOT EXISTS hash_version SMALLINT NOT NULL DEFAULT 1;
+
+ALTER TABLE items_history ALTER COLUMN hash_version SET DEFAULT 2;
+
+ALTER TABLE items_history
+ADD CONSTRAINT items_history_hash_version_check CHECK (hash_version IN (1, 2));
+
+CREATE OR REPLACE FUNCTION items_history_hash(h items_history)
+RETURNS TEXT AS $$
+    SELECT encode(
+        sha256(
+            convert_to(
+                CASE h.hash_version
+                    WHEN 1 THEN concat_ws(
+                        '|',
+                        COALESCE(h.prev_hash, ''),
+                        h.id,
+                        h.item_id,
+                        h.action,
+                        h.username,
+                        COALESCE(h.old_data::text, ''),
+                        COALESCE(h.new_data::text, ''),
+                        COALESCE(h.request_id, ''),
+                        COALESCE(h.client_ip, ''),
+                        COALESCE(h.reason, ''),
+                        to_char(h.changed_at, 'YYYY-MM-DD"T"HH24:MI:SS.US'),
+                        h.api_key_name,
+                        h.location_id
+                    )
+                    ELSE concat_ws(
+                        '|',
+                        'hash_version=' || h.hash_version,
+                        'prev_hash=' || quote_nullable(h.prev_hash),
+                        'id=' || quote_nullable(h.id),
+                        'item_id=' || quote_nullable(h.item_id),
+                        'location_id=' || quote_nullable(h.location_id),
+                        'action=' || quote_nullable(h.action),
+                        'username=' || quote_nullable(h.username),
+                        'api_key_name=' || quote_nullable(h.api_key_name),
+                        'old_data=' || quote_nullable(h.old_data::text),
+                        'new_data=' || quote_nullable(h.new_data::text),
+                        'request_id=' || quote_nullable(h.request_id),
+                        'client_ip=' || quote_nullable(h.client_ip),
+                        'reason=' || quote_nullable(h.reason),
+                        'changed_at=' || quote_nullable(to_char(h.changed_at, 'YYYY-MM-DD"T"HH24:MI:SS.US'))
+                    )
+                END,
+                'UTF8'
+            )
+        ),
+        'hex'
+    );
+$$ LANGUAGE sql STABLE;
+
+COMMENT ON COLUMN items_history.hash_version IS 'Формат хеша записи: 1 - поля через concat_ws (записи до появления версий), 2 - поля с именами, в кавычках и с явным NULL';
@@ -0,0 +1,38 @@
+-- Недостача при приёмке перемещения списывается движением transfer_loss
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_type_check;
+
+ALTER TABLE stock_movements
+ADD CONSTRAINT stock_movements_type_check CHECK (
+    type IN (
+        'receipt',
+        'issue',
+        'adjustment',
+        'write_off',
+        'return',
+        'transfer_out',
+        'transfer_in',
+        'transfer_loss'
+    )
+);
+
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_delta_sign_check;
+
+ALTER TABLE stock_movements
+ADD CONSTRAINT stock_movements_delta_sign_check CHECK (
+    (
+        type IN ('receipt', 'return', 'transfer_in')
+        AND delta > 0
+    )
+    OR (
+        type IN ('issue', 'write_off', 'transfer_out', 'transfer_loss')
+        AND delta < 0
+    )
+    OR (
+        type = 'adjustment'
+        AND delta <> 0
+    )
+);
+
+COMMENT ON COLUMN transfer_orders.received_at IS 'Время приёмки: отгруженное количество оприходовано в ячейки склада-получателя движениями transfer_in, недостача списана движениями transfer_loss. Приёмка по заказу одна и окончательная';
+
+COMMENT ON COLUMN stock_movements.type IS 'Тип операции: receipt - приход, issue - расход, adjustment - корректировка, write_off - списание, return - возврат, transfer_out - отгрузка по перемещению, transfer_in - приёмка по перемещению, transfer_loss - недостача при приёмке перемещения';
@@ -0,0 +1,4 @@
+-- Секрет TOTP хранится зашифрованным и в VARCHAR(64) не помещается
+ALTER TABLE user_mfa ALTER COLUMN secret TYPE TEXT;
+
+COMMENT ON COLUMN user_mfa.secret IS 'Секрет TOTP, зашифрованный AES-256-GCM ключом auth.mfa.encryption_key и привязанный к user_id: "v1:" и base64 от nonce и шифртекста. Секреты, записанные до шифрования, шифруются при запуске приложения';
@@ -0,0 +1,93 @@
+-- Хеш версий 1 и 2 - обычный SHA-256: имея доступ к базе, можно изменить запись
+-- и пересчитать всю цепочку после неё. Версия 3 - HMAC-SHA256 с ключом
+-- history.hash_key, который есть только у приложения, поэтому хеш считается в
+-- приложении, а база отдаёт лишь строку для хеширования. Старые записи остаются
+-- своей версии: первая запись версии 3 закрепляет их через prev_hash.
+ALTER TABLE items_history DROP CONSTRAINT IF EXISTS items_history_hash_version_check;
+
+ALTER TABLE items_history
+ADD CONSTRAINT items_history_hash_version_check CHECK (hash_version IN (1, 2, 3));
+
+ALTER TABLE items_history ALTER COLUMN hash_version SET DEFAULT 3;
+
+CREATE OR REPLACE FUNCTION items_history_hash_input(h items_history)
+RETURNS TEXT AS $$
+    SELECT CASE h.hash_version
+        WHEN 1 THEN concat_ws(
+            '|',
+            COALESCE(h.prev_hash, ''),
+            h.id,
+            h.item_id,
+            h.action,
+            h.username,
+            COALESCE(h.old_data::text, ''),
+            COALESCE(h.new_data::text, ''),
+            COALESCE(h.request_id, ''),
+            COALESCE(h.client_ip, ''),
+            COALESCE(h.reason, ''),
+            to_char(h.changed_at, 'YYYY-MM-DD"T"HH24:MI:SS.US'),
+            h.api_key_name,
+            h.location_id
+        )
+        ELSE concat_ws(
+            '|',
+            'hash_version=' || h.hash_version,
+            'prev_hash=' || quote_nullable(h.prev_hash),
+            'id=' || quote_nullable(h.id),
+            'item_id=' || quote_nullable(h.item_id),
+            'location_id=' || quote_nullable(h.location_id),
+            'action=' || quote_nullable(h.action),
+            'username=' || quote_nullable(h.username),
+            'api_key_name=' || quote_nullable(h.api_key_name),
+            'old_data=' || quote_nullable(h.old_data::text),
+            'new_data=' || quote_nullable(h.new_data::text),
+            'request_id=' || quote_nullable(h.request_id),
+            'client_ip=' || quote_nullable(h.client_ip),
+            'reason=' || quote_nullable(h.reason),
+            'changed_at=' || quote_nullable(to_char(h.changed_at, 'YYYY-MM-DD"T"HH24:MI:SS.US'))
+        )
+    END;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION items_history_hash(h items_history)
+RETURNS TEXT AS $$
+    SELECT CASE
+        WHEN h.hash_version < 3 THEN encode(sha256(convert_to(items_history_hash_input(h), 'UTF8')), 'hex')
+    END;
+$$ LANGUAGE sql STABLE;
+
+-- Удаление записей с конца цепочки по самой цепочке не видно. Голова хранит
+-- число записей и хеш последней, подписанные тем же ключом; приложение
+-- подписывает её при первом запуске после миграции.
+CREATE TABLE IF NOT EXISTS items_history_head (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    row_count BIGINT NOT NULL,
+    last_id INTEGER,
+    last_hash CHAR(64),
+    mac CHAR(64)
+);
+
+INSERT INTO items_history_head (row_count, last_id, last_hash)
+SELECT
+    (SELECT COUNT(*) FROM items_history),
+    tail.id,
+    tail.hash
+FROM (SELECT 1) AS one
+LEFT JOIN (SELECT id, hash FROM items_history ORDER BY id DESC LIMIT 1) AS tail ON TRUE
+ON CONFLICT (id) DO NOTHING;
+
+COMMENT ON COLUMN items_history.hash_version IS 'Формат хеша записи: 1 - поля через concat_ws (записи до появления версий), 2 - поля с именами, в кавычках и с явным NULL, 3 - как 2, но HMAC-SHA256 с ключом приложения';
+
+COMMENT ON COLUMN items_history.hash IS 'Хеш содержимого записи и prev_hash: SHA-256 для версий 1 и 2, HMAC-SHA256 с ключом history.hash_key для версии 3';
+
+COMMENT ON FUNCTION items_history_hash_input (items_history) IS 'Строка, от которой считается хеш записи истории';
+
+COMMENT ON FUNCTION items_history_hash (items_history) IS 'SHA-256 записи истории версий 1 и 2. Для версии 3 NULL: ключ есть только у приложения';
+
+COMMENT ON TABLE items_history_head IS 'Конец цепочки истории, одна строка. Обновляется при каждой записи в истории под блокировкой строки';
+
+COMMENT ON COLUMN items_history_head.row_count IS 'Число записей в цепочке';
+
+COMMENT ON COLUMN items_history_head.last_hash IS 'Хеш последней записи, на неё ссылается prev_hash следующей';
+
+COMMENT ON COLUMN items_history_head.mac IS 'HMAC-SHA256 от row_count, last_id и last_hash с ключом history.hash_key. NULL, пока приложение не подписало голову';
@@ -3,9 +3,12 @@
     'use strict';
 
     const API_URL = '/api';
+    // Максимальный размер страницы /api/items (maxItemLimit на сервере)
+    const ITEMS_PAGE_SIZE = 1000;
     let currentUser = null;
     let items = [];
     let history = [];
+    let transfers = [];
 
     // === ИНИЦИАЛИЗАЦИЯ ===
     document.addEventListener('DOMContentLoaded', init);
@@ -47,8 +50,9 @@
                 return false;
             }
 
+            // Истёкший access-токен будет обновлён при первом запросе
             const now = Math.floor(Date.now() / 1000);
-            if (payload.exp < now) {
+            if (payload.exp < now && !localStorage.getItem('refreshToken')) {
                 console.log('[APP] Токен истёк');
                 clearAuth();
                 return false;
@@ -78,10 +82,45 @@
 
     function clearAuth() {
         localStorage.removeItem('authToken');
+        localStorage.removeItem('refreshToken');
         localStorage.removeItem('authUser');
         currentUser = null;
     }
 
+    async function refreshAuth() {
+        const refreshToken = localStorage.getItem('refreshToken');
+        if (!refreshToken) return false;
+
+        try {
+            const response = await fetch(`${API_URL}/auth/refresh`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ refresh_token: refreshToken })
+            });
+
+            const data = await response.json();
+            if (!response.ok || !data.success) {
+                return false;
+            }
+
+            localStorage.setItem('authToken', data.data.token);
+            localStorage.setItem('refreshToken', data.data.refresh_token);
+
+            // Роль или её права могли измениться с момента входа
+            currentUser = {
+                username: data.data.username,
+                role: data.data.role,
+                permissions: data.data.permissions || []
+            };
+            localStorage.setItem('authUser', JSON.stringify(currentUser));
+            console.log('[APP] Токен обновлён');
+            return true;
+        } catch (error) {
+            console.error('[APP] Ошибка обновления токена:', error);
+            return false;
+        }
+    }
+
     function showApp() {
         const appSection = document.getElementById('appSection');
         if (appSection) {
@@ -102,14 +141,31 @@
         updateUIPermissions();
     }
 
+    // Права приходят с сервера при входе: "*" даёт всё, "items:*" - все права на товары
+    function hasPermission(permission) {
+        const permissions = (currentUser && currentUser.permissions) || [];
+        const resource = permission.split(':')[0];
+        return permissions.some(p => p === '*' || p === permission || p === `${resource}:*`);
+    }
+
     function updateUIPermissions() {
         if (!currentUser) return;
 
-        const canCreate = ['admin', 'manager'].includes(currentUser.role);
+        const canCreate = hasPermission('items:create');
         const addBtn = document.getElementById('addItemBtn');
         if (addBtn) {
             addBtn.style.display = canCreate ? 'inline-block' : 'none';
         }
+
+        const transfersTabBtn = document.getElementById('transfersTabBtn');
+        if (transfersTabBtn) {
+            transfersTabBtn.style.display = hasPermission('transfers:read') ? 'inline-block' : 'none';
+        }
+
+        const addTransferBtn = document.getElementById('addTransferBtn');
+        if (addTransferBtn) {
+            addTransferBtn.style.display = hasPermission('transfers:manage') ? 'inline-block' : 'none';
+        }
     }
 
     // === ОБРАБОТЧИКИ СОБЫТИЙ ===
@@ -119,6 +175,11 @@
             logoutBtn.addEventListener('click', handleLogout);
         }
 
+        const changePasswordBtn = document.getElementById('changePasswordBtn');
+        if (changePasswordBtn) {
+            changePasswordBtn.addEventListener('click', handleChangePassword);
+        }
+
         const addItemBtn = document.getElementById('addItemBtn');
         if (addItemBtn) {
             addItemBtn.addEventListener('click', openAddItemModal);
@@ -144,17 +205,72 @@
             resetFiltersBtn.addEventListener('click', resetFilters);
         }
 
+        const addTransferBtn = document.getElementById('addTransferBtn');
+        if (addTransferBtn) {
+            addTransferBtn.addEventListener('click', createTransfer);
+        }
+
+        const filterTransferStatus = document.getElementById('filterTransferStatus');
+        if (filterTransferStatus) {
+            filterTransferStatus.addEventListener('change', loadTransfers);
+        }
+
         document.querySelectorAll('.tab').forEach(tab => {
             tab.addEventListener('click', () => switchTab(tab.dataset.tab));
         });
     }
 
-    function handleLogout() {
+    async function handleLogout() {
         console.log('[APP] Выход из системы');
+
+        try {
+            await fetch(`${API_URL}/auth/logout`, {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                    'Authorization': `Bearer ${localStorage.getItem('authToken')}`
+                },
+                body: JSON.stringify({ refresh_token: localStorage.getItem('refreshToken') || '' })
+            });
+        } catch (error) {
+            console.error('[APP] Ошибка выхода:', error);
+        }
+
         clearAuth();
         window.location.replace('/login');
     }
 
+    // После смены пароля все сессии завершаются, сервер выдаёт новую пару токенов
+    async function handleChangePassword() {
+        const currentPassword = prompt('Текущий пароль');
+        if (!currentPassword) return;
+
+        const newPassword = prompt('Новый пароль');
+        if (!newPassword) return;
+
+        if (prompt('Повторите новый пароль') !== newPassword) {
+            alert('Пароли не совпадают');
+            return;
+        }
+
+        const response = await apiRequest(`${API_URL}/auth/password`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ current_password: currentPassword, new_password: newPassword })
+        });
+        if (!response) return;
+
+        const data = await response.json();
+        if (!response.ok || !data.success) {
+            alert(data.error || 'Не удалось сменить пароль');
+            return;
+        }
+
+        localStorage.setItem('authToken', data.data.token);
+        localStorage.setItem('refreshToken', data.data.refresh_token);
+        alert('Пароль изменён. Остальные сеансы завершены');
+    }
+
     // === ТАБЫ ===
     function switchTab(tabName) {
         document.querySelectorAll('.tab').forEach(tab => {
@@ -167,13 +283,15 @@
 
         if (tabName === 'items') {
             loadItems();
+        } else if (tabName === 'transfers') {
+            loadTransfers();
         } else if (tabName === 'history') {
             loadHistory();
         }
     }
 
     // === API ЗАПРОСЫ ===
-    async function apiRequest(url, options = {}) {
+    async function apiRequest(url, options = {}, retried = false) {
         const token = localStorage.getItem('authToken');
         if (!token) {
             console.log('[APP] Нет токена, редирект на /login');
@@ -192,6 +310,10 @@
                 headers
             });
 
+            if (response.status === 401 && !retried && await refreshAuth()) {
+                return apiRequest(url, options, true);
+            }
+
             if (response.status === 401 || response.status === 403) {
                 console.log('[APP] Ошибка авторизации (401/403), редирект на /login');
                 clearAuth();
@@ -207,22 +329,36 @@
     }
 
     // === ТОВАРЫ ===
+    // Сервер отдаёт товары страницами, а поиск фильтрует загруженный список,
+    // поэтому загружаем все страницы по meta.has_more.
     async function loadItems() {
         console.log('[APP] Загрузка товаров');
 
         try {
-            const response = await apiRequest(`${API_URL}/items`);
-            if (!response) return;
+            const loaded = [];
+            let offset = 0;
 
-            const data = await response.json();
+            while (true) {
+                const response = await apiRequest(`${API_URL}/items?limit=${ITEMS_PAGE_SIZE}&offset=${offset}`);
+                if (!response) return;
 
-            if (data.success) {
-                items = data.data || [];
-                console.log('[APP] Загружено товаров:', items.length);
-                renderItems();
-            } else {
-                showAlert('itemAlert', data.error || 'Ошибка загрузки товаров', 'error');
+                const data = await response.json();
+
+                if (!data.success) {
+                    showAlert('itemAlert', data.error || 'Ошибка загрузки товаров', 'error');
+                    return;
+                }
+
+                const page = data.data || [];
+                loaded.push(...page);
+
+                if (!data.meta || !data.meta.has_more || page.length === 0) break;
+                offset += page.length;
             }
+
+            items = loaded;
+            console.log('[APP] Загружено товаров:', items.length);
+            renderItems();
         } catch (error) {
             console.error('[APP] Ошибка загрузки товаров:', error);
             showAlert('itemAlert', 'Ошибка подключения к серверу', 'error');
@@ -246,14 +382,17 @@
             return;
         }
 
-        const canUpdate = ['admin', 'manager'].includes(currentUser.role);
-        const canDelete = currentUser.role === 'admin';
+        const canUpdate = hasPermission('items:update');
+        const canDelete = hasPermission('items:delete');
+        const canStock = hasPermission('stock:update');
+        const canReserve = hasPermission('reservations:manage');
 
         let html = `
             <table>
                 <thead>
                     <tr>
                         <th>ID</th>
+                        <th>Артикул</th>
                         <th>Название</th>
                         <th>Описание</th>
                         <th>Количество</th>
@@ -268,14 +407,19 @@
             html += `
                 <tr>
                     <td>${item.id}</td>
+                    <td>${escapeHtml(item.sku)}</td>
                     <td>${escapeHtml(item.name)}</td>
                     <td>${escapeHtml(item.description || '-')}</td>
-                    <td>${item.quantity}</td>
+                    <td>${item.quantity}${renderStock(item.stock)}${renderAvailability(item)}</td>
                     <td>${item.price.toFixed(2)} ₽</td>
                     <td>
                         <div class="action-buttons">
                             <button class="btn btn-primary btn-sm" onclick="window.app.viewItemHistory(${item.id})">История</button>
                             ${canUpdate ? `<button class="btn btn-primary btn-sm" onclick="window.app.editItem(${item.id})">Редактировать</button>` : ''}
+                            ${canStock ? `<button class="btn btn-primary btn-sm" onclick="window.app.moveStock(${item.id})">Движение</button>` : ''}
+                            ${canStock ? `<button class="btn btn-primary btn-sm" onclick="window.app.editStock(${item.id})">Инвентаризация</button>` : ''}
+                            ${canReserve ? `<button class="btn btn-primary btn-sm" onclick="window.app.reserveItem(${item.id})">Резерв</button>` : ''}
+                            ${canReserve && item.reserved > 0 ? `<button class="btn btn-secondary btn-sm" onclick="window.app.releaseReservation(${item.id})">Снять резерв</button>` : ''}
                             ${canDelete ? `<button class="btn btn-danger btn-sm" onclick="window.app.deleteItem(${item.id})">Удалить</button>` : ''}
                         </div>
                     </td>
@@ -287,6 +431,26 @@
         container.innerHTML = html;
     }
 
+    // Разбивка количества по складам и ячейкам
+    function renderStock(stock) {
+        if (!stock || stock.length === 0) return '';
+        return stock.map(level =>
+            `<br><small>${escapeHtml(level.warehouse_code)}/${escapeHtml(level.location_code)}: ${level.quantity}</small>`
+        ).join('');
+    }
+
+    // Резервы и товар в пути не лежат в ячейках, поэтому показываются отдельно
+    function renderAvailability(item) {
+        let html = '';
+        if (item.reserved) {
+            html += `<br><small>в резерве: ${item.reserved}, доступно: ${item.available}</small>`;
+        }
+        if (item.in_transit) {
+            html += `<br><small>в пути: ${item.in_transit}</small>`;
+        }
+        return html;
+    }
+
     function filterItems() {
         const searchInput = document.getElementById('searchItems');
         if (!searchInput) return;
@@ -301,6 +465,8 @@
 
         const filtered = items.filter(item =>
             item.name.toLowerCase().includes(term) ||
+            item.sku.toLowerCase().includes(term) ||
+            (item.barcodes || []).some(code => code.toLowerCase().includes(term)) ||
             (item.description && item.description.toLowerCase().includes(term))
         );
 
@@ -324,9 +490,10 @@
 
         document.getElementById('modalTitle').textContent = 'Редактировать товар';
         document.getElementById('itemId').value = item.id;
+        document.getElementById('itemSku').value = item.sku;
+        document.getElementById('itemBarcodes').value = (item.barcodes || []).join(', ');
         document.getElementById('itemName').value = item.name;
         document.getElementById('itemDescription').value = item.description || '';
-        document.getElementById('itemQuantity').value = item.quantity;
         document.getElementById('itemPrice').value = item.price;
         document.getElementById('itemModal').classList.add('active');
     }
@@ -337,9 +504,13 @@
 
         const id = document.getElementById('itemId').value;
         const data = {
+            sku: document.getElementById('itemSku').value,
+            barcodes: document.getElementById('itemBarcodes').value
+                .split(',')
+                .map(code => code.trim())
+                .filter(code => code),
             name: document.getElementById('itemName').value,
             description: document.getElementById('itemDescription').value,
-            quantity: parseInt(document.getElementById('itemQuantity').value),
             price: parseFloat(document.getElementById('itemPrice').value)
         };
 
@@ -347,9 +518,14 @@
             const url = id ? `${API_URL}/items/${id}` : `${API_URL}/items`;
             const method = id ? 'PUT' : 'POST';
 
+            const headers = { 'Content-Type': 'application/json' };
+            if (id) {
+                headers['If-Match'] = itemETag(parseInt(id));
+            }
+
             const response = await apiRequest(url, {
                 method,
-                headers: { 'Content-Type': 'application/json' },
+                headers,
                 body: JSON.stringify(data)
             });
 
@@ -361,6 +537,10 @@
                 showAlert('itemAlert', id ? 'Товар обновлён' : 'Товар добавлен', 'success');
                 closeItemModal();
                 loadItems();
+            } else if (response.status === 412) {
+                showAlert('itemAlert', 'Товар был изменён другим пользователем. Данные обновлены, повторите изменение', 'error');
+                closeItemModal();
+                loadItems();
             } else {
                 showAlert('itemAlert', result.error || 'Ошибка сохранения', 'error');
             }
@@ -377,7 +557,8 @@
 
         try {
             const response = await apiRequest(`${API_URL}/items/${id}`, {
-                method: 'DELETE'
+                method: 'DELETE',
+                headers: { 'If-Match': itemETag(id) }
             });
 
             if (!response) return;
@@ -387,6 +568,9 @@
             if (data.success) {
                 showAlert('itemAlert', 'Товар удалён', 'success');
                 loadItems();
+            } else if (response.status === 412) {
+                showAlert('itemAlert', 'Товар был изменён другим пользователем. Данные обновлены', 'error');
+                loadItems();
             } else {
                 showAlert('itemAlert', data.error || 'Ошибка удаления', 'error');
             }
@@ -396,10 +580,512 @@
         }
     }
 
+    // Выбор ячейки через prompt: возвращает ячейку и текущий остаток товара в ней
+    async function chooseLocation(item, title) {
+        const response = await apiRequest(`${API_URL}/locations`);
+        if (!response) return null;
+        const data = await response.json();
+        if (!data.success) {
+            showAlert('itemAlert', data.error || 'Ошибка загрузки ячеек', 'error');
+            return null;
+        }
+
+        const locations = data.data || [];
+        if (locations.length === 0) {
+            showAlert('itemAlert', 'Сначала создайте склад и ячейки', 'error');
+            return null;
+        }
+
+        const current = locationId => {
+            const level = (item.stock || []).find(l => l.location_id === locationId);
+            return level ? level.quantity : 0;
+        };
+        const list = locations
+            .map(l => `${l.id}: ${l.warehouse_code}/${l.code}${l.zone ? ' (' + l.zone + ')' : ''} - ${current(l.id)} шт.`)
+            .join('\n');
+
+        const locationInput = prompt(`${title} "${item.name}". Введите номер ячейки:\n${list}`);
+        if (!locationInput) return null;
+        const location = locations.find(l => l.id === parseInt(locationInput));
+        if (!location) {
+            showAlert('itemAlert', 'Ячейка не найдена', 'error');
+            return null;
+        }
+
+        return { location, quantity: current(location.id) };
+    }
+
+    async function editStock(id) {
+        const item = items.find(i => i.id === id);
+        if (!item) return;
+
+        const chosen = await chooseLocation(item, 'Инвентаризация');
+        if (!chosen) return;
+        const { location } = chosen;
+
+        const quantityInput = prompt(`Фактическое количество в ячейке ${location.warehouse_code}/${location.code}:`, chosen.quantity);
+        if (quantityInput === null) return;
+        const quantity = parseInt(quantityInput);
+        if (isNaN(quantity) || quantity < 0) {
+            showAlert('itemAlert', 'Количество должно быть неотрицательным числом', 'error');
+            return;
+        }
+
+        try {
+            const save = overrideReservations => apiRequest(`${API_URL}/items/${id}/stock/${location.id}`, {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json', 'If-Match': itemETag(id) },
+                body: JSON.stringify({ quantity, override_reservations: overrideReservations })
+            });
+
+            let result = await save(false);
+            if (!result) return;
+
+            let body = await result.json();
+            // Пересчёт ниже резерва проводится только после явного подтверждения
+            if (result.status === 409 && item.reserved > 0 &&
+                confirm(`${body.error}.\nФактический остаток меньше зарезервированного (${item.reserved} шт.). Всё равно провести инвентаризацию?`)) {
+                result = await save(true);
+                if (!result) return;
+                body = await result.json();
+            }
+
+            if (body.success) {
+                showAlert('itemAlert', 'Остаток обновлён', 'success');
+            } else if (result.status === 412) {
+                showAlert('itemAlert', 'Товар был изменён другим пользователем. Данные обновлены, повторите изменение', 'error');
+            } else {
+                showAlert('itemAlert', body.error || 'Ошибка изменения остатка', 'error');
+            }
+            loadItems();
+        } catch (error) {
+            console.error('[APP] Ошибка изменения остатка:', error);
+            showAlert('itemAlert', 'Ошибка изменения остатка', 'error');
+        }
+    }
+
+    // Типы движений и допустимые коды причин, как на сервере
+    const movementTypes = {
+        receipt: { label: 'Приход', sign: 1, reasons: ['PURCHASE', 'PRODUCTION', 'OTHER'] },
+        issue: { label: 'Расход', sign: -1, reasons: ['SALE', 'INTERNAL_USE', 'OTHER'] },
+        write_off: { label: 'Списание', sign: -1, reasons: ['DAMAGE', 'EXPIRED', 'LOSS'] },
+        return: { label: 'Возврат', sign: 1, reasons: ['CUSTOMER_RETURN', 'OTHER'] },
+        adjustment: { label: 'Корректировка (со знаком)', sign: 0, reasons: ['CORRECTION', 'STOCKTAKE', 'STOCKTAKE_OVERRIDE'] }
+    };
+
+    async function moveStock(id) {
+        const item = items.find(i => i.id === id);
+        if (!item) return;
+
+        const typeNames = Object.keys(movementTypes);
+        const typeInput = prompt('Тип движения:\n' +
+            typeNames.map((name, i) => `${i + 1}: ${movementTypes[name].label}`).join('\n'));
+        if (!typeInput) return;
+        const type = typeNames[parseInt(typeInput) - 1];
+        if (!type) {
+            showAlert('itemAlert', 'Неизвестный тип движения', 'error');
+            return;
+        }
+        const spec = movementTypes[type];
+
+        const chosen = await chooseLocation(item, spec.label);
+        if (!chosen) return;
+        const { location } = chosen;
+
+        const amount = parseInt(prompt(`Количество (в ячейке сейчас ${chosen.quantity} шт.):`));
+        if (isNaN(amount) || amount === 0 || (spec.sign !== 0 && amount < 0)) {
+            showAlert('itemAlert', 'Некорректное количество', 'error');
+            return;
+        }
+        const delta = spec.sign === 0 ? amount : spec.sign * amount;
+
+        const reasonCode = prompt(`Код причины (${spec.reasons.join(', ')}):`, spec.reasons[0]);
+        if (!reasonCode) return;
+        const reference = prompt('Номер документа-основания (необязательно):') || '';
+
+        try {
+            const result = await apiRequest(`${API_URL}/items/${id}/movements`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json', 'If-Match': itemETag(id) },
+                body: JSON.stringify({ location_id: location.id, type, delta, reason_code: reasonCode, reference })
+            });
+            if (!result) return;
+
+            const body = await result.json();
+            if (body.success) {
+                showAlert('itemAlert', `Движение проведено, остаток в ячейке: ${body.data.movement.balance_after}`, 'success');
+            } else if (result.status === 412) {
+                showAlert('itemAlert', 'Товар был изменён другим пользователем. Данные обновлены, повторите операцию', 'error');
+            } else {
+                showAlert('itemAlert', body.error || 'Ошибка проведения движения', 'error');
+            }
+            loadItems();
+        } catch (error) {
+            console.error('[APP] Ошибка проведения движения:', error);
+            showAlert('itemAlert', 'Ошибка проведения движения', 'error');
+        }
+    }
+
+    function itemETag(id) {
+        const item = items.find(i => i.id === id);
+        return item ? `"${item.version}"` : '*';
+    }
+
     function closeItemModal() {
         document.getElementById('itemModal').classList.remove('active');
     }
 
+    // === РЕЗЕРВЫ ===
+    async function reserveItem(id) {
+        const item = items.find(i => i.id === id);
+        if (!item) return;
+
+        const quantity = parseInt(prompt(`Резерв "${item.name}". Количество (доступно ${item.available}):`));
+        if (isNaN(quantity) || quantity <= 0) {
+            showAlert('itemAlert', 'Количество должно быть положительным числом', 'error');
+            return;
+        }
+
+        const reference = prompt('Номер заказа:');
+        if (!reference) return;
+
+        const hoursInput = prompt('Срок резерва в часах (пусто - по умолчанию):');
+        if (hoursInput === null) return;
+        const payload = { item_id: id, quantity, reference };
+        if (hoursInput.trim() !== '') {
+            const hours = parseFloat(hoursInput);
+            if (isNaN(hours) || hours <= 0) {
+                showAlert('itemAlert', 'Срок должен быть положительным числом', 'error');
+                return;
+            }
+            payload.expires_at = new Date(Date.now() + hours * 3600 * 1000).toISOString();
+        }
+
+        try {
+            const result = await apiRequest(`${API_URL}/reservations`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(payload)
+            });
+            if (!result) return;
+
+            const body = await result.json();
+            if (body.success) {
+                showAlert('itemAlert', `Зарезервировано до ${new Date(body.data.expires_at).toLocaleString('ru-RU')}`, 'success');
+            } else {
+                showAlert('itemAlert', body.error || 'Ошибка резервирования', 'error');
+            }
+            loadItems();
+        } catch (error) {
+            console.error('[APP] Ошибка резервирования:', error);
+            showAlert('itemAlert', 'Ошибка резервирования', 'error');
+        }
+    }
+
+    async function releaseReservation(id) {
+        const item = items.find(i => i.id === id);
+        if (!item) return;
+
+        try {
+            const response = await apiRequest(`${API_URL}/reservations?item_id=${id}&status=active`);
+            if (!response) return;
+            const data = await response.json();
+            if (!data.success) {
+                showAlert('itemAlert', data.error || 'Ошибка загрузки резервов', 'error');
+                return;
+            }
+
+            const reservations = data.data || [];
+            if (reservations.length === 0) {
+                loadItems();
+                return;
+            }
+
+            const list = reservations
+                .map(r => `${r.id}: ${r.quantity} шт., заказ ${r.reference}, ${r.owner}, до ${new Date(r.expires_at).toLocaleString('ru-RU')}`)
+                .join('\n');
+            const input = prompt(`Резервы "${item.name}". Введите номер резерва, который нужно снять:\n${list}`);
+            if (!input) return;
+            const reservation = reservations.find(r => r.id === parseInt(input));
+            if (!reservation) {
+                showAlert('itemAlert', 'Резерв не найден', 'error');
+                return;
+            }
+
+            const result = await apiRequest(`${API_URL}/reservations/${reservation.id}/release`, { method: 'POST' });
+            if (!result) return;
+
+            const body = await result.json();
+            if (body.success) {
+                showAlert('itemAlert', 'Резерв снят', 'success');
+            } else {
+                showAlert('itemAlert', body.error || 'Ошибка снятия резерва', 'error');
+            }
+            loadItems();
+        } catch (error) {
+            console.error('[APP] Ошибка снятия резерва:', error);
+            showAlert('itemAlert', 'Ошибка снятия резерва', 'error');
+        }
+    }
+
+    // === ПЕРЕМЕЩЕНИЯ ===
+    const transferStatuses = {
+        draft: 'Черновик',
+        dispatched: 'В пути',
+        received: 'Получен',
+        cancelled: 'Отменён'
+    };
+
+    async function loadTransfers() {
+        const status = document.getElementById('filterTransferStatus')?.value || '';
+        const params = new URLSearchParams();
+        if (status) params.append('status', status);
+
+        try {
+            const response = await apiRequest(`${API_URL}/transfers?${params}`);
+            if (!response) return;
+
+            const data = await response.json();
+            if (data.success) {
+                transfers = data.data || [];
+                renderTransfers();
+            } else {
+                showAlert('transferAlert', data.error || 'Ошибка загрузки перемещений', 'error');
+            }
+        } catch (error) {
+            console.error('[APP] Ошибка загрузки перемещений:', error);
+            showAlert('transferAlert', 'Ошибка загрузки перемещений', 'error');
+        }
+    }
+
+    function renderTransfers() {
+        const container = document.getElementById('transfersList');
+        if (!container) return;
+
+        if (transfers.length === 0) {
+            container.innerHTML = `
+                <div class="empty-state">
+                    <h3>Нет перемещений</h3>
+                    <p>Создайте перемещение товара между складами</p>
+                </div>
+            `;
+            return;
+        }
+
+        const canManage = hasPermission('transfers:manage');
+
+        let html = `
+            <table>
+                <thead>
+                    <tr>
+                        <th>Номер</th>
+                        <th>Откуда</th>
+                        <th>Куда</th>
+                        <th>Статус</th>
+                        <th>Строки</th>
+                        <th>Создал</th>
+                        <th>Действия</th>
+                    </tr>
+                </thead>
+                <tbody>
+        `;
+
+        transfers.forEach(t => {
+            const lines = t.lines.map(line => {
+                let text = `товар #${line.item_id}: ${line.quantity} шт.`;
+                if (line.received_quantity !== undefined && line.received_quantity !== null) {
+                    text += `, принято ${line.received_quantity}`;
+                    if (line.discrepancy) {
+                        text += ` (расхождение ${line.discrepancy}${line.discrepancy_note ? ': ' + escapeHtml(line.discrepancy_note) : ''})`;
+                    }
+                }
+                return `<small>${text}</small>`;
+            }).join('<br>');
+
+            html += `
+                <tr>
+                    <td>${escapeHtml(t.number)}</td>
+                    <td>${t.source_warehouse_id}</td>
+                    <td>${t.destination_warehouse_id}</td>
+                    <td>${transferStatuses[t.status] || t.status}</td>
+                    <td>${lines}</td>
+                    <td>${escapeHtml(t.created_by)}<br><small>${new Date(t.created_at).toLocaleString('ru-RU')}</small></td>
+                    <td>
+                        <div class="action-buttons">
+                            ${canManage && t.status === 'draft' ? `<button class="btn btn-primary btn-sm" onclick="window.app.dispatchTransfer(${t.id})">Отгрузить</button>` : ''}
+                            ${canManage && t.status === 'draft' ? `<button class="btn btn-danger btn-sm" onclick="window.app.cancelTransfer(${t.id})">Отменить</button>` : ''}
+                            ${canManage && t.status === 'dispatched' ? `<button class="btn btn-success btn-sm" onclick="window.app.receiveTransfer(${t.id})">Принять</button>` : ''}
+                        </div>
+                    </td>
+                </tr>
+            `;
+        });
+
+        html += '</tbody></table>';
+        container.innerHTML = html;
+    }
+
+    async function fetchList(url, alertId, errorText) {
+        const response = await apiRequest(url);
+        if (!response) return null;
+        const data = await response.json();
+        if (!data.success) {
+            showAlert(alertId, data.error || errorText, 'error');
+            return null;
+        }
+        return data.data || [];
+    }
+
+    function chooseFrom(list, title, describe) {
+        const input = prompt(`${title}:\n` + list.map(x => `${x.id}: ${describe(x)}`).join('\n'));
+        if (!input) return null;
+        return list.find(x => x.id === parseInt(input)) || null;
+    }
+
+    // Черновик собирается через prompt: склады, затем строки до пустого ввода товара
+    async function createTransfer() {
+        const warehouses = await fetchList(`${API_URL}/warehouses`, 'transferAlert', 'Ошибка загрузки складов');
+        if (!warehouses) return;
+        if (warehouses.length < 2) {
+            showAlert('transferAlert', 'Для перемещения нужно хотя бы два склада', 'error');
+            return;
+        }
+
+        const describeWarehouse = w => `${w.code} - ${w.name}`;
+        const source = chooseFrom(warehouses, 'Склад-отправитель', describeWarehouse);
+        if (!source) return;
+        const destination = chooseFrom(warehouses.filter(w => w.id !== source.id), 'Склад-получатель', describeWarehouse);
+        if (!destination) return;
+
+        const locations = await fetchList(`${API_URL}/locations?warehouse_id=${source.id}`, 'transferAlert', 'Ошибка загрузки ячеек');
+        if (!locations) return;
+        if (locations.length === 0) {
+            showAlert('transferAlert', 'На складе-отправителе нет ячеек', 'error');
+            return;
+        }
+
+        if (items.length === 0) {
+            await loadItems();
+        }
+
+        const lines = [];
+        for (;;) {
+            const itemInput = prompt(`Строка ${lines.length + 1}. ID или артикул товара (пусто - закончить):`);
+            if (!itemInput) break;
+            const item = items.find(i => i.id === parseInt(itemInput) || i.sku === itemInput.trim().toUpperCase());
+            if (!item) {
+                alert('Товар не найден');
+                continue;
+            }
+
+            const stocked = locations.filter(l => (item.stock || []).some(s => s.location_id === l.id));
+            if (stocked.length === 0) {
+                alert(`Товара "${item.name}" нет на складе ${source.code}`);
+                continue;
+            }
+            const onHand = l => (item.stock.find(s => s.location_id === l.id) || {}).quantity || 0;
+            const location = chooseFrom(stocked, `Ячейка для "${item.name}"`, l => `${l.code} - ${onHand(l)} шт.`);
+            if (!location) continue;
+
+            const quantity = parseInt(prompt('Количество:'));
+            if (isNaN(quantity) || quantity <= 0) {
+                alert('Количество должно быть положительным числом');
+                continue;
+            }
+
+            lines.push({ item_id: item.id, from_location_id: location.id, quantity });
+        }
+
+        if (lines.length === 0) return;
+        const note = prompt('Комментарий (необязательно):') || '';
+
+        const response = await apiRequest(`${API_URL}/transfers`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                source_warehouse_id: source.id,
+                destination_warehouse_id: destination.id,
+                note,
+                lines
+            })
+        });
+        if (!response) return;
+
+        const body = await response.json();
+        if (body.success) {
+            showAlert('transferAlert', `Создано перемещение ${body.data.number}`, 'success');
+        } else {
+            showAlert('transferAlert', body.error || 'Ошибка создания перемещения', 'error');
+        }
+        loadTransfers();
+    }
+
+    async function runTransferAction(id, action, payload, successText) {
+        const options = { method: 'POST' };
+        if (payload) {
+            options.headers = { 'Content-Type': 'application/json' };
+            options.body = JSON.stringify(payload);
+        }
+
+        const response = await apiRequest(`${API_URL}/transfers/${id}/${action}`, options);
+        if (!response) return;
+
+        const body = await response.json();
+        if (body.success) {
+            showAlert('transferAlert', successText, 'success');
+        } else {
+            showAlert('transferAlert', body.error || 'Ошибка изменения перемещения', 'error');
+        }
+        loadTransfers();
+    }
+
+    async function dispatchTransfer(id) {
+        if (!confirm('Отгрузить перемещение? Товар будет списан со склада-отправителя')) return;
+        await runTransferAction(id, 'dispatch', null, 'Перемещение отгружено');
+    }
+
+    async function cancelTransfer(id) {
+        if (!confirm('Отменить черновик перемещения?')) return;
+        await runTransferAction(id, 'cancel', null, 'Перемещение отменено');
+    }
+
+    // Приёмка: для каждой строки ячейка получателя и фактическое количество,
+    // при недостаче запрашивается пояснение
+    async function receiveTransfer(id) {
+        const transfer = transfers.find(t => t.id === id);
+        if (!transfer) return;
+
+        const locations = await fetchList(`${API_URL}/locations?warehouse_id=${transfer.destination_warehouse_id}`, 'transferAlert', 'Ошибка загрузки ячеек');
+        if (!locations) return;
+        if (locations.length === 0) {
+            showAlert('transferAlert', 'На складе-получателе нет ячеек', 'error');
+            return;
+        }
+
+        const lines = [];
+        for (const line of transfer.lines) {
+            const location = chooseFrom(locations, `Товар #${line.item_id}, ${line.quantity} шт. Ячейка для приёмки`, l => l.code);
+            if (!location) return;
+
+            const quantityInput = prompt(`Фактически принято (отгружено ${line.quantity}):`, line.quantity);
+            if (quantityInput === null) return;
+            const quantity = parseInt(quantityInput);
+            if (isNaN(quantity) || quantity < 0 || quantity > line.quantity) {
+                showAlert('transferAlert', `Количество должно быть от 0 до ${line.quantity}`, 'error');
+                return;
+            }
+
+            let note = '';
+            if (quantity < line.quantity) {
+                note = prompt(`Недостача ${line.quantity - quantity} шт. будет списана. Пояснение:`) || '';
+            }
+
+            lines.push({ line_id: line.id, location_id: location.id, quantity, note });
+        }
+
+        await runTransferAction(id, 'receive', { lines }, 'Перемещение принято');
+    }
+
     // === ИСТОРИЯ ===
     async function loadHistory() {
         console.log('[APP] Загрузка истории');
@@ -475,7 +1161,8 @@
                         </div>
                     </div>
                     <div class="history-changes">
-                        <div><strong>Пользователь:</strong> ${escapeHtml(h.username)}</div>
+                        <div><strong>Пользователь:</strong> ${escapeHtml(h.username)}${h.api_key ? ` (API-ключ: ${escapeHtml(h.api_key)})` : ''}</div>
+                        ${h.reason ? `<div><strong>Причина:</strong> ${escapeHtml(h.reason)}</div>` : ''}
                         ${renderChanges(h)}
                     </div>
                 </div>
@@ -493,6 +1180,7 @@
                 <div style="margin-top: 10px;">
                     <strong>Создан товар:</strong>
                     <ul style="margin: 5px 0; padding-left: 20px;">
+                        <li>Артикул: ${escapeHtml(h.new_data.sku || '-')}</li>
                         <li>Название: ${escapeHtml(h.new_data.name)}</li>
                         <li>Количество: ${h.new_data.quantity}</li>
                         <li>Цена: ${h.new_data.price} ₽</li>
@@ -502,6 +1190,16 @@
         } else if (h.action === 'UPDATE' && h.old_data && h.new_data) {
             html += '<div style="margin-top: 10px;"><strong>Изменения:</strong><ul style="margin: 5px 0; padding-left: 20px;">';
 
+            const oldSku = h.old_data.sku || '-';
+            const newSku = h.new_data.sku || '-';
+            if (oldSku !== newSku) {
+                html += `<li>Артикул: <s>${escapeHtml(oldSku)}</s> → ${escapeHtml(newSku)}</li>`;
+            }
+            const oldBarcodes = (h.old_data.barcodes || []).join(', ') || '-';
+            const newBarcodes = (h.new_data.barcodes || []).join(', ') || '-';
+            if (oldBarcodes !== newBarcodes) {
+                html += `<li>Штрихкоды: <s>${escapeHtml(oldBarcodes)}</s> → ${escapeHtml(newBarcodes)}</li>`;
+            }
             if (h.old_data.name !== h.new_data.name) {
                 html += `<li>Название: <s>${escapeHtml(h.old_data.name)}</s> → ${escapeHtml(h.new_data.name)}</li>`;
             }
@@ -516,6 +1214,23 @@
             }
 
             html += '</ul></div>';
+        } else if (h.action === 'STOCK' && h.old_data && h.new_data) {
+            const level = (data, id) => {
+                const found = (data.stock || []).find(l => l.location_id === id);
+                return found ? found.quantity : 0;
+            };
+            const place = (h.new_data.stock || []).concat(h.old_data.stock || [])
+                .find(l => l.location_id === h.location_id);
+            const label = place ? `${place.warehouse_code}/${place.location_code}` : `#${h.location_id}`;
+            html += `
+                <div style="margin-top: 10px;">
+                    <strong>Изменение остатка:</strong>
+                    <ul style="margin: 5px 0; padding-left: 20px;">
+                        <li>Ячейка ${escapeHtml(label)}: <s>${level(h.old_data, h.location_id)}</s> → ${level(h.new_data, h.location_id)}</li>
+                        <li>Всего: <s>${h.old_data.quantity}</s> → ${h.new_data.quantity}</li>
+                    </ul>
+                </div>
+            `;
         } else if (h.action === 'DELETE' && h.old_data) {
             html += `
                 <div style="margin-top: 10px;">
@@ -579,7 +1294,8 @@
                         </div>
                     </div>
                     <div class="history-changes">
-                        <div><strong>Пользователь:</strong> ${escapeHtml(h.username)}</div>
+                        <div><strong>Пользователь:</strong> ${escapeHtml(h.username)}${h.api_key ? ` (API-ключ: ${escapeHtml(h.api_key)})` : ''}</div>
+                        ${h.reason ? `<div><strong>Причина:</strong> ${escapeHtml(h.reason)}</div>` : ''}
                         ${renderChanges(h)}
                     </div>
                 </div>
@@ -628,7 +1344,9 @@
         const actions = {
             'INSERT': 'Создание',
             'UPDATE': 'Обновление',
-            'DELETE': 'Удаление'
+            'DELETE': 'Удаление',
+            'RESTORE': 'Восстановление',
+            'STOCK': 'Изменение остатка'
         };
         return actions[action] || action;
     }
@@ -642,7 +1360,14 @@
     // Экспортируем функции для onclick в HTML
     window.app = {
         editItem,
+        editStock,
+        moveStock,
         deleteItem,
+        reserveItem,
+        releaseReservation,
+        dispatchTransfer,
+        receiveTransfer,
+        cancelTransfer,
         viewItemHistory,
         closeItemModal,
         closeHistoryModal
@@ -23,6 +23,18 @@
             form.addEventListener('submit', handleLogin);
         }
 
+        const forgotLink = document.getElementById('forgotPasswordLink');
+        if (forgotLink) {
+            forgotLink.addEventListener('click', handleForgotPassword);
+        }
+
+        // Вход через SSO, для которого нужен второй фактор
+        const ssoChallenge = sessionStorage.getItem('mfaChallenge');
+        if (ssoChallenge) {
+            sessionStorage.removeItem('mfaChallenge');
+            handleMFA(JSON.parse(ssoChallenge), null);
+        }
+
         console.log('[LOGIN] Готов к работе');
     }
 
@@ -88,21 +100,12 @@
             const data = await response.json();
             console.log('[LOGIN] Ответ сервера:', response.status, data);
 
-            if (response.ok && data.success) {
-                // Сохраняем токен и данные пользователя
-                localStorage.setItem('authToken', data.data.token);
-                localStorage.setItem('authUser', JSON.stringify({
-                    username: data.data.username,
-                    role: data.data.role
-                }));
-
-                console.log('[LOGIN] Авторизация успешна, токен сохранён');
-                showAlert('Вход выполнен! Перенаправление...', 'success');
-
-                // Редирект через 500ms
-                setTimeout(() => {
-                    redirectToHome();
-                }, 500);
+            if (response.ok && data.success && data.data.mfa_required) {
+                await handleMFA(data.data, password);
+            } else if (response.ok && data.success) {
+                await completeLogin(data.data, password);
+            } else if (response.status === 429) {
+                showThrottled(response);
             } else {
                 showAlert(data.error || 'Неверное имя пользователя или пароль', 'error');
             }
@@ -112,6 +115,136 @@
         }
     }
 
+    // Второй шаг входа: код из приложения-аутентификатора или резервный код
+    async function handleMFA(challenge, password) {
+        if (challenge.enrollment_required) {
+            const enrollment = await postJSON('/auth/mfa/enroll', { mfa_token: challenge.mfa_token });
+            if (!enrollment.response.ok) {
+                showAlert(enrollment.data.error || 'Не удалось начать настройку двухфакторной аутентификации', 'error');
+                return;
+            }
+
+            alert('Для вашей роли обязательна двухфакторная аутентификация.\n' +
+                'Добавьте ключ в приложение-аутентификатор:\n\n' +
+                enrollment.data.data.secret + '\n\n' + enrollment.data.data.uri);
+        }
+
+        const code = prompt('Введите код из приложения-аутентификатора или резервный код');
+        if (!code) {
+            showAlert('Вход отменён', 'error');
+            return;
+        }
+
+        const body = { mfa_token: challenge.mfa_token };
+        if (/^\d{6}$/.test(code.trim())) {
+            body.code = code.trim();
+        } else {
+            body.recovery_code = code.trim();
+        }
+
+        const { response, data } = await postJSON('/auth/mfa/verify', body);
+        if (response.ok && data.success) {
+            if (data.data.recovery_codes && data.data.recovery_codes.length) {
+                alert('Сохраните резервные коды, они показываются только один раз:\n\n' +
+                    data.data.recovery_codes.join('\n'));
+            }
+            await completeLogin(data.data, password);
+        } else if (response.status === 429) {
+            showThrottled(response);
+        } else {
+            showAlert(data.error || 'Неверный код подтверждения', 'error');
+        }
+    }
+
+    async function postJSON(path, body, token) {
+        const headers = { 'Content-Type': 'application/json' };
+        if (token) {
+            headers['Authorization'] = `Bearer ${token}`;
+        }
+
+        const response = await fetch(`${API_URL}${path}`, {
+            method: 'POST',
+            headers,
+            body: JSON.stringify(body)
+        });
+
+        return { response, data: await response.json() };
+    }
+
+    async function completeLogin(session, password) {
+        // Пока пароль не сменён, сервер отвечает 403 на всё, кроме смены пароля
+        if (session.password_change_required) {
+            session = await changeRequiredPassword(session, password);
+            if (!session) return;
+        }
+
+        // Сохраняем токен и данные пользователя
+        localStorage.setItem('authToken', session.token);
+        localStorage.setItem('refreshToken', session.refresh_token);
+        localStorage.setItem('authUser', JSON.stringify({
+            username: session.username,
+            role: session.role,
+            permissions: session.permissions || []
+        }));
+
+        console.log('[LOGIN] Авторизация успешна, токен сохранён');
+        showAlert('Вход выполнен! Перенаправление...', 'success');
+
+        // Редирект через 500ms
+        setTimeout(() => {
+            redirectToHome();
+        }, 500);
+    }
+
+    async function changeRequiredPassword(session, currentPassword) {
+        const newPassword = prompt('Необходимо сменить пароль. Введите новый пароль');
+        if (!newPassword) {
+            showAlert('Вход отменён: пароль не изменён', 'error');
+            return null;
+        }
+
+        if (prompt('Повторите новый пароль') !== newPassword) {
+            showAlert('Пароли не совпадают', 'error');
+            return null;
+        }
+
+        const { response, data } = await postJSON('/auth/password',
+            { current_password: currentPassword, new_password: newPassword }, session.token);
+        if (!response.ok || !data.success) {
+            showAlert(data.error || 'Не удалось сменить пароль', 'error');
+            return null;
+        }
+
+        return data.data;
+    }
+
+    async function handleForgotPassword(e) {
+        e.preventDefault();
+
+        const username = prompt('Введите имя пользователя');
+        if (!username) return;
+
+        try {
+            const { response, data } = await postJSON('/auth/password/forgot', { username: username.trim() });
+            if (response.ok && data.success) {
+                showAlert('Если учётная запись существует, ссылка для сброса пароля отправлена', 'success');
+            } else if (response.status === 429) {
+                const retryAfter = response.headers.get('Retry-After');
+                showAlert(`Слишком много запросов сброса пароля. Повторите через ${retryAfter || 'некоторое время'} с`, 'error');
+            } else {
+                showAlert(data.error || 'Не удалось запросить сброс пароля', 'error');
+            }
+        } catch (error) {
+            console.error('[LOGIN] Ошибка:', error);
+            showAlert('Ошибка подключения к серверу', 'error');
+        }
+    }
+
+    function showThrottled(response) {
+        const retryAfter = response.headers.get('Retry-After');
+        showAlert(`Слишком много попыток входа. Повторите через ${retryAfter || 'некоторое время'} с`, 'error');
+    }
+
     function showAlert(message, type) {
         let alert = document.getElementById('loginAlert');
         if (!alert) {
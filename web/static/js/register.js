@@ -65,10 +65,10 @@
         const username = document.getElementById('registerUsername').value.trim();
         const password = document.getElementById('registerPassword').value;
         const passwordConfirm = document.getElementById('registerPasswordConfirm').value;
-        const role = document.getElementById('registerRole').value;
+        const inviteCode = document.getElementById('registerInviteCode').value.trim();
 
         // Валидация
-        if (!username || !password || !role) {
+        if (!username || !password) {
             showAlert('Заполните все поля', 'error');
             return;
         }
@@ -78,8 +78,8 @@
             return;
         }
 
-        if (password.length < 6) {
-            showAlert('Пароль должен содержать минимум 6 символов', 'error');
+        if (password.length < 10) {
+            showAlert('Пароль должен содержать минимум 10 символов', 'error');
             return;
         }
 
@@ -94,7 +94,7 @@
                 headers: {
                     'Content-Type': 'application/json'
                 },
-                body: JSON.stringify({ username, password, role })
+                body: JSON.stringify({ username, password, invite_code: inviteCode })
             });
 
             const data = await response.json();
@@ -0,0 +1,89 @@
+// reset_password.js
+(function () {
+    'use strict';
+
+    const API_URL = '/api';
+
+    document.addEventListener('DOMContentLoaded', init);
+
+    function init() {
+        // Токен приходит в ссылке из уведомления о сбросе пароля
+        const token = new URLSearchParams(window.location.search).get('token');
+        if (!token) {
+            showAlert('Ссылка для сброса пароля недействительна', 'error');
+            return;
+        }
+
+        const form = document.getElementById('resetForm');
+        if (form) {
+            form.addEventListener('submit', (e) => handleReset(e, token));
+        }
+    }
+
+    async function handleReset(e, token) {
+        e.preventDefault();
+
+        const password = document.getElementById('resetPassword').value;
+        const passwordConfirm = document.getElementById('resetPasswordConfirm').value;
+
+        if (password.length < 10) {
+            showAlert('Пароль должен содержать минимум 10 символов', 'error');
+            return;
+        }
+
+        if (password !== passwordConfirm) {
+            showAlert('Пароли не совпадают', 'error');
+            return;
+        }
+
+        try {
+            const response = await fetch(`${API_URL}/auth/password/reset`, {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify({ token, new_password: password })
+            });
+
+            const data = await response.json();
+            console.log('[RESET] Ответ сервера:', response.status, data);
+
+            if (response.ok && data.success) {
+                showAlert('Пароль изменён! Перенаправление на страницу входа...', 'success');
+                setTimeout(() => {
+                    window.location.replace('/login');
+                }, 2000);
+            } else {
+                showAlert(data.error || 'Не удалось сбросить пароль', 'error');
+            }
+        } catch (error) {
+            console.error('[RESET] Ошибка:', error);
+            showAlert('Ошибка подключения к серверу', 'error');
+        }
+    }
+
+    function showAlert(message, type) {
+        const alert = document.getElementById('resetAlert');
+        if (!alert) return;
+
+        alert.className = `alert alert-${type}`;
+        alert.textContent = message;
+        alert.style.display = 'block';
+    }
+
+    // Глобальная функция для переключения видимости пароля
+    window.togglePassword = function (inputId) {
+        const input = document.getElementById(inputId);
+        if (!input) return;
+
+        const btn = input.parentElement.querySelector('.password-toggle-btn');
+        if (input.type === 'password') {
+            input.type = 'text';
+            if (btn) btn.textContent = '🙈';
+        } else {
+            input.type = 'password';
+            if (btn) btn.textContent = '👁️';
+        }
+    };
+
+})();